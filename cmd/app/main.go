@@ -1,40 +1,141 @@
 package main
 
 import (
+	"battlebit/internal/config"
 	"battlebit/internal/hub"
 	"battlebit/internal/log"
+	"battlebit/internal/metrics"
 	"battlebit/internal/middleware"
 	"battlebit/internal/server"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	log.SetLogs(slog.LevelInfo)
+	cfg, printConfig, err := config.Load(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	log.SetLogs(cfg.LogLevel())
 	slog.Info("Starting services...")
-	hub := hub.NewHub()
+	hub := hub.NewHub(cfg.Hub)
 	slog.Info("Hub created")
-	gs := server.NewGameServer(hub)
+	gs := server.NewGameServer(hub, cfg.Server)
 	slog.Info("GameServer created")
 
+	registerHubMetrics(hub)
+
 	mux := http.NewServeMux()
 	setupRoutes(mux, gs)
 
 	muxLR := middleware.LogMiddleware(mux)
 
-	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", "8080"),
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
 		Handler: muxLR,
 	}
+	if cfg.Server.TLSEnabled() {
+		certs, err := server.NewCertReloader(cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile)
+		if err != nil {
+			slog.Error("Error loading TLS certificate", "error", err.Error())
+			os.Exit(1)
+		}
+		srv.TLSConfig = certs.TLSConfig()
+		go reloadCertsOnHangup(certs)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		slog.Info("Server started", slog.Int("port", cfg.Server.Port), slog.Bool("tls", cfg.Server.TLSEnabled()))
+		var err error
+		if cfg.Server.TLSEnabled() {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			slog.Error("Error starting server", slog.String("error", err.Error()))
+			stop()
+		}
+	}()
 
-	slog.Info("Server started", slog.String("port", "8080"))
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		slog.Error("Error starting server", slog.String("error", err.Error()))
+	<-ctx.Done()
+	slog.Info("Shutting down", "drainTimeout", cfg.Server.DrainTimeout.String())
+	drainCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.DrainTimeout)
+	defer cancel()
+
+	hub.StopAccepting()
+	if err := srv.Shutdown(drainCtx); err != nil {
+		slog.Error("Error shutting down http server", "error", err.Error())
+	}
+	gs.Shutdown(drainCtx)
+	if err := hub.Shutdown(drainCtx); err != nil {
+		slog.Error("Drain timeout reached", "error", err.Error())
+	}
+	slog.Info("Server stopped")
+}
+
+func reloadCertsOnHangup(certs *server.CertReloader) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		if err := certs.Reload(); err != nil {
+			slog.Error("Error reloading TLS certificate", "error", err.Error())
+		}
 	}
 }
 
 func setupRoutes(mux *http.ServeMux, gs *server.GameServer) {
 	mux.HandleFunc("/", gs.HomePage)
 	mux.HandleFunc("/ws", gs.WsEndpoint)
+	mux.HandleFunc("GET /healthz", gs.HealthEndpoint)
+	mux.HandleFunc("GET /readyz", gs.ReadyEndpoint)
+	mux.HandleFunc("GET /admin", gs.AdminEndpoint)
+	mux.HandleFunc("POST /admin/tokens", gs.TokenEndpoint)
+	mux.Handle("GET /metrics", metrics.Handler())
+}
+
+func registerHubMetrics(h *hub.Hub) {
+	metrics.Default.NewGaugeFunc("battlebit_games", "Games in the hub by state.", func() []metrics.Sample {
+		stats := h.Stats()
+		return []metrics.Sample{
+			{LabelValues: []string{"pending"}, Value: float64(stats.PendingGames)},
+			{LabelValues: []string{"running"}, Value: float64(stats.RunningGames)},
+			{LabelValues: []string{"finished"}, Value: float64(stats.FinishedGames)},
+		}
+	}, "state")
+	metrics.Default.NewGaugeFunc("battlebit_players", "Players in hub games by kind.", func() []metrics.Sample {
+		stats := h.Stats()
+		return []metrics.Sample{
+			{LabelValues: []string{"human"}, Value: float64(stats.HumanPlayers)},
+			{LabelValues: []string{"autopilot"}, Value: float64(stats.AutoPilotPlayers)},
+		}
+	}, "kind")
+	metrics.Default.NewGaugeFunc("battlebit_matchmaking_queued", "Players waiting in the matchmaking queue.", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(h.Queued())}}
+	})
+	metrics.Default.NewGaugeFunc("battlebit_games_limit", "Configured limit of games in the hub.", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(h.LimitGames)}}
+	})
 }
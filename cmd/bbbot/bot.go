@@ -0,0 +1,170 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"battlebit/pkg/strategy"
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// result is how a bot did in one game. Repeats are moves at bits the bot
+// already knew were on; the feed lags, so other bits may have been on too.
+type result struct {
+	Bot      string  `json:"bot"`
+	GameId   string  `json:"gameId"`
+	Moves    int     `json:"moves"`
+	Repeats  int     `json:"repeats"`
+	Errors   int     `json:"errors"`
+	Finished bool    `json:"finished"`
+	Won      bool    `json:"won"`
+	MeanMs   float64 `json:"meanMs"`
+	Seconds  float64 `json:"seconds"`
+	latency  time.Duration
+}
+
+// bot is a player on its own connection. It follows the board through the
+// spectator feed and asks its strategy where to move.
+type bot struct {
+	opts *options
+	c    *client.Client
+	name string
+	seat int
+	rng  *rand.Rand
+}
+
+func (b *bot) play(ctx context.Context, gameId string) (*result, error) {
+	callCtx, cancel := context.WithTimeout(ctx, b.opts.timeout)
+	added, err := b.c.JoinGame(callCtx, client.PlayerJoin{
+		GameId:     gameId,
+		PlayerName: b.name,
+		InviteCode: b.opts.invite,
+		Password:   b.opts.password,
+	})
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("%s joining the game: %w", b.name, err)
+	}
+	gameId = added.GameId
+	bits, err := b.watch(ctx, gameId, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), b.opts.timeout)
+		defer cancel()
+		b.c.StopSpectating(ctx, gameId)
+	}()
+	next, err := strategy.New(b.opts.strategy, strategy.Options{Rand: b.rng, Seat: b.seat, Seats: b.opts.bots})
+	if err != nil {
+		return nil, err
+	}
+
+	// a closed channel is always ready, to move as fast as the server answers
+	always := make(chan time.Time)
+	close(always)
+	ready := (<-chan time.Time)(always)
+	if b.opts.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / b.opts.rate))
+		defer ticker.Stop()
+		ready = ticker.C
+	}
+	r := &result{Bot: b.name, GameId: gameId}
+	start := time.Now()
+	defer func() {
+		r.Seconds = time.Since(start).Seconds()
+		if r.Moves > 0 {
+			r.MeanMs = float64(r.latency) / float64(time.Millisecond) / float64(r.Moves)
+		}
+	}()
+	for !r.Finished {
+		select {
+		case <-ctx.Done():
+			b.leave(gameId, added.PlayerId)
+			return r, nil
+		case e, ok := <-b.c.Events():
+			if !ok {
+				return r, client.ErrClosed
+			}
+			if e.Method == client.EventReconnected {
+				// the player outlives the connection, only the feed is lost
+				if bits, err = b.watch(ctx, gameId, bits); err != nil {
+					return r, err
+				}
+				continue
+			}
+			b.event(e, gameId, added.PlayerId, bits, r)
+		case <-ready:
+			b.move(ctx, gameId, added.PlayerId, next, bits, r)
+		}
+	}
+	return r, nil
+}
+
+func (b *bot) leave(gameId string, playerId string) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.opts.timeout)
+	defer cancel()
+	b.c.LeaveGame(ctx, gameId, playerId)
+}
+
+func (b *bot) move(ctx context.Context, gameId string, playerId string, next strategy.Strategy, bits *strategy.Bits, r *result) {
+	index := next.Next(bits)
+	if bits.IsOn(index) {
+		r.Repeats++
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.opts.timeout)
+	defer cancel()
+	start := time.Now()
+	moved, err := b.c.Move(ctx, gameId, playerId, index)
+	r.latency += time.Since(start)
+	r.Moves++
+	switch {
+	case err != nil || moved.GameId == "":
+		r.Errors++
+	case moved.GameStatus.IsFinished:
+		// the winning move is the only finished one with a time, later moves
+		// come back without
+		r.Finished = true
+		r.Won = !moved.TimeMove.IsZero()
+	case !moved.GameStatus.IsPaused:
+		bits.Set(index)
+	}
+}
+
+func (b *bot) event(e *client.Event, gameId string, playerId string, bits *strategy.Bits, r *result) {
+	if e.Method != client.EventSpectatorEvent {
+		return
+	}
+	se := new(client.SpectatorEvent)
+	if err := e.Decode(se); err != nil || se.Event == nil || se.GameId != gameId {
+		return
+	}
+	switch {
+	case se.Event.PlayerMoved != nil:
+		bits.Set(se.Event.PlayerMoved.Index)
+	case se.Event.GameFinished != nil:
+		r.Finished = true
+		r.Won = se.Event.GameFinished.WinnerId == playerId
+	}
+}
+
+// watch starts the spectator feed and returns the board it begins with, with
+// the bits of known added.
+func (b *bot) watch(ctx context.Context, gameId string, known *strategy.Bits) (*strategy.Bits, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.opts.timeout)
+	defer cancel()
+	started, err := b.c.Spectate(ctx, client.SpectateGame{GameId: gameId, Password: b.opts.password})
+	if err != nil {
+		return nil, fmt.Errorf("%s watching the game: %w", b.name, err)
+	}
+	bits := strategy.LoadBits(started.State.SizeGame, started.State.Status)
+	if known != nil {
+		for i := 0; i < known.Size(); i++ {
+			if known.IsOn(i) {
+				bits.Set(i)
+			}
+		}
+	}
+	return bits, nil
+}
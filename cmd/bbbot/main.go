@@ -0,0 +1,251 @@
+// Command bbbot plays battlebit games as a normal player over the public
+// protocol, with the strategies the server autopilots use.
+package main
+
+import (
+	"battlebit/pkg/client"
+	"battlebit/pkg/strategy"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+)
+
+type options struct {
+	url      string
+	token    string
+	gameId   string
+	invite   string
+	password string
+	name     string
+	bots     int
+	strategy string
+	rate     float64
+	seed     int64
+	poll     time.Duration
+	timeout  time.Duration
+	json     bool
+}
+
+func main() {
+	opts := new(options)
+	url := os.Getenv("BBCTL_URL")
+	if url == "" {
+		url = "ws://localhost:8080/ws"
+	}
+	flag.StringVar(&opts.url, "url", url, "server websocket (env BBCTL_URL)")
+	flag.StringVar(&opts.token, "token", os.Getenv("BBCTL_TOKEN"), "player token of every bot (env BBCTL_TOKEN)")
+	flag.StringVar(&opts.gameId, "game", "", "game to play, join running games one after another when empty")
+	flag.StringVar(&opts.invite, "invite", "", "invite code of an unlisted game")
+	flag.StringVar(&opts.password, "password", "", "game password")
+	flag.StringVar(&opts.name, "name", "bbbot", "player name, numbered when there are several bots")
+	flag.IntVar(&opts.bots, "bots", 1, "bots to run, one connection each")
+	flag.StringVar(&opts.strategy, "strategy", strategy.Names[0], "how bots pick bits: "+strings.Join(strategy.Names, ", "))
+	flag.Float64Var(&opts.rate, "rate", 10, "moves per second of each bot, as fast as the server answers when 0")
+	flag.Int64Var(&opts.seed, "seed", time.Now().UnixNano(), "seed of the strategies")
+	flag.DurationVar(&opts.poll, "poll", 2*time.Second, "time between looks for a game to join")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "time to wait for each call")
+	flag.BoolVar(&opts.json, "json", false, "print a JSON object per bot and game")
+	flag.Parse()
+	if err := run(opts, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "bbbot:", err)
+		os.Exit(1)
+	}
+}
+
+func (o *options) validate() error {
+	var errs []error
+	if o.bots < 1 {
+		errs = append(errs, fmt.Errorf("bots must be positive, got %d", o.bots))
+	}
+	if o.rate < 0 {
+		errs = append(errs, fmt.Errorf("rate must not be negative, got %g", o.rate))
+	}
+	if o.poll <= 0 || o.timeout <= 0 {
+		errs = append(errs, errors.New("poll and timeout must be positive"))
+	}
+	if _, err := strategy.New(o.strategy, strategy.Options{}); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+func run(opts *options, stdout, stderr io.Writer) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	bots := make([]*bot, 0, opts.bots)
+	defer func() {
+		for _, b := range bots {
+			b.c.Close()
+		}
+	}()
+	for i := 0; i < opts.bots; i++ {
+		dialCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+		c, err := client.Dial(dialCtx, opts.url, client.Options{Token: opts.token, EventBuffer: 4096})
+		cancel()
+		if err != nil {
+			return fmt.Errorf("connecting to %s: %w", opts.url, err)
+		}
+		name := opts.name
+		if opts.bots > 1 {
+			name = fmt.Sprintf("%s %d", opts.name, i+1)
+		}
+		bots = append(bots, &bot{opts: opts, c: c, name: name, seat: i, rng: rand.New(rand.NewSource(opts.seed + int64(i)))})
+	}
+
+	var total totals
+	defer func() {
+		if !opts.json && total.games > 0 {
+			total.print(stdout)
+		}
+	}()
+	for ctx.Err() == nil {
+		gameId := opts.gameId
+		if gameId == "" && opts.invite == "" {
+			var err error
+			if gameId, err = pickGame(ctx, bots[0].c, opts, stderr); err != nil {
+				return err
+			}
+			if gameId == "" {
+				return nil
+			}
+		}
+		results, err := playAll(ctx, bots, gameId)
+		for _, r := range results {
+			total.add(r)
+			if err := printResult(stdout, opts, r); err != nil {
+				return err
+			}
+		}
+		if len(results) > 0 {
+			total.games++
+		}
+		if opts.gameId != "" || opts.invite != "" {
+			return err
+		}
+		if err != nil {
+			// another game may take the bots, look again a bit later
+			fmt.Fprintln(stderr, "bbbot:", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(opts.poll):
+			}
+		}
+	}
+	return nil
+}
+
+// playAll plays the game with every bot at once.
+func playAll(ctx context.Context, bots []*bot, gameId string) ([]*result, error) {
+	results := make([]*result, len(bots))
+	errs := make([]error, len(bots))
+	var wg sync.WaitGroup
+	for i, b := range bots {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = b.play(ctx, gameId)
+		}()
+	}
+	wg.Wait()
+	played := make([]*result, 0, len(results))
+	for _, r := range results {
+		if r != nil {
+			played = append(played, r)
+		}
+	}
+	return played, errors.Join(errs...)
+}
+
+// pickGame waits for a running game and returns the one with the fewest
+// players, or "" when ctx ends first.
+func pickGame(ctx context.Context, c *client.Client, opts *options, stderr io.Writer) (string, error) {
+	waiting := false
+	for {
+		listCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+		games, err := c.ListGames(listCtx)
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			return "", fmt.Errorf("listing the games: %w", err)
+		}
+		var pick *client.GameMetrics
+		for _, g := range games {
+			if !g.GameStatus.IsInProcess || g.GameStatus.IsPaused {
+				continue
+			}
+			if pick == nil || g.Players < pick.Players {
+				pick = g
+			}
+		}
+		if pick != nil {
+			return pick.GameId, nil
+		}
+		if !waiting {
+			fmt.Fprintln(stderr, "bbbot: waiting for a running game")
+			waiting = true
+		}
+		select {
+		case <-ctx.Done():
+			return "", nil
+		case <-time.After(opts.poll):
+		}
+	}
+}
+
+func printResult(w io.Writer, opts *options, r *result) error {
+	if opts.json {
+		return json.NewEncoder(w).Encode(r)
+	}
+	outcome := "left"
+	switch {
+	case r.Won:
+		outcome = "won"
+	case r.Finished:
+		outcome = "lost"
+	}
+	_, err := fmt.Fprintf(w, "%s  game %s  %s  moves %d  repeats %d (%s)  errors %d  mean %.2fms  %.1fs\n",
+		r.Bot, r.GameId, outcome, r.Moves, r.Repeats, percent(r.Repeats, r.Moves), r.Errors, r.MeanMs, r.Seconds)
+	return err
+}
+
+// totals add up the results of every bot and game of the run.
+type totals struct {
+	games   int
+	won     int
+	moves   int
+	repeats int
+	errors  int
+}
+
+func (t *totals) add(r *result) {
+	t.moves += r.Moves
+	t.repeats += r.Repeats
+	t.errors += r.Errors
+	if r.Won {
+		t.won++
+	}
+}
+
+func (t *totals) print(w io.Writer) {
+	fmt.Fprintf(w, "total  games %d  won %d  moves %d  repeats %d (%s)  errors %d\n",
+		t.games, t.won, t.moves, t.repeats, percent(t.repeats, t.moves), t.errors)
+}
+
+func percent(n, of int) string {
+	if of == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.0f%%", 100*float64(n)/float64(of))
+}
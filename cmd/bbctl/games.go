@@ -0,0 +1,190 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+)
+
+func gamesList(ctx context.Context, defaults options, args []string, stdout io.Writer) error {
+	fs, opts := newFlagSet("games list", defaults)
+	if _, err := parseArgs(fs, opts, args, 0); err != nil {
+		return err
+	}
+	c, err := dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	games, err := c.ListGames(callCtx)
+	if err != nil {
+		return err
+	}
+	if opts.output == "json" {
+		return printJSON(stdout, games)
+	}
+	t := newTable(stdout, metricsHeader...)
+	for _, m := range games {
+		t.row(metricsRow(m)...)
+	}
+	return t.flush()
+}
+
+func gamesCreate(ctx context.Context, defaults options, args []string, stdout io.Writer) error {
+	fs, opts := newFlagSet("games create", defaults)
+	ng := client.NewGame{}
+	seed := fs.Int64("seed", 0, "board seed, random when 0")
+	delay := fs.Int("spectator-delay", -1, "spectator delay in seconds, the server default when negative")
+	fs.IntVar(&ng.Size, "size", 64, "number of bits")
+	fs.IntVar(&ng.Autopilots, "bots", 0, "number of autopilots")
+	fs.StringVar(&ng.Visibility, "visibility", "", "public or unlisted")
+	fs.StringVar(&ng.Password, "password", "", "password to join")
+	if _, err := parseArgs(fs, opts, args, 0); err != nil {
+		return err
+	}
+	if *seed != 0 {
+		ng.Seed = seed
+	}
+	if *delay >= 0 {
+		ng.SpectatorDelay = delay
+	}
+	c, err := dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	created, err := c.CreateGame(callCtx, ng)
+	if err != nil {
+		return err
+	}
+	if opts.output == "json" {
+		return printJSON(stdout, created)
+	}
+	return printFields(stdout,
+		"gameId", created.GameId,
+		"size", strconv.Itoa(created.SizeGame),
+		"autopilots", strconv.Itoa(created.NumberAutoPilots),
+		"seed", strconv.FormatInt(created.Seed, 10),
+		"visibility", created.Visibility,
+		"inviteCode", created.InviteCode,
+	)
+}
+
+func gamesRemove(ctx context.Context, defaults options, args []string, stdout io.Writer) error {
+	fs, opts := newFlagSet("games rm", defaults)
+	positional, err := parseArgs(fs, opts, args, 1)
+	if err != nil {
+		return err
+	}
+	c, err := dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for _, gameId := range positional {
+		callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+		err := c.RemoveGame(callCtx, gameId)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("removing %s: %w", gameId, err)
+		}
+		if opts.output == "json" {
+			err = printJSON(stdout, map[string]string{"gameId": gameId, "message": "game removed"})
+		} else {
+			_, err = fmt.Fprintln(stdout, "removed", gameId)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gamesMetrics prints the metrics once, or with --watch a line per interval
+// until the game finishes or the command is interrupted.
+func gamesMetrics(ctx context.Context, defaults options, args []string, stdout io.Writer) error {
+	fs, opts := newFlagSet("games metrics", defaults)
+	watch := fs.Bool("watch", false, "keep printing until the game finishes")
+	interval := fs.Duration("interval", time.Second, "time between lines with --watch")
+	positional, err := parseArgs(fs, opts, args, 1)
+	if err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	c, err := dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	gameId := positional[0]
+	t := newTable(stdout, metricsHeader...)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+		m, err := c.Metrics(callCtx, gameId)
+		cancel()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if opts.output == "json" {
+			err = printJSONLine(stdout, m)
+		} else {
+			t.row(metricsRow(m)...)
+			err = t.flush()
+		}
+		if err != nil || !*watch || m.GameStatus.IsFinished {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+var metricsHeader = []string{"GAME", "SIZE", "PLAYERS", "BOTS", "SPECTATORS", "BOT MOVES", "STATUS", "DURATION", "VISIBILITY"}
+
+func metricsRow(m *client.GameMetrics) []string {
+	return []string{
+		m.GameId,
+		strconv.Itoa(m.SizeGame),
+		strconv.Itoa(m.Players),
+		strconv.Itoa(m.AutoPilots),
+		strconv.Itoa(m.Spectators),
+		strconv.FormatUint(m.AutoPilotMoves, 10),
+		gameStatus(m),
+		m.CurrentDuration,
+		m.Visibility,
+	}
+}
+
+func gameStatus(m *client.GameMetrics) string {
+	switch {
+	case m.GameStatus.IsFinished:
+		return "finished"
+	case m.GameStatus.IsPaused:
+		return "paused"
+	case m.GameStatus.IsInProcess:
+		return "running"
+	default:
+		return "pending"
+	}
+}
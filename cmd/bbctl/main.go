@@ -0,0 +1,159 @@
+// Command bbctl drives a battlebit server from a terminal or a script.
+package main
+
+import (
+	"battlebit/pkg/client"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: bbctl [flags] <command> [flags]
+
+Commands:
+  games list                                  list the games you can see
+  games create --size N --bots N              create and start a game
+  games rm <gameId>                           remove a game
+  games metrics <gameId> [--watch]            show the metrics of a game
+  join <gameId> --name NAME                   add a player to a game
+  move <gameId> --player ID --index N         flip a bit
+  call <method> [params]                      send any method with JSON params
+
+Every command takes:
+  --url      server websocket (env BBCTL_URL, default ws://localhost:8080/ws)
+  --token    player or admin token (env BBCTL_TOKEN)
+  -o         output, table or json (default table)
+  --timeout  time to wait for the server (default 10s)
+`
+
+// options are the flags shared by every command.
+type options struct {
+	url     string
+	token   string
+	output  string
+	timeout time.Duration
+}
+
+type command func(ctx context.Context, defaults options, args []string, stdout io.Writer) error
+
+var errUsage = errors.New("usage")
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdout)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if errors.Is(err, errUsage) {
+		if err != errUsage {
+			fmt.Fprintln(os.Stderr, "bbctl:", strings.TrimPrefix(err.Error(), "usage: "))
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bbctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout io.Writer) error {
+	// the shared flags may also come before the command
+	global := flag.NewFlagSet("bbctl", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(global.Output(), usage) }
+	defaults := addOptions(global, defaultOptions())
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	args = global.Args()
+	if len(args) == 0 {
+		return errUsage
+	}
+	commands := map[string]command{
+		"join": joinCommand,
+		"move": moveCommand,
+		"call": callCommand,
+	}
+	if args[0] == "games" {
+		if len(args) < 2 {
+			return errUsage
+		}
+		commands = map[string]command{
+			"list":    gamesList,
+			"ls":      gamesList,
+			"create":  gamesCreate,
+			"rm":      gamesRemove,
+			"metrics": gamesMetrics,
+		}
+		args = args[1:]
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Fprint(stdout, usage)
+		return nil
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", errUsage, args[0])
+	}
+	return cmd(ctx, *defaults, args[1:], stdout)
+}
+
+func defaultOptions() options {
+	url := os.Getenv("BBCTL_URL")
+	if url == "" {
+		url = "ws://localhost:8080/ws"
+	}
+	return options{url: url, token: os.Getenv("BBCTL_TOKEN"), output: "table", timeout: 10 * time.Second}
+}
+
+func addOptions(fs *flag.FlagSet, defaults options) *options {
+	opts := new(options)
+	fs.StringVar(&opts.url, "url", defaults.url, "server websocket")
+	fs.StringVar(&opts.token, "token", defaults.token, "player or admin token")
+	fs.StringVar(&opts.output, "o", defaults.output, "output, table or json")
+	fs.DurationVar(&opts.timeout, "timeout", defaults.timeout, "time to wait for the server")
+	return opts
+}
+
+func newFlagSet(name string, defaults options) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	return fs, addOptions(fs, defaults)
+}
+
+// parseArgs lets flags follow the positional arguments, as in
+// `games rm <gameId> -o json`, and checks how many positional arguments came.
+func parseArgs(fs *flag.FlagSet, opts *options, args []string, want int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if opts.output != "table" && opts.output != "json" {
+		return nil, fmt.Errorf("unknown output %q, want table or json", opts.output)
+	}
+	if len(positional) < want {
+		return nil, fmt.Errorf("%w: %s needs %d argument(s)", errUsage, fs.Name(), want)
+	}
+	return positional, nil
+}
+
+func dial(ctx context.Context, opts *options) (*client.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	c, err := client.Dial(ctx, opts.url, client.Options{Token: opts.token})
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", opts.url, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+func printJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printJSONLine writes one object per line, for streams read by scripts.
+func printJSONLine(w io.Writer, v any) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// printFields prints key value pairs as a two column table.
+func printFields(w io.Writer, pairs ...string) error {
+	t := newTable(w)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		t.row(pairs[i], pairs[i+1])
+	}
+	return t.flush()
+}
+
+// table pads columns to the widest cell seen so far, so rows flushed one at a
+// time by --watch stay aligned with the header printed with the first.
+type table struct {
+	w      io.Writer
+	header []string
+	rows   [][]string
+	widths []int
+}
+
+func newTable(w io.Writer, header ...string) *table {
+	return &table{w: w, header: header}
+}
+
+func (t *table) row(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *table) flush() error {
+	rows := t.rows
+	if t.header != nil {
+		rows = append([][]string{t.header}, rows...)
+		t.header = nil
+	}
+	for _, r := range rows {
+		for i, cell := range r {
+			if i == len(t.widths) {
+				t.widths = append(t.widths, 0)
+			}
+			t.widths[i] = max(t.widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	var b strings.Builder
+	for _, r := range rows {
+		for i, cell := range r {
+			b.WriteString(cell)
+			if i < len(r)-1 {
+				b.WriteString(strings.Repeat(" ", t.widths[i]-utf8.RuneCountInString(cell)+2))
+			}
+		}
+		b.WriteByte('\n')
+	}
+	t.rows = nil
+	_, err := fmt.Fprint(t.w, b.String())
+	return err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func joinCommand(ctx context.Context, defaults options, args []string, stdout io.Writer) error {
+	fs, opts := newFlagSet("join", defaults)
+	pj := client.PlayerJoin{}
+	fs.StringVar(&pj.PlayerName, "name", "", "player name, the token decides it when authenticated")
+	fs.StringVar(&pj.InviteCode, "invite", "", "invite code of an unlisted game, instead of the game id")
+	fs.StringVar(&pj.Password, "password", "", "game password")
+	positional, err := parseArgs(fs, opts, args, 0)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 0 {
+		pj.GameId = positional[0]
+	}
+	if pj.GameId == "" && pj.InviteCode == "" {
+		return fmt.Errorf("%w: join needs a game id or --invite", errUsage)
+	}
+	c, err := dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	added, err := c.JoinGame(callCtx, pj)
+	if err != nil {
+		return err
+	}
+	if opts.output == "json" {
+		return printJSON(stdout, added)
+	}
+	return printFields(stdout,
+		"gameId", added.GameId,
+		"playerId", added.PlayerId,
+		"playerName", added.PlayerName,
+	)
+}
+
+func moveCommand(ctx context.Context, defaults options, args []string, stdout io.Writer) error {
+	fs, opts := newFlagSet("move", defaults)
+	playerId := fs.String("player", "", "player id returned by join")
+	index := fs.Int("index", -1, "bit to flip")
+	positional, err := parseArgs(fs, opts, args, 1)
+	if err != nil {
+		return err
+	}
+	if *playerId == "" || *index < 0 {
+		return fmt.Errorf("%w: move needs --player and --index", errUsage)
+	}
+	c, err := dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	moved, err := c.Move(callCtx, positional[0], *playerId, *index)
+	if err != nil {
+		return err
+	}
+	if moved.GameId == "" {
+		return fmt.Errorf("move ignored, unknown player or index out of range")
+	}
+	if opts.output == "json" {
+		return printJSON(stdout, moved)
+	}
+	return printFields(stdout,
+		"gameId", moved.GameId,
+		"playerId", moved.PlayerId,
+		"index", strconv.Itoa(moved.Index),
+		"finished", strconv.FormatBool(moved.GameStatus.IsFinished),
+		"paused", strconv.FormatBool(moved.GameStatus.IsPaused),
+	)
+}
+
+// callCommand sends any method, for the ones without a command of their own.
+// The result is always printed as JSON.
+func callCommand(ctx context.Context, defaults options, args []string, stdout io.Writer) error {
+	fs, opts := newFlagSet("call", defaults)
+	positional, err := parseArgs(fs, opts, args, 1)
+	if err != nil {
+		return err
+	}
+	var params json.RawMessage
+	if len(positional) > 1 {
+		params = json.RawMessage(positional[1])
+		if !json.Valid(params) {
+			return fmt.Errorf("params are not valid JSON")
+		}
+	}
+	c, err := dial(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	callCtx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+	var result json.RawMessage
+	if err := c.Call(callCtx, positional[0], params, &result); err != nil {
+		return err
+	}
+	return printJSON(stdout, result)
+}
@@ -0,0 +1,305 @@
+// Command bbload puts a battlebit server under the load of many simulated
+// players and reports how it holds up.
+package main
+
+import (
+	"battlebit/pkg/strategy"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+type options struct {
+	url      string
+	token    string
+	clients  int
+	players  int
+	size     int
+	bots     int
+	rate     float64
+	strategy string
+	duration time.Duration
+	ramp     time.Duration
+	interval time.Duration
+	timeout  time.Duration
+	seed     int64
+	json     bool
+}
+
+func main() {
+	opts := new(options)
+	url := os.Getenv("BBCTL_URL")
+	if url == "" {
+		url = "ws://localhost:8080/ws"
+	}
+	flag.StringVar(&opts.url, "url", url, "server websocket (env BBCTL_URL)")
+	flag.StringVar(&opts.token, "token", os.Getenv("BBCTL_TOKEN"), "token of every client (env BBCTL_TOKEN)")
+	flag.IntVar(&opts.clients, "clients", 10, "simulated clients, one websocket each")
+	flag.IntVar(&opts.players, "players", 2, "clients per game")
+	flag.IntVar(&opts.size, "size", 1024, "bits per game")
+	flag.IntVar(&opts.bots, "bots", 0, "autopilots per game")
+	flag.Float64Var(&opts.rate, "rate", 10, "moves per second of each client, as fast as the server answers when 0")
+	flag.StringVar(&opts.strategy, "strategy", "random", "how clients pick bits: "+strings.Join(strategy.Names, ", "))
+	flag.DurationVar(&opts.duration, "duration", time.Minute, "length of the run")
+	flag.DurationVar(&opts.ramp, "ramp", 0, "time to spread the connections over")
+	flag.DurationVar(&opts.interval, "interval", 5*time.Second, "time between progress lines and heap samples")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "time to wait for each call")
+	flag.Int64Var(&opts.seed, "seed", time.Now().UnixNano(), "seed of the strategies")
+	flag.BoolVar(&opts.json, "json", false, "print the final report as JSON")
+	flag.Parse()
+	if err := run(opts, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "bbload:", err)
+		os.Exit(1)
+	}
+}
+
+func (o *options) validate() error {
+	var errs []error
+	if o.clients < 1 {
+		errs = append(errs, fmt.Errorf("clients must be positive, got %d", o.clients))
+	}
+	if o.players < 1 {
+		errs = append(errs, fmt.Errorf("players must be positive, got %d", o.players))
+	}
+	if o.size < 1 {
+		errs = append(errs, fmt.Errorf("size must be positive, got %d", o.size))
+	}
+	if o.rate < 0 {
+		errs = append(errs, fmt.Errorf("rate must not be negative, got %g", o.rate))
+	}
+	if o.duration <= 0 || o.interval <= 0 || o.timeout <= 0 {
+		errs = append(errs, errors.New("duration, interval and timeout must be positive"))
+	}
+	if _, err := strategy.New(o.strategy, strategy.Options{}); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// heap is the last and the largest server heap seen through game_metrics.
+type heap struct {
+	last atomic.Uint64
+	peak atomic.Uint64
+}
+
+func (h *heap) sample(bytes uint64) {
+	h.last.Store(bytes)
+	for {
+		peak := h.peak.Load()
+		if bytes <= peak || h.peak.CompareAndSwap(peak, bytes) {
+			return
+		}
+	}
+}
+
+func run(opts *options, stdout, stderr io.Writer) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	interrupted, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// the players run until the last heap sample is taken, while their games
+	// are still there
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l := &load{opts: opts, rec: newRecorder(), active: make(map[string]bool)}
+	monitor, err := l.connect(ctx, -1, opts.seed)
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", opts.url, err)
+	}
+	defer monitor.close()
+
+	start := time.Now()
+	h := new(heap)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		l.spawn(ctx)
+	}()
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	deadline := time.NewTimer(opts.duration)
+	defer deadline.Stop()
+	last := start
+	for done := false; !done; {
+		select {
+		case <-interrupted.Done():
+			done = true
+		case <-deadline.C:
+			done = true
+		case now := <-ticker.C:
+			l.sampleHeap(ctx, monitor, h)
+			progress(stderr, l, h, now.Sub(start), now.Sub(last))
+			last = now
+		}
+	}
+	elapsed := time.Since(start)
+	l.sampleHeap(ctx, monitor, h)
+	cancel()
+	fmt.Fprintln(stderr, "bbload: stopping, removing the games")
+	wg.Wait()
+
+	r := newReport(opts, l, h, elapsed)
+	if opts.json {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return r.print(stdout)
+}
+
+// spawn connects the clients, spread over opts.ramp, and starts a group for
+// every opts.players of them. It returns when every group is done.
+func (l *load) spawn(ctx context.Context) {
+	opts := l.opts
+	var wg sync.WaitGroup
+	var seats []*simulated
+	for i := 0; i < opts.clients && ctx.Err() == nil; i++ {
+		if i > 0 && opts.ramp > 0 {
+			select {
+			case <-ctx.Done():
+				continue
+			case <-time.After(opts.ramp / time.Duration(opts.clients)):
+			}
+		}
+		s, err := l.connect(ctx, len(seats), opts.seed+int64(i)+1)
+		if err == nil {
+			seats = append(seats, s)
+		}
+		if len(seats) == opts.players || (i == opts.clients-1 && len(seats) > 0) {
+			g := &group{load: l, players: seats}
+			seats = nil
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() {
+					for _, s := range g.players {
+						s.close()
+					}
+				}()
+				g.run(ctx)
+			}()
+		}
+	}
+	for _, s := range seats {
+		s.close()
+	}
+	wg.Wait()
+}
+
+// sampleHeap asks any running game for its metrics, which carry the heap of
+// the server.
+func (l *load) sampleHeap(ctx context.Context, monitor *simulated, h *heap) {
+	gameId := l.anyGame()
+	if gameId == "" {
+		return
+	}
+	l.call(ctx, "game_metrics", func(ctx context.Context) error {
+		metrics, err := monitor.c.Metrics(ctx, gameId)
+		if err == nil && metrics.ServerHeapBytes > 0 {
+			h.sample(metrics.ServerHeapBytes)
+		}
+		return err
+	})
+}
+
+func progress(w io.Writer, l *load, h *heap, elapsed, window time.Duration) {
+	moves, calls, errs := l.rec.flush("player_move")
+	errorRate := 0.0
+	if calls > 0 {
+		errorRate = 100 * float64(errs) / float64(calls)
+	}
+	fmt.Fprintf(w, "%6s  clients %d  games %d  moves %.0f/s  errors %.2f%%  move p50 %s p99 %s  heap %s\n",
+		elapsed.Round(time.Second), l.connected.Load()-1, l.games.Load(),
+		float64(moves.count)/window.Seconds(), errorRate,
+		round(moves.percentile(0.50)), round(moves.percentile(0.99)), bytes(h.last.Load()))
+}
+
+type report struct {
+	Duration       string         `json:"duration"`
+	Clients        int            `json:"clients"`
+	Strategy       string         `json:"strategy"`
+	Rate           float64        `json:"rate"`
+	GamesStarted   uint64         `json:"gamesStarted"`
+	GamesFinished  uint64         `json:"gamesFinished"`
+	Moves          uint64         `json:"moves"`
+	MovesPerSecond float64        `json:"movesPerSecond"`
+	Reconnects     uint64         `json:"reconnects"`
+	HeapBytes      uint64         `json:"heapBytes"`
+	PeakHeapBytes  uint64         `json:"peakHeapBytes"`
+	Methods        []methodReport `json:"methods"`
+}
+
+func newReport(opts *options, l *load, h *heap, elapsed time.Duration) *report {
+	return &report{
+		Duration:       elapsed.Round(time.Millisecond).String(),
+		Clients:        opts.clients,
+		Strategy:       opts.strategy,
+		Rate:           opts.rate,
+		GamesStarted:   l.gamesStarted.Load(),
+		GamesFinished:  l.gamesFinished.Load(),
+		Moves:          l.moves.Load(),
+		MovesPerSecond: float64(l.moves.Load()) / elapsed.Seconds(),
+		Reconnects:     l.reconnects.Load(),
+		HeapBytes:      h.last.Load(),
+		PeakHeapBytes:  h.peak.Load(),
+		Methods:        l.rec.report(elapsed),
+	}
+}
+
+func (r *report) print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "duration\t%s\n", r.Duration)
+	fmt.Fprintf(tw, "clients\t%d (%s, %g moves/s each)\n", r.Clients, r.Strategy, r.Rate)
+	fmt.Fprintf(tw, "games\t%d started, %d finished\n", r.GamesStarted, r.GamesFinished)
+	fmt.Fprintf(tw, "moves\t%d (%.0f/s)\n", r.Moves, r.MovesPerSecond)
+	fmt.Fprintf(tw, "reconnects\t%d\n", r.Reconnects)
+	fmt.Fprintf(tw, "server heap\t%s (peak %s)\n", bytes(r.HeapBytes), bytes(r.PeakHeapBytes))
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "METHOD\tCALLS\tRATE/S\tERRORS\tMEAN\tP50\tP90\tP99\tMAX")
+	for _, m := range r.Methods {
+		fmt.Fprintf(tw, "%s\t%d\t%.1f\t%s\t%.2fms\t%.2fms\t%.2fms\t%.2fms\t%.2fms\n",
+			m.Method, m.Calls, m.PerSecond, errorsCell(m), m.MeanMs, m.P50Ms, m.P90Ms, m.P99Ms, m.MaxMs)
+	}
+	return tw.Flush()
+}
+
+// errorsCell shows the error rate and how many errors of each code.
+func errorsCell(m methodReport) string {
+	if m.Errors == 0 {
+		return "0"
+	}
+	codes := make([]string, 0, len(m.Codes))
+	for code, n := range m.Codes {
+		codes = append(codes, fmt.Sprintf("%s:%d", code, n))
+	}
+	sort.Strings(codes)
+	return fmt.Sprintf("%.2f%% (%s)", 100*m.ErrorRate, strings.Join(codes, " "))
+}
+
+func round(d time.Duration) time.Duration {
+	if d >= time.Millisecond {
+		return d.Round(100 * time.Microsecond)
+	}
+	return d.Round(time.Microsecond)
+}
+
+func bytes(n uint64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+}
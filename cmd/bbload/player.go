@@ -0,0 +1,213 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"battlebit/pkg/strategy"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errIgnored is a move the server answered with an empty PlayerMoved, as it
+// does for a player it does not know.
+var errIgnored = errors.New("move ignored")
+
+// load is what every simulated player shares.
+type load struct {
+	opts          *options
+	rec           *recorder
+	connected     atomic.Int64
+	reconnects    atomic.Uint64
+	games         atomic.Int64
+	gamesStarted  atomic.Uint64
+	gamesFinished atomic.Uint64
+	moves         atomic.Uint64
+
+	mutex  sync.Mutex
+	active map[string]bool
+}
+
+// call times a call under method. Calls cut short because ctx ended are not
+// counted.
+func (l *load) call(ctx context.Context, method string, call func(ctx context.Context) error) error {
+	callCtx, cancel := context.WithTimeout(ctx, l.opts.timeout)
+	defer cancel()
+	start := time.Now()
+	err := call(callCtx)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+	l.rec.observe(method, time.Since(start), err)
+	return err
+}
+
+// anyGame returns one of the running games, to ask for the server heap.
+func (l *load) anyGame() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for gameId := range l.active {
+		return gameId
+	}
+	return ""
+}
+
+func (l *load) started(gameId string) {
+	l.mutex.Lock()
+	l.active[gameId] = true
+	l.mutex.Unlock()
+	l.games.Add(1)
+	l.gamesStarted.Add(1)
+}
+
+func (l *load) ended(gameId string) {
+	l.mutex.Lock()
+	delete(l.active, gameId)
+	l.mutex.Unlock()
+	l.games.Add(-1)
+}
+
+// simulated is one websocket client playing one seat.
+type simulated struct {
+	load *load
+	c    *client.Client
+	seat int
+	rng  *rand.Rand
+}
+
+func (l *load) connect(ctx context.Context, seat int, seed int64) (*simulated, error) {
+	var c *client.Client
+	err := l.call(ctx, "connect", func(ctx context.Context) (err error) {
+		c, err = client.Dial(ctx, l.opts.url, client.Options{Token: l.opts.token})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.connected.Add(1)
+	go func() {
+		for e := range c.Events() {
+			if e.Method == client.EventReconnected {
+				l.reconnects.Add(1)
+			}
+		}
+	}()
+	return &simulated{load: l, c: c, seat: seat, rng: rand.New(rand.NewSource(seed))}, nil
+}
+
+func (s *simulated) close() {
+	s.c.Close()
+	s.load.connected.Add(-1)
+}
+
+// group is the players of one game at a time. The first one creates the game,
+// all of them join and play until it finishes, then the first one removes it
+// and creates the next.
+type group struct {
+	load    *load
+	players []*simulated
+}
+
+func (g *group) run(ctx context.Context) {
+	l := g.load
+	for ctx.Err() == nil {
+		var created *client.GameCreated
+		err := l.call(ctx, "create_game", func(ctx context.Context) (err error) {
+			created, err = g.players[0].c.CreateGame(ctx, client.NewGame{Size: l.opts.size, Autopilots: l.opts.bots})
+			return err
+		})
+		if err != nil {
+			// the hub may be full, wait for a slot
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		gameId := created.GameId
+		l.started(gameId)
+		round, finish := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		var finished atomic.Bool
+		for _, p := range g.players {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if p.play(round, gameId, len(g.players)) {
+					finished.Store(true)
+					finish()
+				}
+			}()
+		}
+		wg.Wait()
+		finish()
+		if finished.Load() {
+			l.gamesFinished.Add(1)
+		}
+		// remove the game even after the run ended so the server is left clean
+		l.call(context.WithoutCancel(ctx), "remove_game", func(ctx context.Context) error {
+			return g.players[0].c.RemoveGame(ctx, gameId)
+		})
+		l.ended(gameId)
+	}
+}
+
+// play joins the game and moves until ctx ends, reporting whether the game
+// finished.
+func (s *simulated) play(ctx context.Context, gameId string, seats int) bool {
+	l := s.load
+	var added *client.PlayerAdded
+	err := l.call(ctx, "join_game", func(ctx context.Context) (err error) {
+		added, err = s.c.JoinGame(ctx, client.PlayerJoin{GameId: gameId, PlayerName: fmt.Sprintf("load %d", s.seat)})
+		return err
+	})
+	if err != nil {
+		return false
+	}
+	next, _ := strategy.New(l.opts.strategy, strategy.Options{Rand: s.rng, Seat: s.seat, Seats: seats})
+	// the board of a simulated player only knows its own moves
+	bits := strategy.NewBits(l.opts.size)
+	var tick <-chan time.Time
+	if l.opts.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / l.opts.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		if tick != nil {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-tick:
+			}
+		} else if ctx.Err() != nil {
+			return false
+		}
+		var moved *client.PlayerMoved
+		index := next.Next(bits)
+		err := l.call(ctx, "player_move", func(ctx context.Context) (err error) {
+			moved, err = s.c.Move(ctx, gameId, added.PlayerId, index)
+			if err == nil && moved.GameId == "" {
+				err = errIgnored
+			}
+			return err
+		})
+		switch {
+		case err != nil && tick == nil:
+			// do not spin while the connection is down
+			select {
+			case <-ctx.Done():
+			case <-time.After(100 * time.Millisecond):
+			}
+		case err != nil:
+		case moved.GameStatus.IsFinished:
+			return true
+		case !moved.GameStatus.IsPaused:
+			bits.Set(index)
+			l.moves.Add(1)
+		}
+	}
+}
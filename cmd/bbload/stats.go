@@ -0,0 +1,182 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"cmp"
+	"context"
+	"errors"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Latencies go in buckets 5% apart from 1µs up to a few minutes, so the
+// percentiles are off by 5% at most whatever the length of the run.
+const (
+	bucketGrowth = 1.05
+	buckets      = 400
+)
+
+var logGrowth = math.Log(bucketGrowth)
+
+type histogram struct {
+	counts [buckets]uint64
+	count  uint64
+	sum    time.Duration
+	max    time.Duration
+}
+
+func (h *histogram) add(d time.Duration) {
+	us := max(1, float64(d)/float64(time.Microsecond))
+	bucket := min(buckets-1, int(math.Ceil(math.Log(us)/logGrowth)))
+	h.counts[bucket]++
+	h.count++
+	h.sum += d
+	h.max = max(h.max, d)
+}
+
+// percentile returns the upper bound of the bucket holding the p-th
+// percentile, p between 0 and 1.
+func (h *histogram) percentile(p float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(p * float64(h.count)))
+	var seen uint64
+	for bucket, n := range h.counts {
+		seen += n
+		if seen >= max(1, rank) {
+			bound := time.Duration(math.Pow(bucketGrowth, float64(bucket)) * float64(time.Microsecond))
+			return min(bound, h.max)
+		}
+	}
+	return h.max
+}
+
+func (h *histogram) mean() time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.count)
+}
+
+type methodStats struct {
+	total  histogram
+	window histogram
+	errors uint64
+	codes  map[string]uint64
+}
+
+// recorder keeps the latency and the errors of every call by method, for the
+// whole run and for the window since the last report.
+type recorder struct {
+	mutex        sync.Mutex
+	methods      map[string]*methodStats
+	windowCalls  uint64
+	windowErrors uint64
+}
+
+func newRecorder() *recorder {
+	return &recorder{methods: make(map[string]*methodStats)}
+}
+
+func (r *recorder) observe(method string, d time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	m, ok := r.methods[method]
+	if !ok {
+		m = &methodStats{codes: make(map[string]uint64)}
+		r.methods[method] = m
+	}
+	m.total.add(d)
+	m.window.add(d)
+	r.windowCalls++
+	if err != nil {
+		r.windowErrors++
+		m.errors++
+		m.codes[errorCode(err)]++
+	}
+}
+
+// flush returns the latencies of method and the calls and errors of every
+// method since the last flush, and starts a new window.
+func (r *recorder) flush(method string) (h histogram, calls uint64, errs uint64) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for name, m := range r.methods {
+		if name == method {
+			h = m.window
+		}
+		m.window = histogram{}
+	}
+	calls, errs = r.windowCalls, r.windowErrors
+	r.windowCalls, r.windowErrors = 0, 0
+	return h, calls, errs
+}
+
+type methodReport struct {
+	Method    string            `json:"method"`
+	Calls     uint64            `json:"calls"`
+	Errors    uint64            `json:"errors"`
+	ErrorRate float64           `json:"errorRate"`
+	PerSecond float64           `json:"perSecond"`
+	MeanMs    float64           `json:"meanMs"`
+	P50Ms     float64           `json:"p50Ms"`
+	P90Ms     float64           `json:"p90Ms"`
+	P99Ms     float64           `json:"p99Ms"`
+	MaxMs     float64           `json:"maxMs"`
+	Codes     map[string]uint64 `json:"codes,omitempty"`
+}
+
+func (r *recorder) report(elapsed time.Duration) []methodReport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	reports := make([]methodReport, 0, len(r.methods))
+	for method, m := range r.methods {
+		h := &m.total
+		reports = append(reports, methodReport{
+			Method:    method,
+			Calls:     h.count,
+			Errors:    m.errors,
+			ErrorRate: float64(m.errors) / float64(h.count),
+			PerSecond: float64(h.count) / elapsed.Seconds(),
+			MeanMs:    ms(h.mean()),
+			P50Ms:     ms(h.percentile(0.50)),
+			P90Ms:     ms(h.percentile(0.90)),
+			P99Ms:     ms(h.percentile(0.99)),
+			MaxMs:     ms(h.max),
+		})
+		if len(m.codes) > 0 {
+			reports[len(reports)-1].Codes = maps.Clone(m.codes)
+		}
+	}
+	slices.SortFunc(reports, func(a, b methodReport) int {
+		return cmp.Compare(b.Calls, a.Calls)
+	})
+	return reports
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// errorCode names an error by the server code, or by what went wrong on the
+// way when the server did not answer.
+func errorCode(err error) string {
+	var serverErr *client.Error
+	switch {
+	case errors.As(err, &serverErr):
+		return strconv.Itoa(serverErr.Code)
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, client.ErrDisconnected):
+		return "disconnected"
+	case errors.Is(err, errIgnored):
+		return "ignored"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"fmt"
+	"slices"
+	"time"
+)
+
+const feedSize = 200
+
+// flashFor is how long a flipped bit stays highlighted.
+const flashFor = time.Second
+
+// board is the game as the spectator feed tells it: the bits, the players
+// with the bits they flipped and a log of what happened.
+type board struct {
+	gameId   string
+	size     int
+	bits     []byte
+	on       int
+	delay    time.Duration
+	players  []*standing
+	byId     map[string]*standing
+	feed     []string
+	flashes  []flash
+	paused   bool
+	finished bool
+	winner   string
+}
+
+type standing struct {
+	playerId  string
+	name      string
+	autoPilot bool
+	left      bool
+	bits      int
+}
+
+type flash struct {
+	index int
+	at    time.Time
+}
+
+func newBoard(started *client.SpectateStarted) *board {
+	state := started.State
+	b := &board{
+		gameId: started.GameId,
+		size:   state.SizeGame,
+		bits:   make([]byte, (state.SizeGame+7)/8),
+		delay:  time.Duration(started.DelayMs) * time.Millisecond,
+		byId:   make(map[string]*standing),
+		paused: state.Paused,
+	}
+	copy(b.bits, state.Status)
+	for i := 0; i < b.size; i++ {
+		if b.isOn(i) {
+			b.on++
+		}
+	}
+	for _, p := range state.Players {
+		b.add(p.PlayerId, p.PlayerName, p.AutoPilot).bits = p.BitsFlipped
+		b.byId[p.PlayerId].left = p.Left
+	}
+	if state.HasFinished {
+		b.finished = true
+		b.winner = state.WinnerName
+	}
+	return b
+}
+
+func (b *board) add(playerId string, name string, autoPilot bool) *standing {
+	if s, ok := b.byId[playerId]; ok {
+		return s
+	}
+	s := &standing{playerId: playerId, name: name, autoPilot: autoPilot}
+	b.players = append(b.players, s)
+	b.byId[playerId] = s
+	return s
+}
+
+func (b *board) isOn(index int) bool {
+	return b.bits[index>>3]&(1<<(index&7)) != 0
+}
+
+// set turns a bit on, as the server never turns one off, and reports whether
+// it was off.
+func (b *board) set(index int, now time.Time) bool {
+	if index < 0 || index >= b.size || b.isOn(index) {
+		return false
+	}
+	b.bits[index>>3] |= 1 << (index & 7)
+	b.on++
+	b.flashes = append(b.flashes, flash{index: index, at: now})
+	return true
+}
+
+func (b *board) apply(e *client.GameEvent, now time.Time) {
+	t := e.Time.Local().Format(time.TimeOnly)
+	switch {
+	case e.PlayerAdded != nil:
+		b.add(e.PlayerAdded.PlayerId, e.PlayerAdded.PlayerName, e.PlayerAdded.AutoPilot).left = false
+		b.log("%s %s joined", t, e.PlayerAdded.PlayerName)
+	case e.PlayerRemoved != nil:
+		if s, ok := b.byId[e.PlayerRemoved.PlayerId]; ok {
+			s.left = true
+		}
+		b.log("%s %s left", t, b.name(e.PlayerRemoved.PlayerId))
+	case e.PlayerKicked != nil:
+		if s, ok := b.byId[e.PlayerKicked.PlayerId]; ok {
+			s.left = true
+		}
+		b.log("%s %s was kicked", t, b.name(e.PlayerKicked.PlayerId))
+	case e.PlayerMoved != nil:
+		if s, ok := b.byId[e.PlayerMoved.PlayerId]; ok {
+			s.bits++
+		}
+		// our own moves are already on the board
+		b.set(e.PlayerMoved.Index, now)
+		b.log("%s %s flipped %d", t, b.name(e.PlayerMoved.PlayerId), e.PlayerMoved.Index)
+	case e.GamePaused != nil:
+		b.paused = true
+		b.log("%s game paused", t)
+	case e.GameResumed != nil:
+		b.paused = false
+		b.log("%s game resumed", t)
+	case e.ChatMessage != nil:
+		b.log("%s <%s> %s", t, e.ChatMessage.SenderName, e.ChatMessage.Text)
+	case e.GameFinished != nil:
+		b.finished = true
+		b.winner = e.GameFinished.WinnerName
+		if b.winner == "" {
+			b.log("%s game finished, %s", t, e.GameFinished.Reason)
+		} else {
+			b.log("%s game finished, %s won", t, b.winner)
+		}
+	}
+}
+
+func (b *board) log(format string, args ...any) {
+	b.feed = append(b.feed, fmt.Sprintf(format, args...))
+	if len(b.feed) > feedSize {
+		b.feed = slices.Delete(b.feed, 0, len(b.feed)-feedSize)
+	}
+}
+
+func (b *board) name(playerId string) string {
+	if s, ok := b.byId[playerId]; ok {
+		return s.name
+	}
+	return playerId
+}
+
+// cells returns how many cells the board takes with zoom bits per cell.
+func (b *board) cells(zoom int) int {
+	return (b.size + zoom - 1) / zoom
+}
+
+// density returns the share of bits on in a cell.
+func (b *board) density(cell int, zoom int) float64 {
+	from, to := cell*zoom, min((cell+1)*zoom, b.size)
+	on := 0
+	for i := from; i < to; i++ {
+		if b.isOn(i) {
+			on++
+		}
+	}
+	return float64(on) / float64(to-from)
+}
+
+// firstOff returns the first bit off in a cell, or -1 when it is full.
+func (b *board) firstOff(cell int, zoom int) int {
+	for i := cell * zoom; i < min((cell+1)*zoom, b.size); i++ {
+		if !b.isOn(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// flashing returns the cells with a bit flipped lately and drops the old
+// flashes.
+func (b *board) flashing(zoom int, now time.Time) map[int]bool {
+	b.flashes = slices.DeleteFunc(b.flashes, func(f flash) bool {
+		return now.Sub(f.at) > flashFor
+	})
+	cells := make(map[int]bool, len(b.flashes))
+	for _, f := range b.flashes {
+		cells[f.index/zoom] = true
+	}
+	return cells
+}
+
+func (b *board) ranking() []*standing {
+	ranking := slices.Clone(b.players)
+	slices.SortStableFunc(ranking, func(x, y *standing) int {
+		return y.bits - x.bits
+	})
+	return ranking
+}
@@ -0,0 +1,78 @@
+package main
+
+import "io"
+
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyLeft      = "left"
+	keyRight     = "right"
+	keyPageUp    = "pgup"
+	keyPageDown  = "pgdn"
+	keyEnter     = "enter"
+	keyBackspace = "backspace"
+	keyEsc       = "esc"
+	keyQuit      = "quit"
+)
+
+var escapes = map[string]string{
+	"[A": keyUp, "[B": keyDown, "[C": keyRight, "[D": keyLeft,
+	"OA": keyUp, "OB": keyDown, "OC": keyRight, "OD": keyLeft,
+	"[5~": keyPageUp, "[6~": keyPageDown,
+}
+
+// readKeys sends the keys typed on r until it fails. Printable keys are sent
+// as themselves.
+func readKeys(r io.Reader, keys chan<- string) {
+	defer close(keys)
+	buf := make([]byte, 64)
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+func decodeKeys(p []byte) []string {
+	keys := make([]string, 0, len(p))
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case c == 0x1b:
+			key, size := decodeEscape(p[i+1:])
+			keys = append(keys, key)
+			i += size
+		case c == '\r' || c == '\n':
+			keys = append(keys, keyEnter)
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, keyBackspace)
+		case c == 0x03 || c == 0x04:
+			keys = append(keys, keyQuit)
+		case c >= 0x20 && c < 0x7f:
+			keys = append(keys, string(rune(c)))
+		}
+	}
+	return keys
+}
+
+// decodeEscape reads the sequence after an escape byte. A lone escape, or
+// one we do not know, is the escape key.
+func decodeEscape(p []byte) (string, int) {
+	for seq, key := range escapes {
+		if len(p) >= len(seq) && string(p[:len(seq)]) == seq {
+			return key, len(seq)
+		}
+	}
+	if len(p) >= 2 && (p[0] == '[' || p[0] == 'O') {
+		// skip the unknown sequence up to its final byte
+		for i := 1; i < len(p); i++ {
+			if p[i] >= 0x40 && p[i] <= 0x7e {
+				return "", i + 1
+			}
+		}
+	}
+	return keyEsc, 0
+}
@@ -0,0 +1,178 @@
+// Command bbtui plays or watches a battlebit game in a terminal.
+package main
+
+import (
+	"battlebit/pkg/client"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// frameEvery limits redraws while the feed is busy.
+const frameEvery = 50 * time.Millisecond
+
+type options struct {
+	url      string
+	token    string
+	gameId   string
+	invite   string
+	password string
+	name     string
+	watch    bool
+	zoom     int
+}
+
+func main() {
+	opts := new(options)
+	url := os.Getenv("BBCTL_URL")
+	if url == "" {
+		url = "ws://localhost:8080/ws"
+	}
+	flag.StringVar(&opts.url, "url", url, "server websocket (env BBCTL_URL)")
+	flag.StringVar(&opts.token, "token", os.Getenv("BBCTL_TOKEN"), "player token (env BBCTL_TOKEN)")
+	flag.StringVar(&opts.gameId, "game", "", "game to open, pick one from a list when empty")
+	flag.StringVar(&opts.invite, "invite", "", "invite code of an unlisted game")
+	flag.StringVar(&opts.password, "password", "", "game password")
+	flag.StringVar(&opts.name, "name", os.Getenv("USER"), "player name, the token decides it when authenticated")
+	flag.BoolVar(&opts.watch, "watch", false, "spectate without joining")
+	flag.IntVar(&opts.zoom, "zoom", 0, "bits per cell, fit the screen when 0")
+	flag.Parse()
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, "bbtui:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts *options) error {
+	fd := int(os.Stdin.Fd())
+	if _, _, err := termSize(fd); err != nil {
+		return fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	c, err := client.Dial(ctx, opts.url, client.Options{Token: opts.token, EventBuffer: 4096})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("connecting to %s: %w", opts.url, err)
+	}
+	defer c.Close()
+
+	restore, err := makeRaw(fd)
+	if err != nil {
+		return err
+	}
+	// alternate screen, hidden cursor
+	os.Stdout.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		os.Stdout.WriteString("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+	keys := make(chan string, 16)
+	go readKeys(os.Stdin, keys)
+
+	if opts.gameId == "" && opts.invite == "" {
+		gameId, err := pickGame(c, fd, keys)
+		if err != nil || gameId == "" {
+			return err
+		}
+		opts.gameId = gameId
+	}
+	u := &ui{c: c, zoom: opts.zoom, moves: make(chan moveResult, 16)}
+	if !opts.watch {
+		added, err := join(c, opts)
+		if err != nil {
+			return err
+		}
+		u.playerId, u.playerName = added.PlayerId, added.PlayerName
+		opts.gameId = added.GameId
+		defer func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			c.LeaveGame(ctx, added.GameId, added.PlayerId)
+		}()
+	}
+	if u.board, err = spectate(c, opts); err != nil {
+		return err
+	}
+	u.width, u.height, _ = termSize(fd)
+	if u.zoom <= 0 {
+		u.zoom = u.fitZoom()
+	}
+	u.setZoom(u.zoom)
+	return loop(u, fd, keys, opts)
+}
+
+func loop(u *ui, fd int, keys <-chan string, opts *options) error {
+	ticker := time.NewTicker(frameEvery)
+	defer ticker.Stop()
+	dirty := true
+	for {
+		select {
+		case k, ok := <-keys:
+			if !ok || u.key(k) {
+				return nil
+			}
+			dirty = true
+		case r := <-u.moves:
+			u.moved(r)
+			dirty = true
+		case e, ok := <-u.c.Events():
+			if !ok {
+				return errors.New("connection closed")
+			}
+			if u.event(e) {
+				u.status = "reconnected"
+				b, err := spectate(u.c, opts)
+				if err != nil {
+					u.status = "reconnected, but the feed did not start again: " + err.Error()
+					continue
+				}
+				b.feed = append(u.board.feed, "reconnected")
+				u.board = b
+			}
+			dirty = true
+			continue
+		case <-ticker.C:
+			dirty = dirty || len(u.board.flashes) > 0
+		}
+		if !dirty {
+			continue
+		}
+		if w, h, err := termSize(fd); err == nil {
+			u.width, u.height = w, h
+		}
+		os.Stdout.WriteString(u.render(time.Now()))
+		dirty = false
+	}
+}
+
+func join(c *client.Client, opts *options) (*client.PlayerAdded, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	added, err := c.JoinGame(ctx, client.PlayerJoin{
+		GameId:     opts.gameId,
+		PlayerName: opts.name,
+		InviteCode: opts.invite,
+		Password:   opts.password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("joining the game: %w", err)
+	}
+	return added, nil
+}
+
+func spectate(c *client.Client, opts *options) (*board, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	sg := client.SpectateGame{GameId: opts.gameId, Password: opts.password}
+	if sg.GameId == "" {
+		sg.InviteCode = opts.invite
+	}
+	started, err := c.Spectate(ctx, sg)
+	if err != nil {
+		return nil, fmt.Errorf("watching the game: %w", err)
+	}
+	return newBoard(started), nil
+}
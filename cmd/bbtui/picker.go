@@ -0,0 +1,94 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// pickGame lists the games until the user picks one, or quits with an empty
+// game id.
+func pickGame(c *client.Client, fd int, keys <-chan string) (string, error) {
+	selected := 0
+	var games []*client.GameMetrics
+	status := ""
+	refresh := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		list, err := c.ListGames(ctx)
+		if err != nil {
+			status = err.Error()
+			return
+		}
+		games, status = list, ""
+	}
+	refresh()
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+	for {
+		width, height, _ := termSize(fd)
+		selected = max(0, min(selected, len(games)-1))
+		os.Stdout.WriteString(renderPicker(games, selected, status, width, height))
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return "", nil
+			}
+			switch k {
+			case keyQuit, "q":
+				return "", nil
+			case keyUp, "k":
+				selected--
+			case keyDown, "j":
+				selected++
+			case "r":
+				refresh()
+			case keyEnter:
+				if len(games) > 0 {
+					return games[selected].GameId, nil
+				}
+			}
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+func renderPicker(games []*client.GameMetrics, selected int, status string, width int, height int) string {
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	line := func(s string, highlight bool) {
+		if highlight {
+			sb.WriteString(ansiReverse)
+		}
+		sb.WriteString(fit(s, width-1))
+		sb.WriteString(ansiReset + "\x1b[K\r\n")
+	}
+	line("battlebit  pick a game", false)
+	line(fmt.Sprintf("  %-36s  %6s  %7s  %4s  %-8s  %s", "GAME", "SIZE", "PLAYERS", "BOTS", "STATUS", "DURATION"), false)
+	rows := max(1, height-4)
+	top := max(0, selected-rows+1)
+	for i := top; i < min(len(games), top+rows); i++ {
+		m := games[i]
+		state := "running"
+		switch {
+		case m.GameStatus.IsFinished:
+			state = "finished"
+		case m.GameStatus.IsPaused:
+			state = "paused"
+		}
+		line(fmt.Sprintf("  %-36s  %6d  %7d  %4d  %-8s  %s", m.GameId, m.SizeGame, m.Players, m.AutoPilots, state, m.CurrentDuration), i == selected)
+	}
+	if len(games) == 0 {
+		line("  no games yet, create one with bbctl games create", false)
+	}
+	if status != "" {
+		line(status, false)
+	}
+	sb.WriteString("\x1b[J")
+	sb.WriteString(fit("arrows choose  enter open  r refresh  q quit", width-1))
+	return sb.String()
+}
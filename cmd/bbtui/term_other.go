@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+func makeRaw(fd int) (func(), error) {
+	return nil, errors.New("bbtui needs a unix terminal")
+}
+
+func termSize(fd int) (width int, height int, err error) {
+	return 0, 0, errors.New("bbtui needs a unix terminal")
+}
@@ -0,0 +1,356 @@
+package main
+
+import (
+	"battlebit/pkg/client"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	labelWidth = 7
+	panelWidth = 36
+	maxColumns = 64
+)
+
+const (
+	ansiReset   = "\x1b[0m"
+	ansiReverse = "\x1b[7m"
+	ansiGreen   = "\x1b[32m"
+	ansiFlash   = "\x1b[1;33m"
+	ansiDim     = "\x1b[2m"
+)
+
+type moveResult struct {
+	index int
+	moved *client.PlayerMoved
+	err   error
+}
+
+// ui draws the board as a grid of cells of zoom bits each, shaded by how many
+// of their bits are on, next to the players and the event feed.
+type ui struct {
+	c          *client.Client
+	board      *board
+	playerId   string
+	playerName string
+	zoom       int
+	cursor     int
+	top        int
+	input      string
+	status     string
+	width      int
+	height     int
+	moves      chan moveResult
+}
+
+func (u *ui) gridColumns() int {
+	return max(8, min(maxColumns, u.width-labelWidth-panelWidth-2))
+}
+
+func (u *ui) gridRows() int {
+	return max(1, u.height-3)
+}
+
+// fitZoom returns the smallest zoom that shows the whole board.
+func (u *ui) fitZoom() int {
+	zoom := 1
+	for (u.board.cells(zoom)+u.gridColumns()-1)/u.gridColumns() > u.gridRows() {
+		zoom *= 2
+	}
+	return zoom
+}
+
+// setZoom keeps the cursor on the same bit.
+func (u *ui) setZoom(zoom int) {
+	zoom = max(1, min(zoom, u.board.size))
+	bit := u.cursor * u.zoom
+	u.zoom = zoom
+	u.cursor = bit / zoom
+}
+
+// key handles a key and reports whether the user quits.
+func (u *ui) key(k string) bool {
+	cols := u.gridColumns()
+	switch k {
+	case keyQuit, "q":
+		return true
+	case keyLeft, "h":
+		u.cursor--
+	case keyRight, "l":
+		u.cursor++
+	case keyUp, "k":
+		u.cursor -= cols
+	case keyDown, "j":
+		u.cursor += cols
+	case keyPageUp:
+		u.cursor -= cols * u.gridRows()
+	case keyPageDown:
+		u.cursor += cols * u.gridRows()
+	case "+", "=":
+		u.setZoom(u.zoom / 2)
+	case "-", "_":
+		u.setZoom(u.zoom * 2)
+	case "z":
+		u.setZoom(u.fitZoom())
+	case keyBackspace:
+		if u.input != "" {
+			u.input = u.input[:len(u.input)-1]
+		}
+	case keyEsc:
+		u.input = ""
+	case "g":
+		if index, ok := u.typedIndex(); ok {
+			u.cursor = index / u.zoom
+		}
+	case keyEnter, " ":
+		if u.input != "" {
+			if index, ok := u.typedIndex(); ok {
+				u.move(index)
+			}
+			break
+		}
+		if index := u.board.firstOff(u.cursor, u.zoom); index >= 0 {
+			u.move(index)
+		} else {
+			u.status = "every bit of the cell is on"
+		}
+	default:
+		if len(k) == 1 && k[0] >= '0' && k[0] <= '9' && len(u.input) < 10 {
+			u.input += k
+		}
+	}
+	u.cursor = max(0, min(u.cursor, u.board.cells(u.zoom)-1))
+	return false
+}
+
+func (u *ui) typedIndex() (int, bool) {
+	index, err := strconv.Atoi(u.input)
+	u.input = ""
+	if err != nil || index >= u.board.size {
+		u.status = fmt.Sprintf("index must be between 0 and %d", u.board.size-1)
+		return 0, false
+	}
+	return index, true
+}
+
+// move sends the move without blocking the screen, the result comes back on
+// u.moves.
+func (u *ui) move(index int) {
+	switch {
+	case u.playerId == "":
+		u.status = "watching only, run without -watch to play"
+		return
+	case u.board.finished:
+		u.status = "the game is finished"
+		return
+	}
+	gameId, playerId := u.board.gameId, u.playerId
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		moved, err := u.c.Move(ctx, gameId, playerId, index)
+		u.moves <- moveResult{index: index, moved: moved, err: err}
+	}()
+}
+
+func (u *ui) moved(r moveResult) {
+	switch {
+	case r.err != nil:
+		u.status = r.err.Error()
+	case r.moved.GameId == "":
+		u.status = fmt.Sprintf("move %d ignored", r.index)
+	case r.moved.GameStatus.IsPaused:
+		u.status = "the game is paused"
+	case u.board.set(r.index, time.Now()):
+		u.status = fmt.Sprintf("flipped %d", r.index)
+	default:
+		u.status = fmt.Sprintf("%d was already on", r.index)
+	}
+}
+
+// event applies a notification and reports whether the feed must be started
+// again on a new connection.
+func (u *ui) event(e *client.Event) bool {
+	switch e.Method {
+	case client.EventSpectatorEvent:
+		se := new(client.SpectatorEvent)
+		if err := e.Decode(se); err != nil || se.Event == nil || se.GameId != u.board.gameId {
+			return false
+		}
+		u.board.apply(se.Event, time.Now())
+	case client.EventSpectateFinished:
+		u.board.log("feed ended")
+	case client.EventServerShutdown:
+		u.status = "the server is shutting down"
+	case client.EventReconnected:
+		return true
+	}
+	return false
+}
+
+func (u *ui) render(now time.Time) string {
+	b := u.board
+	cols, rows := u.gridColumns(), u.gridRows()
+	cells := b.cells(u.zoom)
+	totalRows := (cells + cols - 1) / cols
+	row := u.cursor / cols
+	if row < u.top {
+		u.top = row
+	}
+	if row >= u.top+rows {
+		u.top = row - rows + 1
+	}
+	u.top = max(0, min(u.top, totalRows-rows))
+	flashing := b.flashing(u.zoom, now)
+	panel := u.panel(rows)
+
+	var sb strings.Builder
+	sb.WriteString("\x1b[H")
+	sb.WriteString(fit(u.header(), u.width-1))
+	sb.WriteString("\x1b[K\r\n")
+	for i := 0; i < rows; i++ {
+		r := u.top + i
+		if r < totalRows {
+			fmt.Fprintf(&sb, "%s%*d%s ", ansiDim, labelWidth-1, r*cols*u.zoom, ansiReset)
+			for c := 0; c < cols; c++ {
+				cell := r*cols + c
+				if cell >= cells {
+					sb.WriteByte(' ')
+					continue
+				}
+				u.writeCell(&sb, cell, flashing[cell])
+			}
+		} else {
+			sb.WriteString(strings.Repeat(" ", labelWidth+cols))
+		}
+		sb.WriteString("  ")
+		sb.WriteString(fit(panel[i], panelWidth))
+		sb.WriteString("\x1b[K\r\n")
+	}
+	sb.WriteString(fit(u.statusLine(), u.width-1))
+	sb.WriteString("\x1b[K\r\n")
+	sb.WriteString(fit(u.helpLine(), u.width-1))
+	sb.WriteString("\x1b[K\x1b[J")
+	return sb.String()
+}
+
+func (u *ui) writeCell(sb *strings.Builder, cell int, flashing bool) {
+	d := u.board.density(cell, u.zoom)
+	shade := "·"
+	switch {
+	case d >= 1:
+		shade = "█"
+	case d >= 0.66:
+		shade = "▓"
+	case d >= 0.33:
+		shade = "▒"
+	case d > 0:
+		shade = "░"
+	}
+	switch {
+	case cell == u.cursor:
+		sb.WriteString(ansiReverse)
+	case flashing:
+		sb.WriteString(ansiFlash)
+	case d > 0:
+		sb.WriteString(ansiGreen)
+	default:
+		sb.WriteString(ansiDim)
+	}
+	sb.WriteString(shade)
+	sb.WriteString(ansiReset)
+}
+
+func (u *ui) header() string {
+	b := u.board
+	state := "running"
+	switch {
+	case b.finished && b.winner != "":
+		state = "finished, " + b.winner + " won"
+	case b.finished:
+		state = "finished"
+	case b.paused:
+		state = "paused"
+	}
+	who := "watching"
+	if u.playerId != "" {
+		who = "playing as " + u.playerName
+	}
+	header := fmt.Sprintf("battlebit %s  %s  %d/%d on (%.0f%%)  %d bit(s)/cell  %s",
+		b.gameId, who, b.on, b.size, 100*float64(b.on)/float64(b.size), u.zoom, state)
+	if b.delay > 0 {
+		header += fmt.Sprintf("  delayed %s", b.delay)
+	}
+	return header
+}
+
+func (u *ui) panel(rows int) []string {
+	lines := make([]string, 0, rows)
+	lines = append(lines, "PLAYERS")
+	ranking := u.board.ranking()
+	shown := min(len(ranking), max(1, rows/2-2))
+	for _, s := range ranking[:shown] {
+		marker := " "
+		if s.playerId == u.playerId {
+			marker = ">"
+		}
+		name := s.name
+		switch {
+		case s.left:
+			name += " (left)"
+		case s.autoPilot:
+			name += " (bot)"
+		}
+		lines = append(lines, fmt.Sprintf("%s %-26s %6d", marker, fit(name, 26), s.bits))
+	}
+	if hidden := len(ranking) - shown; hidden > 0 {
+		lines = append(lines, fmt.Sprintf("  and %d more", hidden))
+	}
+	lines = append(lines, "", "EVENTS")
+	feed := u.board.feed
+	if room := rows - len(lines); len(feed) > room {
+		feed = feed[len(feed)-max(0, room):]
+	}
+	lines = append(lines, feed...)
+	for len(lines) < rows {
+		lines = append(lines, "")
+	}
+	return lines[:rows]
+}
+
+func (u *ui) statusLine() string {
+	b := u.board
+	from, to := u.cursor*u.zoom, min((u.cursor+1)*u.zoom, b.size)-1
+	where := fmt.Sprintf("bit %d", from)
+	if to > from {
+		where = fmt.Sprintf("bits %d-%d", from, to)
+	}
+	line := fmt.Sprintf("%s: %.0f%% on", where, 100*b.density(u.cursor, u.zoom))
+	if u.status != "" {
+		line += "  |  " + u.status
+	}
+	return line
+}
+
+func (u *ui) helpLine() string {
+	if u.input != "" {
+		return fmt.Sprintf("index %s_   enter flip  g go to  esc cancel", u.input)
+	}
+	if u.playerId == "" {
+		return "arrows move  +/- zoom  z fit  type an index and g to go to it  q quit"
+	}
+	return "arrows move  enter flip  +/- zoom  z fit  type an index and enter to flip it  q quit"
+}
+
+// fit cuts s to width runes and pads it to width.
+func fit(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n > width {
+		return string([]rune(s)[:max(0, width)])
+	}
+	return s + strings.Repeat(" ", width-n)
+}
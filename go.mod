@@ -4,4 +4,4 @@ go 1.22.0
 
 require github.com/google/uuid v1.6.0
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"battlebit/internal/contextkey"
+	"context"
+)
+
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextkey.IdentityCtx, id)
+}
+
+func GetIdentity(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(contextkey.IdentityCtx).(*Identity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,18 @@
+package auth
+
+import "time"
+
+type Claims struct {
+	Subject   string `json:"sub"`
+	Name      string `json:"name"`
+	Role      Role   `json:"role,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type Identity struct {
+	AccountId string    `json:"accountId"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
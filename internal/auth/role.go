@@ -0,0 +1,32 @@
+package auth
+
+import "fmt"
+
+type Role string
+
+const (
+	RoleSpectator Role = "spectator"
+	RolePlayer    Role = "player"
+	RoleHost      Role = "host"
+	RoleAdmin     Role = "admin"
+)
+
+// every role can do everything the roles ranked below it can
+var roleRank = map[Role]int{
+	RoleSpectator: 1,
+	RolePlayer:    2,
+	RoleHost:      3,
+	RoleAdmin:     4,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q, want spectator, player, host or admin", s)
+	}
+	return role, nil
+}
+
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
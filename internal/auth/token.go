@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// header is fixed, tokens signed with anything but HS256 are rejected.
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+type Signer struct {
+	secret []byte
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewSigner(secret string, ttl time.Duration) *Signer {
+	return &Signer{secret: []byte(secret), ttl: ttl, now: time.Now}
+}
+
+// Issue signs a token for the account, generating an account id when empty.
+func (s *Signer) Issue(accountId string, name string, role Role) (string, *Identity, error) {
+	if name == "" {
+		return "", nil, fmt.Errorf("name is required")
+	}
+	if _, ok := roleRank[role]; !ok {
+		return "", nil, fmt.Errorf("unknown role %q", role)
+	}
+	if accountId == "" {
+		accountId = uuid.New().String()
+	}
+	now := s.now()
+	c := &Claims{
+		Subject:   accountId,
+		Name:      name,
+		Role:      role,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(s.ttl).Unix(),
+	}
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", nil, err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + s.sign(unsigned), c.identity(), nil
+}
+
+func (s *Signer) Verify(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return nil, ErrInvalidToken
+	}
+	unsigned := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(unsigned))) {
+		return nil, ErrInvalidToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	c := new(Claims)
+	if err := json.Unmarshal(payload, c); err != nil || c.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	if s.now().Unix() >= c.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return c.identity(), nil
+}
+
+func (s *Signer) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (c *Claims) identity() *Identity {
+	role := c.Role
+	if _, ok := roleRank[role]; !ok {
+		role = RolePlayer
+	}
+	return &Identity{AccountId: c.Subject, Name: c.Name, Role: role, ExpiresAt: time.Unix(c.ExpiresAt, 0).UTC()}
+}
@@ -0,0 +1,84 @@
+package bb
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+)
+
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPassword = "password"
+)
+
+// SetAccess must be called before the game starts. Only a salted hash of the
+// password is kept.
+func (g *Game) SetAccess(visibility string, password string) error {
+	switch visibility {
+	case "", VisibilityPublic, VisibilityUnlisted:
+		if password != "" {
+			return fmt.Errorf("password requires visibility %q", VisibilityPassword)
+		}
+	case VisibilityPassword:
+		if password == "" {
+			return fmt.Errorf("visibility %q requires a password", VisibilityPassword)
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return err
+		}
+		g.passwordSalt = hex.EncodeToString(salt)
+		g.passwordHash = hashPassword(g.passwordSalt, password)
+	default:
+		return fmt.Errorf("unknown visibility %q, want public, unlisted or password", visibility)
+	}
+	if visibility == "" {
+		visibility = VisibilityPublic
+	}
+	g.Visibility = visibility
+	return nil
+}
+
+func (g *Game) CheckPassword(password string) bool {
+	if g.Visibility != VisibilityPassword {
+		return true
+	}
+	hash := hashPassword(g.passwordSalt, password)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(g.passwordHash)) == 1
+}
+
+// HasMember tells whether a player of the game joined with the account, or
+// anonymously from the connection.
+func (g *Game) HasMember(accountId string, connection string) bool {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	for _, p := range g.Players {
+		if p.AutoPilot {
+			continue
+		}
+		if (p.AccountId != "" && p.AccountId == accountId) || (p.AccountId == "" && p.PlayerConnection == connection) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Game) SetInviteCode(code string) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.inviteCode = code
+}
+
+func (g *Game) InviteCode() string {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return g.inviteCode
+}
+
+func hashPassword(salt string, password string) string {
+	sum := sha256.Sum256([]byte(salt + password))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,113 @@
+package bb
+
+import (
+	"battlebit/internal/log"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrPlayerMuted = errors.New("player is muted")
+
+// ChatHistorySize is how many of the last messages of a game ChatHistory
+// keeps.
+const ChatHistorySize = 200
+
+// SendChat adds a message to the game log. Messages from a player need the
+// player in the game and not muted; without a player id they come from a host.
+func (g *Game) SendChat(ctx context.Context, msg *ChatMessage) (*Event, error) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	msg.GameId = g.GameId
+	if msg.PlayerId != "" {
+		p, err := g.GetPlayerById(ctx, msg.PlayerId)
+		if err != nil {
+			return nil, err
+		}
+		if g.isMuted(p.PlayerId, p.AccountId) {
+			return nil, ErrPlayerMuted
+		}
+		msg.SenderName = p.PlayerName
+	}
+	event := &Event{Type: EventChatMessage, ChatMessage: msg}
+	g.record(ctx, event)
+	return event, nil
+}
+
+func (g *Game) MutePlayer(ctx context.Context, playerId string, muted bool) (*PlayerMuted, error) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	p, err := g.GetPlayerById(ctx, playerId)
+	if err != nil {
+		return nil, err
+	}
+	if p.AutoPilot {
+		return nil, fmt.Errorf("autopilots do not chat")
+	}
+	pm := &PlayerMuted{GameId: g.GameId, PlayerId: p.PlayerId, AccountId: p.AccountId, Muted: muted}
+	g.mute(pm)
+	g.record(ctx, &Event{Type: EventPlayerMuted, PlayerMuted: pm})
+	g.persist(ctx)
+	log.GetLogger(ctx).Info("Player muted", "gameId", g.GameId, "playerId", p.PlayerId, "muted", muted)
+	return pm, nil
+}
+
+// mute must be called with playerMutex held.
+func (g *Game) mute(pm *PlayerMuted) {
+	if g.mutedPlayers == nil {
+		g.mutedPlayers = make(map[string]bool)
+		g.mutedAccounts = make(map[string]bool)
+	}
+	if pm.Muted {
+		if pm.PlayerId != "" {
+			g.mutedPlayers[pm.PlayerId] = true
+		}
+		if pm.AccountId != "" {
+			g.mutedAccounts[pm.AccountId] = true
+		}
+		return
+	}
+	delete(g.mutedPlayers, pm.PlayerId)
+	delete(g.mutedAccounts, pm.AccountId)
+}
+
+// isMuted must be called with playerMutex held.
+func (g *Game) isMuted(playerId string, accountId string) bool {
+	return g.mutedPlayers[playerId] || (accountId != "" && g.mutedAccounts[accountId])
+}
+
+// ChatHistory returns the last chat messages logged after afterSeq.
+func (g *Game) ChatHistory(afterSeq uint64) []*Event {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return eventsAfter(g.chat, afterSeq)
+}
+
+// DelayedChat is ChatHistory as spectators may read it at now, like
+// DelayedEvents.
+func (g *Game) DelayedChat(afterSeq uint64, now time.Time) []*Event {
+	g.playerMutex.Lock()
+	cutoff := now.Add(-g.SpectatorDelay)
+	finished := g.Game.HasFinished
+	messages := eventsAfter(g.chat, afterSeq)
+	g.playerMutex.Unlock()
+	if finished {
+		return messages
+	}
+	for i, event := range messages {
+		if event.Time.After(cutoff) {
+			return messages[:i]
+		}
+	}
+	return messages
+}
+
+// keepChat must be called with playerMutex held.
+func (g *Game) keepChat(event *Event) {
+	if len(g.chat) == ChatHistorySize {
+		copy(g.chat, g.chat[1:])
+		g.chat = g.chat[:len(g.chat)-1]
+	}
+	g.chat = append(g.chat, event)
+}
@@ -0,0 +1,73 @@
+package bb
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+}
+
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// countingSource counts the values drawn from the seeded source, so a restored
+// game can fast forward to the same point of the sequence.
+type countingSource struct {
+	source rand.Source64
+	draws  atomic.Uint64
+}
+
+func newCountingSource(seed int64, draws uint64) *countingSource {
+	cs := &countingSource{source: rand.NewSource(seed).(rand.Source64)}
+	for i := uint64(0); i < draws; i++ {
+		cs.source.Uint64()
+	}
+	cs.draws.Store(draws)
+	return cs
+}
+
+// skip draws from the source until draws values were drawn.
+func (cs *countingSource) skip(draws uint64) {
+	for cs.draws.Load() < draws {
+		cs.Uint64()
+	}
+}
+
+func (cs *countingSource) Int63() int64 {
+	cs.draws.Add(1)
+	return cs.source.Int63()
+}
+
+func (cs *countingSource) Uint64() uint64 {
+	cs.draws.Add(1)
+	return cs.source.Uint64()
+}
+
+func (cs *countingSource) Seed(seed int64) {
+	cs.draws.Store(0)
+	cs.source.Seed(seed)
+}
+
+func (g *Game) SetClock(clock Clock) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.clock = clock
+}
+
+func (g *Game) SetSeed(seed int64) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.seedRandom(seed, 0)
+}
+
+func (g *Game) seedRandom(seed int64, draws uint64) {
+	g.Seed = seed
+	g.source = newCountingSource(seed, draws)
+	g.rng = rand.New(g.source)
+}
@@ -3,10 +3,17 @@ package bb
 import "time"
 
 type GameStarted struct {
-	GameId           string    `json:"gameId"`
-	SizeGame         int       `json:"sizeGame"`
-	InitTime         time.Time `json:"initTime"`
-	NumberAutoPilots int       `json:"numberAutoPilots"`
+	GameId           string        `json:"gameId"`
+	SizeGame         int           `json:"sizeGame"`
+	InitTime         time.Time     `json:"initTime"`
+	NumberAutoPilots int           `json:"numberAutoPilots"`
+	DelayAutoPilots  int           `json:"delayAutoPilots"`
+	Seed             int64         `json:"seed"`
+	CreatorId        string        `json:"creatorId,omitempty"`
+	Mode             string        `json:"mode,omitempty"`
+	MaxPlayers       int           `json:"maxPlayers,omitempty"`
+	Visibility       string        `json:"visibility,omitempty"`
+	SpectatorDelay   time.Duration `json:"spectatorDelay,omitempty"`
 }
 
 type GameFinished struct {
@@ -17,12 +24,53 @@ type GameFinished struct {
 	WinnerId         string        `json:"winnerId"`
 	WinnerName       string        `json:"winnerName"`
 	Duration         time.Duration `json:"duration"`
+	Reason           string        `json:"reason"`
+}
+
+// GameResult is handed to the finish hook once a game ends. Players holds
+// everyone who took part, including those who left.
+type GameResult struct {
+	GameId     string
+	Reason     string
+	WinnerId   string
+	Duration   time.Duration
+	FinishedAt time.Time
+	CreatorId  string
+	Finished   *GameFinished
+	Config     GameConfig
+	Players    []Participant
 }
 
+type GameConfig struct {
+	Mode            string `json:"mode"`
+	Size            int    `json:"size"`
+	AutoPilots      int    `json:"autoPilots"`
+	DelayAutoPilots int    `json:"delayAutoPilots"`
+	MaxPlayers      int    `json:"maxPlayers"`
+	Visibility      string `json:"visibility"`
+	Seed            int64  `json:"seed"`
+}
+
+type Participant struct {
+	PlayerId    string `json:"playerId"`
+	PlayerName  string `json:"playerName"`
+	AccountId   string `json:"accountId,omitempty"`
+	AutoPilot   bool   `json:"autoPilot"`
+	BitsFlipped int    `json:"bitsFlipped"`
+	Left        bool   `json:"left,omitempty"`
+}
+
+const DefaultMaxPlayers = 10
+
+const FinishReasonCompleted = "completed"
+const FinishReasonShutdown = "aborted: server shutting down"
+
 type PlayerAdded struct {
 	GameId     string `json:"gameId"`
 	PlayerId   string `json:"playerId"`
 	PlayerName string `json:"playerName"`
+	AccountId  string `json:"accountId,omitempty"`
+	AutoPilot  bool   `json:"autoPilot"`
 }
 
 type PlayerRemoved struct {
@@ -30,6 +78,40 @@ type PlayerRemoved struct {
 	PlayerId string `json:"playerId"`
 }
 
+type PlayerKicked struct {
+	GameId     string `json:"gameId"`
+	PlayerId   string `json:"playerId"`
+	Reason     string `json:"reason,omitempty"`
+	Banned     bool   `json:"banned"`
+	AccountId  string `json:"accountId,omitempty"`
+	Connection string `json:"connection,omitempty"`
+}
+
+type GamePaused struct {
+	GameId string `json:"gameId"`
+}
+
+type GameResumed struct {
+	GameId    string        `json:"gameId"`
+	PausedFor time.Duration `json:"pausedFor"`
+}
+
+type ChatMessage struct {
+	GameId     string `json:"gameId"`
+	PlayerId   string `json:"playerId,omitempty"`
+	SenderId   string `json:"senderId,omitempty"`
+	SenderName string `json:"senderName"`
+	Text       string `json:"text"`
+	Filtered   bool   `json:"filtered,omitempty"`
+}
+
+type PlayerMuted struct {
+	GameId    string `json:"gameId"`
+	PlayerId  string `json:"playerId"`
+	AccountId string `json:"accountId,omitempty"`
+	Muted     bool   `json:"muted"`
+}
+
 type PlayerMoved struct {
 	GameId     string     `json:"gameId"`
 	PlayerId   string     `json:"playerId"`
@@ -41,6 +123,7 @@ type PlayerMoved struct {
 type GameStatus struct {
 	IsInProcess bool `json:"isInProcess"`
 	IsFinished  bool `json:"isFinished"`
+	IsPaused    bool `json:"isPaused,omitempty"`
 }
 
 type GameMetrics struct {
@@ -54,11 +137,32 @@ type GameMetrics struct {
 	AutoPilotMoves        uint64     `json:"autoPilotMoves"`
 	CurrentDuration       string     `json:"currentDuration"`
 	GameStatus            GameStatus `json:"gameStatus"`
+	CreatorId             string     `json:"creatorId,omitempty"`
+	Visibility            string     `json:"visibility"`
+	Spectators            int        `json:"spectators"`
+	// ServerHeapBytes is the heap of the whole server, only game_metrics sets it.
+	ServerHeapBytes uint64 `json:"serverHeapBytes,omitempty"`
+}
+
+type AutoPilotState struct {
+	Running         bool   `json:"running"`
+	AutoPilots      int    `json:"autoPilots"`
+	DelayAutoPilots int    `json:"delayAutoPilots"`
+	Iterations      int    `json:"iterations"`
+	TotalIterations uint64 `json:"totalIterations"`
 }
 
 type PlayerJoin struct {
 	GameId     string `json:"gameId"`
 	PlayerName string `json:"playerName"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+type SpectateGame struct {
+	GameId     string `json:"gameId"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Password   string `json:"password,omitempty"`
 }
 
 type PlayerLeave struct {
@@ -70,3 +174,112 @@ type PlayerMove struct {
 	PlayerId string `json:"playerName"`
 	Index    int    `json:"index"`
 }
+
+type PlayerKick struct {
+	GameId   string `json:"gameId"`
+	PlayerId string `json:"playerId"`
+	Reason   string `json:"reason"`
+}
+
+type GameSnapshot struct {
+	GameId            string           `json:"gameId"`
+	SizeGame          int              `json:"sizeGame"`
+	Status            []byte           `json:"status"`
+	HasStarted        bool             `json:"hasStarted"`
+	HasFinished       bool             `json:"hasFinished"`
+	Players           []PlayerSnapshot `json:"players"`
+	NumberAutoPilots  int              `json:"numberAutoPilots"`
+	DelayAutoPilots   int              `json:"delayAutoPilots"`
+	MaxPlayers        int              `json:"maxPlayers"`
+	CreatorId         string           `json:"creatorId,omitempty"`
+	Visibility        string           `json:"visibility,omitempty"`
+	Mode              string           `json:"mode,omitempty"`
+	SpectatorDelay    time.Duration    `json:"spectatorDelay,omitempty"`
+	Participants      []Participant    `json:"participants,omitempty"`
+	InviteCode        string           `json:"inviteCode,omitempty"`
+	PasswordSalt      string           `json:"passwordSalt,omitempty"`
+	PasswordHash      string           `json:"passwordHash,omitempty"`
+	InitTime          time.Time        `json:"initTime"`
+	LastMoveTime      time.Time        `json:"lastMoveTime"`
+	LastMoveBy        string           `json:"lastMoveBy"`
+	TotalIterations   uint64           `json:"totalIterations"`
+	Iterations        int              `json:"iterations"`
+	WinnerId          string           `json:"winnerId"`
+	WinnerName        string           `json:"winnerName"`
+	Seq               uint64           `json:"seq"`
+	Seed              int64            `json:"seed"`
+	RandomDraws       uint64           `json:"randomDraws"`
+	Paused            bool             `json:"paused,omitempty"`
+	PausedAt          time.Time        `json:"pausedAt,omitempty"`
+	PausedTotal       time.Duration    `json:"pausedTotal,omitempty"`
+	BannedAccounts    []string         `json:"bannedAccounts,omitempty"`
+	BannedConnections []string         `json:"bannedConnections,omitempty"`
+	MutedPlayers      []string         `json:"mutedPlayers,omitempty"`
+	MutedAccounts     []string         `json:"mutedAccounts,omitempty"`
+}
+
+// GameView is what get_game shows of a game: the snapshot without the seed,
+// the invite code, the password, the bans and the connections of its players.
+type GameView struct {
+	GameId           string        `json:"gameId"`
+	SizeGame         int           `json:"sizeGame"`
+	Status           []byte        `json:"status"`
+	HasStarted       bool          `json:"hasStarted"`
+	HasFinished      bool          `json:"hasFinished"`
+	Paused           bool          `json:"paused,omitempty"`
+	Players          []Participant `json:"players"`
+	NumberAutoPilots int           `json:"numberAutoPilots"`
+	MaxPlayers       int           `json:"maxPlayers"`
+	CreatorId        string        `json:"creatorId,omitempty"`
+	Visibility       string        `json:"visibility"`
+	Mode             string        `json:"mode,omitempty"`
+	SpectatorDelayMs int64         `json:"spectatorDelayMs"`
+	InitTime         time.Time     `json:"initTime"`
+	LastMoveTime     time.Time     `json:"lastMoveTime"`
+	LastMoveBy       string        `json:"lastMoveBy"`
+	WinnerId         string        `json:"winnerId,omitempty"`
+	WinnerName       string        `json:"winnerName,omitempty"`
+	Seq              uint64        `json:"seq"`
+}
+
+type PlayerSnapshot struct {
+	PlayerId         string `json:"playerId"`
+	PlayerName       string `json:"playerName"`
+	PlayerConnection string `json:"playerConnection"`
+	AccountId        string `json:"accountId,omitempty"`
+	AutoPilot        bool   `json:"autoPilot"`
+}
+
+type EventType string
+
+const (
+	EventGameStarted   EventType = "game_started"
+	EventGameFinished  EventType = "game_finished"
+	EventPlayerAdded   EventType = "player_added"
+	EventPlayerRemoved EventType = "player_removed"
+	EventPlayerMoved   EventType = "player_moved"
+	EventPlayerKicked  EventType = "player_kicked"
+	EventGamePaused    EventType = "game_paused"
+	EventGameResumed   EventType = "game_resumed"
+	EventChatMessage   EventType = "chat_message"
+	EventPlayerMuted   EventType = "player_muted"
+)
+
+type Event struct {
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	Type EventType `json:"type"`
+	// RandomDraws is how far the game's random source was drawn at a move, so
+	// a restored game continues the same autopilot sequence.
+	RandomDraws   uint64         `json:"randomDraws,omitempty"`
+	GameStarted   *GameStarted   `json:"gameStarted,omitempty"`
+	GameFinished  *GameFinished  `json:"gameFinished,omitempty"`
+	PlayerAdded   *PlayerAdded   `json:"playerAdded,omitempty"`
+	PlayerRemoved *PlayerRemoved `json:"playerRemoved,omitempty"`
+	PlayerMoved   *PlayerMoved   `json:"playerMoved,omitempty"`
+	PlayerKicked  *PlayerKicked  `json:"playerKicked,omitempty"`
+	GamePaused    *GamePaused    `json:"gamePaused,omitempty"`
+	GameResumed   *GameResumed   `json:"gameResumed,omitempty"`
+	ChatMessage   *ChatMessage   `json:"chatMessage,omitempty"`
+	PlayerMuted   *PlayerMuted   `json:"playerMuted,omitempty"`
+}
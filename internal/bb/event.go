@@ -0,0 +1,218 @@
+package bb
+
+import (
+	"battlebit/internal/log"
+	"battlebit/internal/player"
+	"battlebit/internal/status"
+	"context"
+	"fmt"
+	"sort"
+)
+
+// record must be called with playerMutex held.
+func (g *Game) record(ctx context.Context, event *Event) {
+	g.seq++
+	event.Seq = g.seq
+	if event.Time.IsZero() {
+		event.Time = g.clock.Now()
+	}
+	g.events = append(g.events, event)
+	if event.Type == EventChatMessage {
+		g.keepChat(event)
+	}
+	if g.journal == nil {
+		return
+	}
+	if err := g.journal.AppendEvent(g.GameId, event); err != nil {
+		log.GetLogger(ctx).Error("Error appending event", "gameId", g.GameId, "seq", event.Seq, "type", event.Type, "error", err.Error())
+	}
+}
+
+// Events returns the events after afterSeq, from the journal when some of
+// them were already dropped from memory.
+func (g *Game) Events(afterSeq uint64) []*Event {
+	g.playerMutex.Lock()
+	journal := g.journal
+	truncated := afterSeq < g.truncatedSeq
+	events := eventsAfter(g.events, afterSeq)
+	g.playerMutex.Unlock()
+	if truncated && journal != nil {
+		if logged, err := journal.LoadEvents(g.GameId); err == nil {
+			return eventsAfter(logged, afterSeq)
+		}
+	}
+	return events
+}
+
+func eventsAfter(events []*Event, afterSeq uint64) []*Event {
+	i := sort.Search(len(events), func(i int) bool { return events[i].Seq > afterSeq })
+	after := make([]*Event, len(events)-i)
+	copy(after, events[i:])
+	return after
+}
+
+// Redacted returns the event without what predicts the autopilots: the seed
+// and how far its random source was drawn.
+func (e *Event) Redacted() *Event {
+	if e.RandomDraws == 0 && e.GameStarted == nil {
+		return e
+	}
+	c := *e
+	c.RandomDraws = 0
+	if e.GameStarted != nil {
+		started := *e.GameStarted
+		started.Seed = 0
+		c.GameStarted = &started
+	}
+	return &c
+}
+
+func Replay(ctx context.Context, events []*Event) (*Game, error) {
+	slog := log.GetLogger(ctx)
+
+	var started *GameStarted
+	for _, event := range events {
+		if event.Type == EventGameStarted && event.GameStarted != nil {
+			started = event.GameStarted
+			break
+		}
+	}
+	if started == nil {
+		return nil, fmt.Errorf("game started event not found")
+	}
+	g := NewGame(status.NewGameStatus(started.SizeGame), started.NumberAutoPilots)
+	g.GameId = started.GameId
+	for _, event := range events {
+		if err := g.apply(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+	g.events = append(g.events, events...)
+	slog.Debug("Game replayed", "gameId", g.GameId, "events", len(events), "finished", g.Game.HasFinished)
+	return g, nil
+}
+
+func (g *Game) apply(ctx context.Context, event *Event) error {
+	if event.Seq <= g.seq {
+		return fmt.Errorf("event %d out of order after %d", event.Seq, g.seq)
+	}
+	switch event.Type {
+	case EventGameStarted:
+		if event.GameStarted == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		g.InitTimer = event.GameStarted.InitTime
+		g.NumberAutoPilots = event.GameStarted.NumberAutoPilots
+		g.DelayAutoPilots = event.GameStarted.DelayAutoPilots
+		g.CreatorId = event.GameStarted.CreatorId
+		if event.GameStarted.Mode != "" {
+			g.Mode = event.GameStarted.Mode
+		}
+		if event.GameStarted.MaxPlayers > 0 {
+			g.MaxPlayers = event.GameStarted.MaxPlayers
+		}
+		if event.GameStarted.Visibility != "" {
+			g.Visibility = event.GameStarted.Visibility
+		}
+		g.SpectatorDelay = event.GameStarted.SpectatorDelay
+		g.seedRandom(event.GameStarted.Seed, 0)
+		g.Game.HasStarted = true
+	case EventPlayerAdded:
+		if event.PlayerAdded == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		if _, err := g.GetPlayerById(ctx, event.PlayerAdded.PlayerId); err != nil {
+			p := &player.Player{
+				PlayerId:   event.PlayerAdded.PlayerId,
+				PlayerName: event.PlayerAdded.PlayerName,
+				AccountId:  event.PlayerAdded.AccountId,
+				AutoPilot:  event.PlayerAdded.AutoPilot,
+			}
+			g.Players = append(g.Players, p)
+			g.participate(p)
+		}
+	case EventPlayerRemoved:
+		if event.PlayerRemoved == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		for i, p := range g.Players {
+			if p.PlayerId == event.PlayerRemoved.PlayerId {
+				g.Players = append(g.Players[:i], g.Players[i+1:]...)
+				break
+			}
+		}
+		g.leave(event.PlayerRemoved.PlayerId)
+	case EventPlayerMoved:
+		if event.PlayerMoved == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		if event.PlayerMoved.Index < 0 || event.PlayerMoved.Index >= g.Game.Size {
+			return fmt.Errorf("event %d index %d out of range", event.Seq, event.PlayerMoved.Index)
+		}
+		if event.RandomDraws > g.source.draws.Load() {
+			// the first move of an autopilot round
+			g.source.skip(event.RandomDraws)
+			g.advanceIteration()
+		}
+		if g.Game.ToggleBit(ctx, event.PlayerMoved.Index) {
+			g.countMove(event.PlayerMoved.PlayerId)
+		}
+		g.LastMoveTime = event.PlayerMoved.TimeMove
+		g.LastMoveBy = event.PlayerMoved.PlayerId
+	case EventPlayerKicked:
+		if event.PlayerKicked == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		for i, p := range g.Players {
+			if p.PlayerId == event.PlayerKicked.PlayerId {
+				g.Players = append(g.Players[:i], g.Players[i+1:]...)
+				break
+			}
+		}
+		g.leave(event.PlayerKicked.PlayerId)
+		if event.PlayerKicked.Banned {
+			g.ban(event.PlayerKicked)
+		}
+	case EventGamePaused:
+		if event.GamePaused == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		if g.resumed == nil {
+			g.pausedAt = event.Time
+			g.resumed = make(chan struct{})
+		}
+	case EventGameResumed:
+		if event.GameResumed == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		if g.resumed != nil {
+			g.pausedTotal += event.GameResumed.PausedFor
+			close(g.resumed)
+			g.resumed = nil
+		}
+	case EventChatMessage:
+		if event.ChatMessage == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		g.keepChat(event)
+	case EventPlayerMuted:
+		if event.PlayerMuted == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		g.mute(event.PlayerMuted)
+	case EventGameFinished:
+		if event.GameFinished == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		g.Game.HasFinished = true
+		g.WinnerId = event.GameFinished.WinnerId
+		g.WinnerName = event.GameFinished.WinnerName
+		if g.resumed != nil {
+			g.pausedTotal += event.Time.Sub(g.pausedAt)
+			close(g.resumed)
+			g.resumed = nil
+		}
+	}
+	g.seq = event.Seq
+	return nil
+}
@@ -0,0 +1,141 @@
+package bb
+
+import (
+	"battlebit/internal/log"
+	"context"
+	"fmt"
+	"time"
+)
+
+// KickPlayer removes a player from the game. A ban also keeps its account from
+// joining again, or its connection when it has none.
+func (g *Game) KickPlayer(ctx context.Context, playerId string, reason string, ban bool) (*PlayerKicked, error) {
+	slog := log.GetLogger(ctx)
+
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	for i, p := range g.Players {
+		if p.PlayerId != playerId {
+			continue
+		}
+		if ban && p.AutoPilot {
+			return nil, fmt.Errorf("autopilots cannot be banned")
+		}
+		kicked := &PlayerKicked{
+			GameId:   g.GameId,
+			PlayerId: p.PlayerId,
+			Reason:   reason,
+			Banned:   ban,
+		}
+		if ban {
+			// many players can share a host behind a NAT or a proxy, so
+			// anonymous players are banned for their connection only
+			kicked.AccountId = p.AccountId
+			if p.AccountId == "" {
+				kicked.Connection = p.PlayerConnection
+			}
+			g.ban(kicked)
+		}
+		g.Players = append(g.Players[:i], g.Players[i+1:]...)
+		g.leave(p.PlayerId)
+		g.record(ctx, &Event{Type: EventPlayerKicked, PlayerKicked: kicked})
+		g.persist(ctx)
+		slog.Info("Player kicked", "gameId", g.GameId, "playerId", p.PlayerId, "banned", ban, "reason", reason)
+		return kicked, nil
+	}
+	return nil, fmt.Errorf("player not found")
+}
+
+// ban must be called with playerMutex held.
+func (g *Game) ban(kicked *PlayerKicked) {
+	if g.bannedAccounts == nil {
+		g.bannedAccounts = make(map[string]bool)
+		g.bannedConnections = make(map[string]bool)
+	}
+	if kicked.AccountId != "" {
+		g.bannedAccounts[kicked.AccountId] = true
+	}
+	if kicked.Connection != "" {
+		g.bannedConnections[kicked.Connection] = true
+	}
+}
+
+// isBanned must be called with playerMutex held.
+func (g *Game) isBanned(accountId string, connection string) bool {
+	if accountId != "" && g.bannedAccounts[accountId] {
+		return true
+	}
+	return connection != "" && g.bannedConnections[connection]
+}
+
+func (g *Game) PauseGame(ctx context.Context) (*GamePaused, error) {
+	slog := log.GetLogger(ctx)
+
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	if !g.Game.HasStarted || g.Game.HasFinished {
+		return nil, fmt.Errorf("game is not in process")
+	}
+	if g.resumed != nil {
+		return nil, fmt.Errorf("game already paused")
+	}
+	g.pausedAt = g.clock.Now()
+	g.resumed = make(chan struct{})
+	paused := &GamePaused{GameId: g.GameId}
+	g.record(ctx, &Event{Type: EventGamePaused, Time: g.pausedAt, GamePaused: paused})
+	g.persist(ctx)
+	slog.Info("Game paused", "gameId", g.GameId)
+	return paused, nil
+}
+
+func (g *Game) UnpauseGame(ctx context.Context) (*GameResumed, error) {
+	slog := log.GetLogger(ctx)
+
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	if g.resumed == nil {
+		return nil, fmt.Errorf("game is not paused")
+	}
+	now := g.clock.Now()
+	resumed := &GameResumed{GameId: g.GameId, PausedFor: now.Sub(g.pausedAt)}
+	g.pausedTotal += resumed.PausedFor
+	close(g.resumed)
+	g.resumed = nil
+	g.record(ctx, &Event{Type: EventGameResumed, Time: now, GameResumed: resumed})
+	g.persist(ctx)
+	slog.Info("Game resumed", "gameId", g.GameId, "pausedFor", resumed.PausedFor.String())
+	return resumed, nil
+}
+
+func (g *Game) IsPaused() bool {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return g.resumed != nil
+}
+
+// waitIfPaused blocks the autopilot loop while the game is paused and reports
+// false when it has to stop instead.
+func (g *Game) waitIfPaused(finisher chan struct{}) bool {
+	g.playerMutex.Lock()
+	resumed := g.resumed
+	g.playerMutex.Unlock()
+	if resumed == nil {
+		return true
+	}
+	select {
+	case <-resumed:
+		return true
+	case <-finisher:
+		return false
+	}
+}
+
+// activeDuration must be called with playerMutex held, paused time is not
+// counted.
+func (g *Game) activeDuration(now time.Time) time.Duration {
+	paused := g.pausedTotal
+	if g.resumed != nil {
+		paused += now.Sub(g.pausedAt)
+	}
+	return now.Sub(g.InitTimer) - paused
+}
@@ -0,0 +1,62 @@
+package bb
+
+import (
+	"battlebit/internal/player"
+	"battlebit/internal/status"
+	"context"
+	"testing"
+)
+
+func TestBan(t *testing.T) {
+	tests := []struct {
+		name       string
+		banned     player.Player
+		joining    player.Player
+		wantJoined bool
+	}{
+		{
+			name:    "same account on another connection",
+			banned:  player.Player{AccountId: "42", PlayerConnection: "session-1"},
+			joining: player.Player{AccountId: "42", PlayerConnection: "session-2"},
+		},
+		{
+			name:       "other account on the same connection",
+			banned:     player.Player{AccountId: "42", PlayerConnection: "session-1"},
+			joining:    player.Player{AccountId: "43", PlayerConnection: "session-1"},
+			wantJoined: true,
+		},
+		{
+			name:    "anonymous on the same connection",
+			banned:  player.Player{PlayerConnection: "session-1"},
+			joining: player.Player{PlayerConnection: "session-1"},
+		},
+		{
+			name:       "anonymous on another connection",
+			banned:     player.Player{PlayerConnection: "session-1"},
+			joining:    player.Player{PlayerConnection: "session-2"},
+			wantJoined: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			g := NewGame(status.NewGameStatus(8), 0)
+			banned := player.NewPlayer("banned", tt.banned.PlayerConnection)
+			banned.AccountId = tt.banned.AccountId
+			if _, err := g.AddPlayer(ctx, banned); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := g.KickPlayer(ctx, banned.PlayerId, "test", true); err != nil {
+				t.Fatal(err)
+			}
+			// bans survive a restore
+			g = RestoreGame(ctx, g.Snapshot(), nil)
+			joining := player.NewPlayer("joining", tt.joining.PlayerConnection)
+			joining.AccountId = tt.joining.AccountId
+			_, err := g.AddPlayer(ctx, joining)
+			if joined := err == nil; joined != tt.wantJoined {
+				t.Errorf("joined %v, want %v (error %v)", joined, tt.wantJoined, err)
+			}
+		})
+	}
+}
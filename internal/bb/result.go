@@ -0,0 +1,78 @@
+package bb
+
+import (
+	"battlebit/internal/player"
+	"time"
+)
+
+const ModeCustom = "custom"
+
+// SetFinishHook registers fn to receive the result when the game finishes. It
+// runs with the game locked and must not call back into the game.
+func (g *Game) SetFinishHook(fn func(*GameResult)) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.onFinish = fn
+}
+
+// participate must be called with playerMutex held.
+func (g *Game) participate(p *player.Player) {
+	if i, ok := g.participantIndex[p.PlayerId]; ok {
+		g.participants[i].Left = false
+		return
+	}
+	if g.participantIndex == nil {
+		g.participantIndex = make(map[string]int)
+	}
+	g.participantIndex[p.PlayerId] = len(g.participants)
+	g.participants = append(g.participants, Participant{
+		PlayerId:   p.PlayerId,
+		PlayerName: p.PlayerName,
+		AccountId:  p.AccountId,
+		AutoPilot:  p.AutoPilot,
+	})
+}
+
+// countMove counts a bit the player turned on. It must be called with
+// playerMutex held.
+func (g *Game) countMove(playerId string) {
+	if i, ok := g.participantIndex[playerId]; ok {
+		g.participants[i].BitsFlipped++
+	}
+}
+
+// leave must be called with playerMutex held.
+func (g *Game) leave(playerId string) {
+	if i, ok := g.participantIndex[playerId]; ok {
+		g.participants[i].Left = true
+	}
+}
+
+func (g *Game) Participants() []Participant {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return append([]Participant(nil), g.participants...)
+}
+
+// result must be called with playerMutex held.
+func (g *Game) result(finished *GameFinished, now time.Time) *GameResult {
+	return &GameResult{
+		GameId:     g.GameId,
+		Reason:     finished.Reason,
+		WinnerId:   finished.WinnerId,
+		Duration:   finished.Duration,
+		FinishedAt: now,
+		CreatorId:  g.CreatorId,
+		Finished:   finished,
+		Config: GameConfig{
+			Mode:            g.Mode,
+			Size:            g.Game.Size,
+			AutoPilots:      g.NumberAutoPilots,
+			DelayAutoPilots: g.DelayAutoPilots,
+			MaxPlayers:      g.MaxPlayers,
+			Visibility:      g.Visibility,
+			Seed:            g.Seed,
+		},
+		Players: append([]Participant(nil), g.participants...),
+	}
+}
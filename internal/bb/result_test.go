@@ -0,0 +1,40 @@
+package bb
+
+import (
+	"battlebit/internal/player"
+	"battlebit/internal/status"
+	"context"
+	"testing"
+)
+
+func TestBitsFlipped(t *testing.T) {
+	ctx := context.Background()
+	g := NewGame(status.NewGameStatus(16), 0)
+	g.StartGame(ctx)
+	alice, bob := player.NewPlayer("alice", "session-1"), player.NewPlayer("bob", "session-2")
+	for _, p := range []*player.Player{alice, bob} {
+		if _, err := g.AddPlayer(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	g.PlayerMove(ctx, alice.PlayerId, 3)
+	// a bit already on stays on, so repeating the move flips nothing
+	g.PlayerMove(ctx, alice.PlayerId, 3)
+	g.PlayerMove(ctx, bob.PlayerId, 3)
+	g.PlayerMove(ctx, bob.PlayerId, 4)
+
+	want := map[string]int{alice.PlayerId: 1, bob.PlayerId: 1}
+	check := func(name string, participants []Participant) {
+		for _, p := range participants {
+			if p.BitsFlipped != want[p.PlayerId] {
+				t.Errorf("%s: %s flipped %d bits, want %d", name, p.PlayerName, p.BitsFlipped, want[p.PlayerId])
+			}
+		}
+	}
+	check("live", g.Participants())
+	replayed, err := Replay(ctx, g.Events(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("replayed", replayed.Participants())
+}
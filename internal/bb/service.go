@@ -2,45 +2,84 @@ package bb
 
 import (
 	"battlebit/internal/log"
+	"battlebit/internal/metrics"
 	"battlebit/internal/player"
 	"battlebit/internal/status"
+	"battlebit/pkg/strategy"
 	"context"
 	"fmt"
 	"log/slog"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Game struct {
-	GameId           string
-	SizeGame         int
-	Game             *status.GameStatus
-	Players          []*player.Player
-	LastMoveTime     time.Time
-	LastMoveBy       string
-	playerMutex      sync.Mutex
-	InitTimer        time.Time
-	NumberAutoPilots int
-	DelayAutoPilots  int
-	autoPilotBreak   chan struct{}
-	totalIterations  uint64
-	iterarations     int
-	WinnerId         string
-	WinnerName       string
+	GameId            string
+	SizeGame          int
+	Game              *status.GameStatus
+	Players           []*player.Player
+	LastMoveTime      time.Time
+	LastMoveBy        string
+	playerMutex       sync.Mutex
+	InitTimer         time.Time
+	NumberAutoPilots  int
+	DelayAutoPilots   int
+	MaxPlayers        int
+	CreatorId         string
+	Visibility        string
+	Mode              string
+	SpectatorDelay    time.Duration
+	spectators        int
+	participants      []Participant
+	participantIndex  map[string]int
+	inviteCode        string
+	passwordSalt      string
+	passwordHash      string
+	autoPilotBreak    chan struct{}
+	autoPilotDone     chan struct{}
+	totalIterations   uint64
+	iterarations      int
+	WinnerId          string
+	WinnerName        string
+	journal           Journal
+	seq               uint64
+	events            []*Event
+	truncatedSeq      uint64
+	chat              []*Event
+	Seed              int64
+	source            *countingSource
+	rng               *rand.Rand
+	clock             Clock
+	autoPilotRunning  atomic.Bool
+	pausedAt          time.Time
+	pausedTotal       time.Duration
+	resumed           chan struct{}
+	bannedAccounts    map[string]bool
+	bannedConnections map[string]bool
+	mutedPlayers      map[string]bool
+	mutedAccounts     map[string]bool
+	onFinish          func(*GameResult)
 }
 
 func NewGame(status *status.GameStatus, NumberPilots int) *Game {
-	return &Game{
+	g := &Game{
 		GameId:           uuid.New().String(),
 		Game:             status,
 		Players:          make([]*player.Player, 0),
 		NumberAutoPilots: NumberPilots,
 		DelayAutoPilots:  0,
+		MaxPlayers:       DefaultMaxPlayers,
+		Visibility:       VisibilityPublic,
+		Mode:             ModeCustom,
 		autoPilotBreak:   make(chan struct{}, 1),
+		clock:            SystemClock{},
 	}
+	g.seedRandom(rand.Int63(), 0)
+	return g
 }
 
 func (g *Game) AddPlayer(ctx context.Context, player *player.Player) (*PlayerAdded, error) {
@@ -48,7 +87,7 @@ func (g *Game) AddPlayer(ctx context.Context, player *player.Player) (*PlayerAdd
 
 	g.playerMutex.Lock()
 	defer g.playerMutex.Unlock()
-	if len(g.Players) >= 10 {
+	if len(g.Players) >= g.MaxPlayers {
 		slog.Debug("Game is full", "gameId", g.GameId, "size", g.Game.Size)
 		return nil, fmt.Errorf("game is full")
 	}
@@ -57,13 +96,31 @@ func (g *Game) AddPlayer(ctx context.Context, player *player.Player) (*PlayerAdd
 		slog.Debug("Player already added", "gameId", g.GameId, "playerId", player.PlayerId)
 		return nil, fmt.Errorf("player already added")
 	}
+	if g.isBanned(player.AccountId, player.PlayerConnection) {
+		slog.Debug("Player is banned", "gameId", g.GameId, "accountId", player.AccountId, "connection", player.PlayerConnection)
+		return nil, fmt.Errorf("player is banned from this game")
+	}
+	if player.AccountId != "" {
+		for _, p := range g.Players {
+			if p.AccountId == player.AccountId {
+				slog.Debug("Account already in game", "gameId", g.GameId, "accountId", player.AccountId)
+				return nil, fmt.Errorf("account already in game")
+			}
+		}
+	}
 	g.Players = append(g.Players, player)
-	slog.Debug("Player added", "gameId", g.GameId, "playerId", player.PlayerId, "playerName", player.PlayerName)
-	return &PlayerAdded{
+	g.participate(player)
+	added := &PlayerAdded{
 		GameId:     g.GameId,
 		PlayerId:   player.PlayerId,
 		PlayerName: player.PlayerName,
-	}, nil
+		AccountId:  player.AccountId,
+		AutoPilot:  player.AutoPilot,
+	}
+	g.record(ctx, &Event{Type: EventPlayerAdded, PlayerAdded: added})
+	g.persist(ctx)
+	slog.Debug("Player added", "gameId", g.GameId, "playerId", player.PlayerId, "playerName", player.PlayerName)
+	return added, nil
 }
 func (g *Game) RemovePlayer(ctx context.Context, playerId string) *PlayerRemoved {
 	slog := log.GetLogger(ctx)
@@ -73,11 +130,15 @@ func (g *Game) RemovePlayer(ctx context.Context, playerId string) *PlayerRemoved
 	for i, player := range g.Players {
 		if player.PlayerId == playerId {
 			g.Players = append(g.Players[:i], g.Players[i+1:]...)
-			slog.Debug("Player removed", "gameId", g.GameId, "playerId", player.PlayerId)
-			return &PlayerRemoved{
+			g.leave(player.PlayerId)
+			removed := &PlayerRemoved{
 				GameId:   g.GameId,
 				PlayerId: player.PlayerId,
 			}
+			g.record(ctx, &Event{Type: EventPlayerRemoved, PlayerRemoved: removed})
+			g.persist(ctx)
+			slog.Debug("Player removed", "gameId", g.GameId, "playerId", player.PlayerId)
+			return removed
 		}
 	}
 	slog.Debug("Player not found", "gameId", g.GameId, "playerId", playerId)
@@ -86,26 +147,40 @@ func (g *Game) RemovePlayer(ctx context.Context, playerId string) *PlayerRemoved
 func (g *Game) PlayerMove(ctx context.Context, playerId string, index int) *PlayerMoved {
 	slog := log.GetLogger(ctx)
 
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
 	player, err := g.GetPlayerById(ctx, playerId)
 	if err != nil {
 		return &PlayerMoved{}
 	}
-	if index >= g.Game.Size {
+	if index < 0 || index >= g.Game.Size {
 		slog.Debug("Index out of range", "gameId", g.GameId, "playerId", player.PlayerId, "index", index)
 		return &PlayerMoved{}
 	}
-	g.Game.ToggleBit(ctx, index)
-	g.playerMutex.Lock()
-	defer g.playerMutex.Unlock()
-	g.LastMoveTime = time.Now()
-	g.LastMoveBy = player.PlayerId
 	if g.Game.HasFinished {
-		g.WinnerId = player.PlayerId
-		g.WinnerName = player.PlayerName
-		g.FinishGame(ctx)
+		slog.Debug("Game already finished", "gameId", g.GameId, "playerId", player.PlayerId, "index", index)
+		return &PlayerMoved{
+			GameId:     g.GameId,
+			PlayerId:   player.PlayerId,
+			Index:      index,
+			GameStatus: GameStatus{IsFinished: true},
+		}
 	}
-	slog.Debug("Player moved", "gameId", g.GameId, "playerId", player.PlayerId, "index", index, "timeMove", g.LastMoveTime)
-	return &PlayerMoved{
+	if g.resumed != nil {
+		slog.Debug("Game is paused", "gameId", g.GameId, "playerId", player.PlayerId, "index", index)
+		return &PlayerMoved{
+			GameId:     g.GameId,
+			PlayerId:   player.PlayerId,
+			Index:      index,
+			GameStatus: GameStatus{IsInProcess: true, IsPaused: true},
+		}
+	}
+	if g.Game.ToggleBit(ctx, index) {
+		g.countMove(player.PlayerId)
+	}
+	g.LastMoveTime = g.clock.Now()
+	g.LastMoveBy = player.PlayerId
+	moved := &PlayerMoved{
 		GameId:   g.GameId,
 		PlayerId: player.PlayerId,
 		Index:    index,
@@ -115,8 +190,23 @@ func (g *Game) PlayerMove(ctx context.Context, playerId string, index int) *Play
 			IsFinished:  g.Game.HasFinished,
 		},
 	}
+	g.record(ctx, &Event{Type: EventPlayerMoved, Time: g.LastMoveTime, RandomDraws: g.source.draws.Load(), PlayerMoved: moved})
+	if player.AutoPilot {
+		metrics.AutoPilotMoves.Inc()
+	} else {
+		metrics.HumanMoves.Inc()
+	}
+	if g.Game.HasFinished {
+		g.WinnerId = player.PlayerId
+		g.WinnerName = player.PlayerName
+		g.FinishGame(ctx)
+		g.persist(ctx)
+	}
+	slog.Debug("Player moved", "gameId", g.GameId, "playerId", player.PlayerId, "index", index, "timeMove", g.LastMoveTime)
+	return moved
 }
 
+// GetPlayerById must be called with playerMutex held.
 func (g *Game) GetPlayerById(ctx context.Context, playerId string) (*player.Player, error) {
 	slog := log.GetLogger(ctx)
 
@@ -130,47 +220,108 @@ func (g *Game) GetPlayerById(ctx context.Context, playerId string) (*player.Play
 	return nil, fmt.Errorf("player not found")
 }
 
-func (g *Game) GenerateGaussianRandomInt(mean, stddev, max int) int {
-	for {
-		randFloat := rand.NormFloat64()*float64(stddev) + float64(mean)
-		randInt := int(randFloat)
-		if randInt >= 0 && randInt < max {
-			return randInt
+func (g *Game) HasStarted() bool {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return g.Game.HasStarted
+}
+
+func (g *Game) HasFinished() bool {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return g.Game.HasFinished
+}
+
+func (g *Game) PlayerCounts() (int, int) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	humans, autoPilots := 0, 0
+	for _, p := range g.Players {
+		if p.AutoPilot {
+			autoPilots++
+		} else {
+			humans++
 		}
 	}
+	return humans, autoPilots
 }
 
 func (g *Game) StartGame(ctx context.Context) *GameStarted {
 	slog := log.GetLogger(ctx)
 
-	g.InitTimer = time.Now()
+	g.playerMutex.Lock()
+	g.InitTimer = g.clock.Now()
 	g.Game.HasStarted = true
-	g.StartAutoPilots(ctx)
-
-	slog.Debug("Game started", "gameId", g.GameId, "size", g.Game.Size, "players", len(g.Players))
-	return &GameStarted{
+	started := &GameStarted{
 		GameId:           g.GameId,
 		SizeGame:         g.Game.Size,
 		InitTime:         g.InitTimer,
 		NumberAutoPilots: g.NumberAutoPilots,
+		DelayAutoPilots:  g.DelayAutoPilots,
+		Seed:             g.Seed,
+		CreatorId:        g.CreatorId,
+		Mode:             g.Mode,
+		MaxPlayers:       g.MaxPlayers,
+		Visibility:       g.Visibility,
+		SpectatorDelay:   g.SpectatorDelay,
 	}
+	g.record(ctx, &Event{Type: EventGameStarted, Time: g.InitTimer, GameStarted: started})
+	g.persist(ctx)
+	g.playerMutex.Unlock()
+	g.StartAutoPilots(ctx)
+
+	slog.Debug("Game started", "gameId", g.GameId, "size", g.Game.Size, "players", len(g.Players))
+	return started
 }
 
 func (g *Game) FinishGame(ctx context.Context) *GameFinished {
+	return g.finish(ctx, FinishReasonCompleted)
+}
+
+// AbortGame finishes a running game without a winner.
+func (g *Game) AbortGame(ctx context.Context, reason string) *GameFinished {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	if g.Game.HasFinished {
+		return nil
+	}
+	finished := g.finish(ctx, reason)
+	g.persist(ctx)
+	return finished
+}
+
+// finish must be called with playerMutex held.
+func (g *Game) finish(ctx context.Context, reason string) *GameFinished {
 	slog := log.GetLogger(ctx)
-	g.autoPilotBreak <- struct{}{}
+	select {
+	case g.autoPilotBreak <- struct{}{}:
+	default:
+	}
 	g.Game.HasFinished = true
 
-	slog.Info("Game finished", "gameId", g.GameId, "size", g.Game.Size, "players", len(g.Players), "duration", time.Since(g.InitTimer).String())
-	slog.Info("Winner", "playerId", g.WinnerId, "playerName", g.WinnerName)
-	return &GameFinished{
+	now := g.clock.Now()
+	duration := g.activeDuration(now)
+	if g.resumed != nil {
+		g.pausedTotal += now.Sub(g.pausedAt)
+		close(g.resumed)
+		g.resumed = nil
+	}
+	slog.Info("Game finished", "gameId", g.GameId, "size", g.Game.Size, "players", len(g.Players), "duration", duration.String())
+	slog.Info("Winner", "playerId", g.WinnerId, "playerName", g.WinnerName, "reason", reason)
+	finished := &GameFinished{
 		GameId:           g.GameId,
 		SizeGame:         g.Game.Size,
 		InitTime:         g.InitTimer,
 		NumberAutoPilots: g.NumberAutoPilots,
 		WinnerId:         g.WinnerId,
 		WinnerName:       g.WinnerName,
-		Duration:         time.Since(g.InitTimer)}
+		Duration:         duration,
+		Reason:           reason}
+	g.record(ctx, &Event{Type: EventGameFinished, GameFinished: finished})
+	if g.onFinish != nil {
+		g.onFinish(g.result(finished, now))
+	}
+	return finished
 }
 
 func (g *Game) StartAutoPilots(ctx context.Context) {
@@ -178,7 +329,7 @@ func (g *Game) StartAutoPilots(ctx context.Context) {
 
 	autoPilots := make([]*player.Player, 0)
 	for i := 0; i < g.NumberAutoPilots; i++ {
-		autoPilot := player.NewPlayer(fmt.Sprintf("Autopilot %d", i), fmt.Sprintf("Connection %d", i))
+		autoPilot := player.NewAutoPilot(fmt.Sprintf("Autopilot %d", i), fmt.Sprintf("Connection %d", i))
 		_, err := g.AddPlayer(ctx, autoPilot)
 		if err != nil {
 			slog.Error("Error adding autopilot", "error", err.Error())
@@ -186,28 +337,48 @@ func (g *Game) StartAutoPilots(ctx context.Context) {
 		}
 		autoPilots = append(autoPilots, autoPilot)
 	}
+	g.runAutoPilots(ctx, autoPilots)
+}
 
+func (g *Game) runAutoPilots(ctx context.Context, autoPilots []*player.Player) {
+	slog := log.GetLogger(ctx)
+
+	// without autopilots the loop would only spin until the game finishes
+	if len(autoPilots) == 0 {
+		return
+	}
 	timeDelay := time.Duration(g.DelayAutoPilots) * time.Millisecond
 	if g.DelayAutoPilots == 0 {
 		timeDelay = 1 * time.Nanosecond
 	}
 	delay := time.NewTicker(timeDelay)
-	go g.AutopilotGame(autoPilots, delay, g.autoPilotBreak)
+	// the strategy draws from the game's random source, so seeded games
+	// replay the same moves
+	next := &strategy.Gaussian{StdDev: 5, Mean: g.iterarations, Rand: g.rng}
+	g.autoPilotDone = make(chan struct{})
+	g.autoPilotRunning.Store(true)
+	go g.AutopilotGame(autoPilots, next, delay, g.autoPilotBreak)
 	slog.Debug("AutoPilots started", "number", g.NumberAutoPilots, "delay", g.DelayAutoPilots)
 }
 
-func (g *Game) AutopilotGame(autoPilots []*player.Player, delay *time.Ticker, finisher chan struct{}) {
+func (g *Game) AutopilotGame(autoPilots []*player.Player, next strategy.Strategy, delay *time.Ticker, finisher chan struct{}) {
 	ctx := context.Background()
-	for !g.Game.HasFinished {
-		randIndex1 := g.GenerateGaussianRandomInt(g.iterarations, 5, g.Game.Size)
-		for _, autoPilot := range autoPilots {
-			g.PlayerMove(ctx, autoPilot.PlayerId, randIndex1)
+	defer close(g.autoPilotDone)
+	defer g.autoPilotRunning.Store(false)
+	defer delay.Stop()
+	for !g.HasFinished() {
+		if !g.waitIfPaused(finisher) {
+			slog.Debug("AutoPilots Breaking while paused", "iterations", g.totalIterations)
+			return
 		}
-		g.iterarations++
-		if g.iterarations > g.Game.Size {
-			g.totalIterations += uint64(g.iterarations)
-			g.iterarations = 0
-			slog.Debug("RESTARTING ITERATOR. Game is still running", "gameId", g.GameId, "size", g.Game.Size, "players", len(g.Players), "duration", time.Since(g.InitTimer).String())
+		// the draws and the iteration change together, so a snapshot never
+		// sees one without the other
+		g.playerMutex.Lock()
+		index := next.Next(board{g.Game})
+		g.advanceIteration()
+		g.playerMutex.Unlock()
+		for _, autoPilot := range autoPilots {
+			g.PlayerMove(ctx, autoPilot.PlayerId, index)
 		}
 		<-delay.C
 
@@ -221,9 +392,56 @@ func (g *Game) AutopilotGame(autoPilots []*player.Player, delay *time.Ticker, fi
 	}
 }
 
+// advanceIteration must be called with playerMutex held.
+func (g *Game) advanceIteration() {
+	g.iterarations++
+	if g.iterarations > g.Game.Size {
+		g.totalIterations += uint64(g.iterarations)
+		g.iterarations = 0
+		slog.Debug("RESTARTING ITERATOR. Game is still running", "gameId", g.GameId, "size", g.Game.Size, "players", len(g.Players), "duration", g.clock.Now().Sub(g.InitTimer).String())
+	}
+}
+
+// board shows the bits of a game to the autopilot strategy.
+type board struct {
+	status *status.GameStatus
+}
+
+func (b board) Size() int {
+	return b.status.Size
+}
+
+func (b board) IsOn(index int) bool {
+	return b.status.IsOn(index)
+}
+
+// StopAutoPilots breaks the autopilot loop and waits for it to return.
+func (g *Game) StopAutoPilots(ctx context.Context) error {
+	if !g.autoPilotRunning.Load() {
+		return nil
+	}
+	select {
+	case g.autoPilotBreak <- struct{}{}:
+	default:
+	}
+	select {
+	case <-g.autoPilotDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (g *Game) Metrics(ctx context.Context) *GameMetrics {
 	log := log.GetLogger(ctx)
 	log.Debug("Getting metrics", "gameId", g.GameId)
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return g.metrics(g.clock.Now())
+}
+
+// metrics must be called with playerMutex held.
+func (g *Game) metrics(now time.Time) *GameMetrics {
 	return &GameMetrics{
 		GameId:                g.GameId,
 		SizeGame:              g.Game.Size,
@@ -233,10 +451,38 @@ func (g *Game) Metrics(ctx context.Context) *GameMetrics {
 		AutoPilotTotalIters:   g.totalIterations,
 		AutoPilotCurrentIters: g.iterarations,
 		AutoPilotMoves:        (g.totalIterations + uint64(g.iterarations)) * uint64(g.NumberAutoPilots),
-		CurrentDuration:       time.Since(g.InitTimer).String(),
+		CurrentDuration:       g.activeDuration(now).String(),
 		GameStatus: GameStatus{
 			IsInProcess: g.Game.HasStarted && !g.Game.HasFinished,
 			IsFinished:  g.Game.HasFinished,
+			IsPaused:    g.resumed != nil,
 		},
+		CreatorId:  g.CreatorId,
+		Visibility: g.Visibility,
+		Spectators: g.spectators,
+	}
+}
+
+// PlayerOwner returns a copy of the player, to tell who may act for it: its
+// account, or the connection of an anonymous player.
+func (g *Game) PlayerOwner(ctx context.Context, playerId string) (player.Player, error) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	p, err := g.GetPlayerById(ctx, playerId)
+	if err != nil {
+		return player.Player{}, err
+	}
+	return *p, nil
+}
+
+func (g *Game) AutoPilotState() *AutoPilotState {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return &AutoPilotState{
+		Running:         g.autoPilotRunning.Load(),
+		AutoPilots:      g.NumberAutoPilots,
+		DelayAutoPilots: g.DelayAutoPilots,
+		Iterations:      g.iterarations,
+		TotalIterations: g.totalIterations,
 	}
 }
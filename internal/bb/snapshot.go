@@ -0,0 +1,256 @@
+package bb
+
+import (
+	"battlebit/internal/log"
+	"battlebit/internal/player"
+	"battlebit/internal/status"
+	"context"
+	"sort"
+	"time"
+)
+
+type Journal interface {
+	AppendEvent(gameId string, event *Event) error
+	SaveSnapshot(snapshot *GameSnapshot) error
+	LoadEvents(gameId string) ([]*Event, error)
+}
+
+func (g *Game) SetJournal(journal Journal) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.journal = journal
+}
+
+func (g *Game) Snapshot() *GameSnapshot {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	return g.snapshot()
+}
+
+func (g *Game) View() *GameView {
+	s := g.Snapshot()
+	return &GameView{
+		GameId:           s.GameId,
+		SizeGame:         s.SizeGame,
+		Status:           s.Status,
+		HasStarted:       s.HasStarted,
+		HasFinished:      s.HasFinished,
+		Paused:           s.Paused,
+		Players:          append(make([]Participant, 0), s.Participants...),
+		NumberAutoPilots: s.NumberAutoPilots,
+		MaxPlayers:       s.MaxPlayers,
+		CreatorId:        s.CreatorId,
+		Visibility:       s.Visibility,
+		Mode:             s.Mode,
+		SpectatorDelayMs: s.SpectatorDelay.Milliseconds(),
+		InitTime:         s.InitTime,
+		LastMoveTime:     s.LastMoveTime,
+		LastMoveBy:       s.LastMoveBy,
+		WinnerId:         s.WinnerId,
+		WinnerName:       s.WinnerName,
+		Seq:              s.Seq,
+	}
+}
+
+func (g *Game) snapshot() *GameSnapshot {
+	players := make([]PlayerSnapshot, 0, len(g.Players))
+	for _, p := range g.Players {
+		players = append(players, PlayerSnapshot{
+			PlayerId:         p.PlayerId,
+			PlayerName:       p.PlayerName,
+			PlayerConnection: p.PlayerConnection,
+			AccountId:        p.AccountId,
+			AutoPilot:        p.AutoPilot,
+		})
+	}
+	return &GameSnapshot{
+		GameId:            g.GameId,
+		SizeGame:          g.Game.Size,
+		Status:            g.Game.Bytes(),
+		HasStarted:        g.Game.HasStarted,
+		HasFinished:       g.Game.HasFinished,
+		Players:           players,
+		NumberAutoPilots:  g.NumberAutoPilots,
+		DelayAutoPilots:   g.DelayAutoPilots,
+		MaxPlayers:        g.MaxPlayers,
+		CreatorId:         g.CreatorId,
+		Visibility:        g.Visibility,
+		Mode:              g.Mode,
+		SpectatorDelay:    g.SpectatorDelay,
+		Participants:      append([]Participant(nil), g.participants...),
+		InviteCode:        g.inviteCode,
+		PasswordSalt:      g.passwordSalt,
+		PasswordHash:      g.passwordHash,
+		InitTime:          g.InitTimer,
+		LastMoveTime:      g.LastMoveTime,
+		LastMoveBy:        g.LastMoveBy,
+		TotalIterations:   g.totalIterations,
+		Iterations:        g.iterarations,
+		WinnerId:          g.WinnerId,
+		WinnerName:        g.WinnerName,
+		Seq:               g.seq,
+		Seed:              g.Seed,
+		RandomDraws:       g.source.draws.Load(),
+		Paused:            g.resumed != nil,
+		PausedAt:          g.pausedAt,
+		PausedTotal:       g.pausedTotal,
+		BannedAccounts:    keys(g.bannedAccounts),
+		BannedConnections: keys(g.bannedConnections),
+		MutedPlayers:      keys(g.mutedPlayers),
+		MutedAccounts:     keys(g.mutedAccounts),
+	}
+}
+
+func keys(m map[string]bool) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// Persist saves a snapshot through the journal, if the game has one.
+func (g *Game) Persist(ctx context.Context) {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.persist(ctx)
+}
+
+// persist must be called with playerMutex held.
+func (g *Game) persist(ctx context.Context) {
+	if g.journal == nil {
+		return
+	}
+	if err := g.journal.SaveSnapshot(g.snapshot()); err != nil {
+		log.GetLogger(ctx).Error("Error saving snapshot", "gameId", g.GameId, "error", err.Error())
+		return
+	}
+	g.truncate(g.seq, g.clock.Now().Add(-g.SpectatorDelay))
+}
+
+// truncate drops from memory the events up to seq that spectators could
+// already see at cutoff. They are read back from the journal when asked for.
+// It must be called with playerMutex held.
+func (g *Game) truncate(seq uint64, cutoff time.Time) {
+	i := sort.Search(len(g.events), func(i int) bool {
+		return g.events[i].Seq > seq || g.events[i].Time.After(cutoff)
+	})
+	if i == 0 {
+		return
+	}
+	g.truncatedSeq = g.events[i-1].Seq
+	g.events = append([]*Event(nil), g.events[i:]...)
+}
+
+func RestoreGame(ctx context.Context, snapshot *GameSnapshot, events []*Event) *Game {
+	slog := log.GetLogger(ctx)
+
+	g := &Game{
+		GameId:           snapshot.GameId,
+		SizeGame:         snapshot.SizeGame,
+		Game:             status.RestoreGameStatus(snapshot.SizeGame, snapshot.Status, snapshot.HasStarted, snapshot.HasFinished),
+		Players:          make([]*player.Player, 0, len(snapshot.Players)),
+		LastMoveTime:     snapshot.LastMoveTime,
+		LastMoveBy:       snapshot.LastMoveBy,
+		InitTimer:        snapshot.InitTime,
+		NumberAutoPilots: snapshot.NumberAutoPilots,
+		DelayAutoPilots:  snapshot.DelayAutoPilots,
+		MaxPlayers:       snapshot.MaxPlayers,
+		CreatorId:        snapshot.CreatorId,
+		Visibility:       snapshot.Visibility,
+		Mode:             snapshot.Mode,
+		SpectatorDelay:   snapshot.SpectatorDelay,
+		inviteCode:       snapshot.InviteCode,
+		passwordSalt:     snapshot.PasswordSalt,
+		passwordHash:     snapshot.PasswordHash,
+		autoPilotBreak:   make(chan struct{}, 1),
+		totalIterations:  snapshot.TotalIterations,
+		iterarations:     snapshot.Iterations,
+		WinnerId:         snapshot.WinnerId,
+		WinnerName:       snapshot.WinnerName,
+		seq:              snapshot.Seq,
+		clock:            SystemClock{},
+		pausedAt:         snapshot.PausedAt,
+		pausedTotal:      snapshot.PausedTotal,
+	}
+	if snapshot.Paused {
+		g.resumed = make(chan struct{})
+	}
+	for _, accountId := range snapshot.BannedAccounts {
+		g.ban(&PlayerKicked{AccountId: accountId})
+	}
+	for _, connection := range snapshot.BannedConnections {
+		g.ban(&PlayerKicked{Connection: connection})
+	}
+	for _, playerId := range snapshot.MutedPlayers {
+		g.mute(&PlayerMuted{PlayerId: playerId, Muted: true})
+	}
+	for _, accountId := range snapshot.MutedAccounts {
+		g.mute(&PlayerMuted{AccountId: accountId, Muted: true})
+	}
+	if g.MaxPlayers == 0 {
+		g.MaxPlayers = DefaultMaxPlayers
+	}
+	if g.Visibility == "" {
+		g.Visibility = VisibilityPublic
+	}
+	if g.Mode == "" {
+		g.Mode = ModeCustom
+	}
+	g.participantIndex = make(map[string]int)
+	for i, p := range snapshot.Participants {
+		g.participants = append(g.participants, p)
+		g.participantIndex[p.PlayerId] = i
+	}
+	g.seedRandom(snapshot.Seed, snapshot.RandomDraws)
+	for _, p := range snapshot.Players {
+		restored := &player.Player{
+			PlayerId:         p.PlayerId,
+			PlayerName:       p.PlayerName,
+			PlayerConnection: p.PlayerConnection,
+			AccountId:        p.AccountId,
+			AutoPilot:        p.AutoPilot,
+		}
+		g.Players = append(g.Players, restored)
+		// snapshots taken before participants were tracked
+		g.participate(restored)
+	}
+	for i, event := range events {
+		if event.Seq <= snapshot.Seq {
+			if event.Type == EventChatMessage {
+				g.keepChat(event)
+			}
+			continue
+		}
+		if err := g.apply(ctx, event); err != nil {
+			slog.Error("Error applying event, dropping the rest of the log", "gameId", g.GameId, "seq", event.Seq, "error", err.Error())
+			events = events[:i]
+			break
+		}
+	}
+	g.events = append(g.events, events...)
+	g.truncate(snapshot.Seq, g.clock.Now().Add(-g.SpectatorDelay))
+	slog.Debug("Game restored", "gameId", g.GameId, "size", g.Game.Size, "players", len(g.Players), "seq", g.seq)
+	return g
+}
+
+func (g *Game) ResumeGame(ctx context.Context) {
+	slog := log.GetLogger(ctx)
+
+	g.playerMutex.Lock()
+	if g.Game.HasFinished {
+		g.playerMutex.Unlock()
+		slog.Debug("Game already finished, not resuming", "gameId", g.GameId)
+		return
+	}
+	autoPilots := make([]*player.Player, 0)
+	for _, p := range g.Players {
+		if p.AutoPilot {
+			autoPilots = append(autoPilots, p)
+		}
+	}
+	g.playerMutex.Unlock()
+	g.runAutoPilots(ctx, autoPilots)
+	slog.Debug("Game resumed", "gameId", g.GameId, "autoPilots", len(autoPilots))
+}
@@ -0,0 +1,210 @@
+package bb
+
+import (
+	"battlebit/internal/status"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memJournal keeps what a game logs, like the file store does on disk.
+type memJournal struct {
+	mutex    sync.Mutex
+	events   []*Event
+	snapshot *GameSnapshot
+}
+
+func (j *memJournal) AppendEvent(gameId string, event *Event) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.events = append(j.events, event)
+	return nil
+}
+
+func (j *memJournal) SaveSnapshot(snapshot *GameSnapshot) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.snapshot = snapshot
+	return nil
+}
+
+func (j *memJournal) LoadEvents(gameId string) ([]*Event, error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if len(j.events) == 0 {
+		return nil, fmt.Errorf("game not found")
+	}
+	return append([]*Event(nil), j.events...), nil
+}
+
+func (j *memJournal) lastSnapshot() *GameSnapshot {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.snapshot
+}
+
+func (j *memJournal) moves() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	n := 0
+	for _, event := range j.events {
+		if event.Type == EventPlayerMoved {
+			n++
+		}
+	}
+	return n
+}
+
+func movedIndexes(events []*Event) []int {
+	indexes := make([]int, 0, len(events))
+	for _, event := range events {
+		if event.Type == EventPlayerMoved {
+			indexes = append(indexes, event.PlayerMoved.Index)
+		}
+	}
+	return indexes
+}
+
+func newSeededGame(seed int64, size int, autoPilots int, journal *memJournal) (*Game, chan struct{}) {
+	g := NewGame(status.NewGameStatus(size), autoPilots)
+	g.SetSeed(seed)
+	g.SetJournal(journal)
+	finished := make(chan struct{})
+	g.SetFinishHook(func(*GameResult) { close(finished) })
+	return g, finished
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitFinished(t *testing.T, finished chan struct{}) {
+	t.Helper()
+	select {
+	case <-finished:
+	case <-time.After(30 * time.Second):
+		t.Fatal("game did not finish")
+	}
+}
+
+func TestRestoreKeepsAutopilotSequence(t *testing.T) {
+	tests := []struct {
+		name       string
+		seed       int64
+		size       int
+		autoPilots int
+		snapshotAt int
+		stopAt     int
+	}{
+		{name: "one autopilot", seed: 1, size: 24, autoPilots: 1, snapshotAt: 10, stopAt: 40},
+		{name: "two autopilots", seed: 42, size: 24, autoPilots: 2, snapshotAt: 20, stopAt: 60},
+		{name: "stopped at the snapshot", seed: 7, size: 16, autoPilots: 3, snapshotAt: 30, stopAt: 30},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			reference := new(memJournal)
+			g, finished := newSeededGame(tt.seed, tt.size, tt.autoPilots, reference)
+			g.StartGame(ctx)
+			waitFinished(t, finished)
+			logged, _ := reference.LoadEvents(g.GameId)
+			want := movedIndexes(logged)
+
+			journal := new(memJournal)
+			g, _ = newSeededGame(tt.seed, tt.size, tt.autoPilots, journal)
+			g.DelayAutoPilots = 1
+			g.StartGame(ctx)
+			waitFor(t, "the snapshot", func() bool { return journal.moves() >= tt.snapshotAt })
+			g.Persist(ctx)
+			waitFor(t, "the stop", func() bool { return journal.moves() >= tt.stopAt })
+			if err := g.StopAutoPilots(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if g.Snapshot().HasFinished {
+				t.Fatal("game finished before the restore, make it larger")
+			}
+			snapshot := journal.lastSnapshot()
+			if tt.stopAt > tt.snapshotAt && snapshot.Seq >= g.Snapshot().Seq {
+				t.Fatal("no events after the snapshot")
+			}
+
+			events, _ := journal.LoadEvents(g.GameId)
+			restored := RestoreGame(ctx, snapshot, events)
+			restored.SetJournal(journal)
+			finished = make(chan struct{})
+			restored.SetFinishHook(func(*GameResult) { close(finished) })
+			restored.ResumeGame(ctx)
+			waitFinished(t, finished)
+
+			if err := restored.StopAutoPilots(ctx); err != nil {
+				t.Fatal(err)
+			}
+			logged, _ = journal.LoadEvents(g.GameId)
+			if got := movedIndexes(logged); !slices.Equal(got, want) {
+				t.Errorf("moves after restore differ from the uninterrupted game\n got %v\nwant %v", got, want)
+			}
+		})
+	}
+}
+
+func TestReplayMatchesRestore(t *testing.T) {
+	tests := []struct {
+		name           string
+		seed           int64
+		size           int
+		autoPilots     int
+		maxPlayers     int
+		visibility     string
+		spectatorDelay time.Duration
+	}{
+		{name: "one autopilot", seed: 3, size: 16, autoPilots: 1, maxPlayers: DefaultMaxPlayers, visibility: VisibilityPublic},
+		{name: "three autopilots", seed: 5, size: 32, autoPilots: 3, maxPlayers: DefaultMaxPlayers, visibility: VisibilityPublic},
+		{name: "custom settings", seed: 9, size: 16, autoPilots: 1, maxPlayers: 2, visibility: VisibilityUnlisted, spectatorDelay: 30 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			journal := new(memJournal)
+			g, finished := newSeededGame(tt.seed, tt.size, tt.autoPilots, journal)
+			g.MaxPlayers = tt.maxPlayers
+			g.SpectatorDelay = tt.spectatorDelay
+			if err := g.SetAccess(tt.visibility, ""); err != nil {
+				t.Fatal(err)
+			}
+			g.StartGame(ctx)
+			waitFinished(t, finished)
+			if err := g.StopAutoPilots(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			events, _ := journal.LoadEvents(g.GameId)
+			replayed, err := Replay(ctx, events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			restored := RestoreGame(ctx, journal.lastSnapshot(), events)
+			for _, s := range []*GameSnapshot{replayed.Snapshot(), restored.Snapshot()} {
+				want := g.Snapshot()
+				if !slices.Equal(s.Status, want.Status) || s.Seq != want.Seq || s.WinnerId != want.WinnerId || s.RandomDraws != want.RandomDraws || s.Iterations != want.Iterations {
+					t.Errorf("rebuilt game differs: seq %d/%d draws %d/%d iterations %d/%d winner %q/%q",
+						s.Seq, want.Seq, s.RandomDraws, want.RandomDraws, s.Iterations, want.Iterations, s.WinnerId, want.WinnerId)
+				}
+				if s.MaxPlayers != tt.maxPlayers || s.Visibility != tt.visibility || s.SpectatorDelay != tt.spectatorDelay {
+					t.Errorf("rebuilt settings differ: max players %d/%d visibility %q/%q spectator delay %v/%v",
+						s.MaxPlayers, tt.maxPlayers, s.Visibility, tt.visibility, s.SpectatorDelay, tt.spectatorDelay)
+				}
+			}
+		})
+	}
+}
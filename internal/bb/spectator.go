@@ -0,0 +1,102 @@
+package bb
+
+import (
+	"context"
+	"time"
+)
+
+// MaxSpectatorDelay bounds the delay a game can set on its spectator feed.
+const MaxSpectatorDelay = time.Hour
+
+// AddSpectator counts a connection watching the game. Spectators are not
+// players, so they do not count toward MaxPlayers.
+func (g *Game) AddSpectator() {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.spectators++
+}
+
+func (g *Game) RemoveSpectator() {
+	g.playerMutex.Lock()
+	defer g.playerMutex.Unlock()
+	g.spectators--
+}
+
+// SpectatorEvents returns the events after afterSeq that are older than the
+// spectator delay at now, and whether they reach the end of a finished game.
+func (g *Game) SpectatorEvents(afterSeq uint64, now time.Time) ([]*Event, bool) {
+	g.playerMutex.Lock()
+	cutoff := now.Add(-g.SpectatorDelay)
+	finished := g.Game.HasFinished
+	g.playerMutex.Unlock()
+	events := g.Events(afterSeq)
+	for i, event := range events {
+		if event.Time.After(cutoff) {
+			return events[:i], false
+		}
+	}
+	return events, finished
+}
+
+// DelayedEvents returns the events after afterSeq that spectators may read at
+// now: those older than the spectator delay while the game is live, all of
+// them once it finished.
+func (g *Game) DelayedEvents(afterSeq uint64, now time.Time) []*Event {
+	g.playerMutex.Lock()
+	finished := g.Game.HasFinished
+	g.playerMutex.Unlock()
+	if finished {
+		return g.Events(afterSeq)
+	}
+	events, _ := g.SpectatorEvents(afterSeq, now)
+	return events
+}
+
+// DelayedView is View with the board and the players rebuilt from
+// DelayedEvents.
+func (g *Game) DelayedView(ctx context.Context, now time.Time) (*GameView, error) {
+	view := g.View()
+	if view.HasFinished || view.SpectatorDelayMs == 0 {
+		return view, nil
+	}
+	past := &GameView{Status: make([]byte, len(view.Status)), Players: make([]Participant, 0)}
+	if events := g.DelayedEvents(0, now); len(events) > 0 {
+		replayed, err := Replay(ctx, events)
+		if err != nil {
+			return nil, err
+		}
+		past = replayed.View()
+	}
+	view.Status, view.HasStarted, view.Paused, view.Players = past.Status, past.HasStarted, past.Paused, past.Players
+	view.LastMoveTime, view.LastMoveBy, view.Seq = past.LastMoveTime, past.LastMoveBy, past.Seq
+	return view, nil
+}
+
+// DelayedMetrics is Metrics as spectators see the game at now, rebuilt from
+// DelayedEvents.
+func (g *Game) DelayedMetrics(ctx context.Context, now time.Time) (*GameMetrics, error) {
+	live := g.Metrics(ctx)
+	g.playerMutex.Lock()
+	delay := g.SpectatorDelay
+	g.playerMutex.Unlock()
+	if live.GameStatus.IsFinished || delay == 0 {
+		return live, nil
+	}
+	events := g.DelayedEvents(0, now)
+	if len(events) == 0 {
+		past := *live
+		past.Players, past.AutoPilotTotalIters, past.AutoPilotCurrentIters, past.AutoPilotMoves = 0, 0, 0, 0
+		past.CurrentDuration = time.Duration(0).String()
+		past.GameStatus = GameStatus{}
+		return &past, nil
+	}
+	replayed, err := Replay(ctx, events)
+	if err != nil {
+		return nil, err
+	}
+	replayed.playerMutex.Lock()
+	past := replayed.metrics(now.Add(-delay))
+	replayed.playerMutex.Unlock()
+	past.CreatorId, past.Visibility, past.Spectators = live.CreatorId, live.Visibility, live.Spectators
+	return past, nil
+}
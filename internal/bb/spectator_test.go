@@ -0,0 +1,85 @@
+package bb
+
+import (
+	"battlebit/internal/player"
+	"battlebit/internal/status"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestDelayedReads(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: start}
+	g := NewGame(status.NewGameStatus(16), 0)
+	g.SetClock(clock)
+	g.SpectatorDelay = time.Minute
+	g.StartGame(ctx)
+	p := player.NewPlayer("alice", "session-1")
+	if _, err := g.AddPlayer(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	clock.advance(10 * time.Second)
+	g.PlayerMove(ctx, p.PlayerId, 3)
+	if _, err := g.SendChat(ctx, &ChatMessage{PlayerId: p.PlayerId, Text: "gg"}); err != nil {
+		t.Fatal(err)
+	}
+	live := g.View()
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantMoves int
+		wantChat  int
+	}{
+		{name: "join past the delay", now: start.Add(65 * time.Second), wantMoves: 0, wantChat: 0},
+		{name: "move past the delay", now: start.Add(75 * time.Second), wantMoves: 1, wantChat: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(movedIndexes(g.DelayedEvents(0, tt.now))); got != tt.wantMoves {
+				t.Errorf("%d moves, want %d", got, tt.wantMoves)
+			}
+			if got := len(g.DelayedChat(0, tt.now)); got != tt.wantChat {
+				t.Errorf("%d chat messages, want %d", got, tt.wantChat)
+			}
+			view, err := g.DelayedView(ctx, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(view.Players) != 1 {
+				t.Errorf("%d players, want the one that joined before the delay", len(view.Players))
+			}
+			if moved := view.Status[0]&(1<<3) != 0; moved != (tt.wantMoves == 1) {
+				t.Errorf("bit 3 set %v with %d moves visible", moved, tt.wantMoves)
+			}
+			if caughtUp := view.Seq == live.Seq; caughtUp != (tt.wantMoves == 1) {
+				t.Errorf("seq %d of %d with %d moves visible", view.Seq, live.Seq, tt.wantMoves)
+			}
+		})
+	}
+
+	g.FinishGame(ctx)
+	if got := len(g.DelayedEvents(0, start.Add(40*time.Second))); got != len(g.Events(0)) {
+		t.Errorf("%d events of the finished game, want all %d", got, len(g.Events(0)))
+	}
+}
@@ -0,0 +1,43 @@
+package chat
+
+import "time"
+
+const (
+	ChannelGame  = "game"
+	ChannelLobby = "lobby"
+)
+
+type SendChat struct {
+	Channel  string `json:"channel"`
+	GameId   string `json:"gameId,omitempty"`
+	PlayerId string `json:"playerId,omitempty"`
+	Text     string `json:"text"`
+}
+
+type GetChat struct {
+	Channel    string `json:"channel"`
+	GameId     string `json:"gameId,omitempty"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Password   string `json:"password,omitempty"`
+	AfterSeq   uint64 `json:"afterSeq"`
+}
+
+// MuteChat mutes a player of a game, or a lobby sender by its senderId when
+// there is no gameId.
+type MuteChat struct {
+	GameId   string `json:"gameId,omitempty"`
+	PlayerId string `json:"playerId,omitempty"`
+	SenderId string `json:"senderId,omitempty"`
+}
+
+type Message struct {
+	Channel    string    `json:"channel"`
+	GameId     string    `json:"gameId,omitempty"`
+	Seq        uint64    `json:"seq"`
+	Time       time.Time `json:"time"`
+	PlayerId   string    `json:"playerId,omitempty"`
+	SenderId   string    `json:"senderId,omitempty"`
+	SenderName string    `json:"senderName"`
+	Text       string    `json:"text"`
+	Filtered   bool      `json:"filtered,omitempty"`
+}
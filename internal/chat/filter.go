@@ -0,0 +1,49 @@
+package chat
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Filter returns the text to publish and whether it changed it. It is the
+// hook to plug a profanity filter in.
+type Filter func(text string) (string, bool)
+
+// NewWordFilter masks the blocked words, ignoring case, with asterisks.
+func NewWordFilter(words []string) Filter {
+	blocked := make(map[string]bool, len(words))
+	for _, w := range words {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			blocked[w] = true
+		}
+	}
+	return func(text string) (string, bool) {
+		if len(blocked) == 0 {
+			return text, false
+		}
+		runes := []rune(text)
+		changed := false
+		for start := 0; start < len(runes); {
+			if !isWordRune(runes[start]) {
+				start++
+				continue
+			}
+			end := start
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			if blocked[strings.ToLower(string(runes[start:end]))] {
+				for i := start; i < end; i++ {
+					runes[i] = '*'
+				}
+				changed = true
+			}
+			start = end
+		}
+		return string(runes), changed
+	}
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,57 @@
+package chat
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter allows each sender limit messages in any window.
+type Limiter struct {
+	limit  int
+	window time.Duration
+	mutex  sync.Mutex
+	sent   map[string][]time.Time
+	swept  time.Time
+}
+
+func NewLimiter(limit int, window time.Duration) *Limiter {
+	return &Limiter{limit: limit, window: window, sent: make(map[string][]time.Time)}
+}
+
+func (l *Limiter) Allow(senderId string, now time.Time) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if now.Sub(l.swept) >= l.window {
+		l.prune(now)
+		l.swept = now
+	}
+	recent := l.sent[senderId][:0]
+	for _, t := range l.sent[senderId] {
+		if now.Sub(t) < l.window {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= l.limit {
+		l.sent[senderId] = recent
+		return false
+	}
+	l.sent[senderId] = append(recent, now)
+	return true
+}
+
+// prune drops the senders with nothing left in their window, so those who
+// stopped chatting are not kept. It must be called with mutex held.
+func (l *Limiter) prune(now time.Time) {
+	for senderId, sent := range l.sent {
+		if len(sent) == 0 || now.Sub(sent[len(sent)-1]) >= l.window {
+			delete(l.sent, senderId)
+		}
+	}
+}
+
+// Forget drops the history of a sender that went away.
+func (l *Limiter) Forget(senderId string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	delete(l.sent, senderId)
+}
@@ -0,0 +1,33 @@
+package chat
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	start := time.Now()
+	l := NewLimiter(2, time.Second)
+
+	allowed := []bool{
+		l.Allow("alice", start),
+		l.Allow("alice", start.Add(100*time.Millisecond)),
+		l.Allow("alice", start.Add(200*time.Millisecond)),
+		l.Allow("alice", start.Add(1100*time.Millisecond)),
+	}
+	want := []bool{true, true, false, true}
+	for i := range want {
+		if allowed[i] != want[i] {
+			t.Errorf("message %d: allowed %v, want %v", i, allowed[i], want[i])
+		}
+	}
+
+	l.Allow("bob", start.Add(1200*time.Millisecond))
+	l.Allow("bob", start.Add(3*time.Second))
+	if _, ok := l.sent["alice"]; ok {
+		t.Error("alice is still kept after the window emptied")
+	}
+	if _, ok := l.sent["bob"]; !ok {
+		t.Error("bob was dropped while chatting")
+	}
+}
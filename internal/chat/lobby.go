@@ -0,0 +1,61 @@
+package chat
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const LobbyHistory = 100
+
+// Lobby is the server wide channel. It keeps the last LobbyHistory messages in
+// memory only.
+type Lobby struct {
+	mutex    sync.Mutex
+	seq      uint64
+	messages []*Message
+	muted    map[string]bool
+}
+
+func NewLobby() *Lobby {
+	return &Lobby{messages: make([]*Message, 0, LobbyHistory), muted: make(map[string]bool)}
+}
+
+func (l *Lobby) Post(msg *Message) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.muted[msg.SenderId] {
+		return fmt.Errorf("sender is muted")
+	}
+	l.seq++
+	msg.Channel = ChannelLobby
+	msg.Seq = l.seq
+	msg.Time = time.Now()
+	if len(l.messages) == LobbyHistory {
+		l.messages = append(l.messages[:0], l.messages[1:]...)
+	}
+	l.messages = append(l.messages, msg)
+	return nil
+}
+
+func (l *Lobby) Mute(senderId string, muted bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if muted {
+		l.muted[senderId] = true
+		return
+	}
+	delete(l.muted, senderId)
+}
+
+func (l *Lobby) History(afterSeq uint64) []*Message {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	messages := make([]*Message, 0)
+	for _, msg := range l.messages {
+		if msg.Seq > afterSeq {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
@@ -0,0 +1,197 @@
+package config
+
+import (
+	"battlebit/internal/auth"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	Log    Log
+	Server Server
+	Hub    Hub
+}
+
+type Log struct {
+	Level string `config:"log-level" help:"log level: debug, info, warn or error"`
+}
+
+type Server struct {
+	Port            int           `config:"port" help:"port the http server listens on"`
+	ReadBufferSize  int           `config:"read-buffer-size" help:"websocket read buffer size in bytes"`
+	WriteBufferSize int           `config:"write-buffer-size" help:"websocket write buffer size in bytes"`
+	AdminToken      string        `config:"admin-token" secret:"true" help:"bearer token for /admin, disabled when empty"`
+	DrainTimeout    time.Duration `config:"drain-timeout" help:"time allowed to drain games on shutdown"`
+	AllowedOrigins  []string      `config:"allowed-origins" help:"comma separated origins allowed to open a websocket, like https://*.example.com; empty allows same origin only, * allows any"`
+	TLSCertFile     string        `config:"tls-cert-file" help:"PEM certificate file, enables TLS together with tls-key-file"`
+	TLSKeyFile      string        `config:"tls-key-file" help:"PEM private key file, enables TLS together with tls-cert-file"`
+	AuthSecret      string        `config:"auth-secret" secret:"true" help:"HMAC secret used to sign player tokens, disabled when empty"`
+	TokenTTL        time.Duration `config:"token-ttl" help:"lifetime of issued player tokens"`
+	RequireAuth     bool          `config:"require-auth" help:"reject join_game from unauthenticated connections"`
+	AnonymousRole   string        `config:"anonymous-role" help:"role of unauthenticated connections: spectator, player or host"`
+	ChatLobby       bool          `config:"chat-lobby" help:"enable the server wide lobby chat channel"`
+	ChatMaxLength   int           `config:"chat-max-length" help:"maximum length of a chat message in characters"`
+	ChatRateLimit   int           `config:"chat-rate-limit" help:"chat messages a sender may send per chat-rate-window"`
+	ChatRateWindow  time.Duration `config:"chat-rate-window" help:"window of the chat rate limit"`
+	ChatBlocked     []string      `config:"chat-blocked-words" help:"comma separated words masked in chat messages"`
+}
+
+type Hub struct {
+	LimitGames       int           `config:"limit-games" help:"maximum number of unfinished games in the hub"`
+	MinGameSize      int           `config:"min-game-size" help:"smallest game size in bits clients may ask for"`
+	MaxGameSize      int           `config:"max-game-size" help:"largest game size in bits clients may ask for"`
+	MaxPlayers       int           `config:"max-players" help:"maximum number of players per game"`
+	StorageDir       string        `config:"storage-dir" help:"directory to persist games, disabled when empty"`
+	SnapshotInterval time.Duration `config:"snapshot-interval" help:"interval between game snapshots"`
+	MatchInterval    time.Duration `config:"match-interval" help:"interval between matchmaking rounds"`
+	MatchFillAfter   time.Duration `config:"match-fill-after" help:"wait before filling a short match with autopilots"`
+	SpectatorDelay   time.Duration `config:"spectator-delay" help:"default delay of the spectator feed of new games"`
+}
+
+func Default() *Config {
+	return &Config{
+		Log: Log{
+			Level: "info",
+		},
+		Server: Server{
+			Port:            8080,
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			DrainTimeout:    30 * time.Second,
+			TokenTTL:        24 * time.Hour,
+			AnonymousRole:   "spectator",
+			ChatLobby:       true,
+			ChatMaxLength:   280,
+			ChatRateLimit:   5,
+			ChatRateWindow:  10 * time.Second,
+		},
+		Hub: Hub{
+			LimitGames:       5,
+			MinGameSize:      1,
+			MaxGameSize:      1 << 24,
+			MaxPlayers:       10,
+			SnapshotInterval: 30 * time.Second,
+			MatchInterval:    time.Second,
+			MatchFillAfter:   30 * time.Second,
+		},
+	}
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	if _, err := parseLevel(c.Log.Level); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+	if c.Server.ReadBufferSize < 1 {
+		errs = append(errs, fmt.Errorf("read-buffer-size must be positive, got %d", c.Server.ReadBufferSize))
+	}
+	if c.Server.WriteBufferSize < 1 {
+		errs = append(errs, fmt.Errorf("write-buffer-size must be positive, got %d", c.Server.WriteBufferSize))
+	}
+	if c.Server.DrainTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("drain-timeout must be positive, got %s", c.Server.DrainTimeout))
+	}
+	for _, origin := range c.Server.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, fmt.Errorf("tls-cert-file and tls-key-file must be set together"))
+	}
+	if c.Server.RequireAuth && c.Server.AuthSecret == "" {
+		errs = append(errs, fmt.Errorf("require-auth needs auth-secret"))
+	}
+	if c.Server.AuthSecret != "" && len(c.Server.AuthSecret) < 32 {
+		errs = append(errs, fmt.Errorf("auth-secret must be at least 32 bytes"))
+	}
+	if role, err := auth.ParseRole(c.Server.AnonymousRole); err != nil {
+		errs = append(errs, fmt.Errorf("anonymous-role: %w", err))
+	} else if role == auth.RoleAdmin {
+		errs = append(errs, fmt.Errorf("anonymous-role must not be admin"))
+	}
+	if c.Server.TokenTTL <= 0 {
+		errs = append(errs, fmt.Errorf("token-ttl must be positive, got %s", c.Server.TokenTTL))
+	}
+	if c.Server.ChatMaxLength < 1 {
+		errs = append(errs, fmt.Errorf("chat-max-length must be positive, got %d", c.Server.ChatMaxLength))
+	}
+	if c.Server.ChatRateLimit < 1 {
+		errs = append(errs, fmt.Errorf("chat-rate-limit must be positive, got %d", c.Server.ChatRateLimit))
+	}
+	if c.Server.ChatRateWindow <= 0 {
+		errs = append(errs, fmt.Errorf("chat-rate-window must be positive, got %s", c.Server.ChatRateWindow))
+	}
+	if c.Hub.LimitGames < 1 {
+		errs = append(errs, fmt.Errorf("limit-games must be positive, got %d", c.Hub.LimitGames))
+	}
+	if c.Hub.MinGameSize < 1 {
+		errs = append(errs, fmt.Errorf("min-game-size must be positive, got %d", c.Hub.MinGameSize))
+	}
+	if c.Hub.MaxGameSize < c.Hub.MinGameSize {
+		errs = append(errs, fmt.Errorf("max-game-size must not be below min-game-size, got %d", c.Hub.MaxGameSize))
+	}
+	if c.Hub.MaxPlayers < 1 {
+		errs = append(errs, fmt.Errorf("max-players must be positive, got %d", c.Hub.MaxPlayers))
+	}
+	if c.Hub.SnapshotInterval <= 0 {
+		errs = append(errs, fmt.Errorf("snapshot-interval must be positive, got %s", c.Hub.SnapshotInterval))
+	}
+	if c.Hub.MatchInterval <= 0 {
+		errs = append(errs, fmt.Errorf("match-interval must be positive, got %s", c.Hub.MatchInterval))
+	}
+	if c.Hub.SpectatorDelay < 0 || c.Hub.SpectatorDelay > time.Hour {
+		errs = append(errs, fmt.Errorf("spectator-delay must be between 0 and 1h, got %s", c.Hub.SpectatorDelay))
+	}
+	if c.Hub.MatchFillAfter < 0 {
+		errs = append(errs, fmt.Errorf("match-fill-after must not be negative, got %s", c.Hub.MatchFillAfter))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Server) TLSEnabled() bool {
+	return s.TLSCertFile != "" && s.TLSKeyFile != ""
+}
+
+func (c *Config) LogLevel() slog.Level {
+	level, _ := parseLevel(c.Log.Level)
+	return level
+}
+
+func parseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("log-level must be debug, info, warn or error, got %q", level)
+}
+
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	host := origin
+	if scheme, rest, ok := strings.Cut(origin, "://"); ok {
+		if scheme != "http" && scheme != "https" {
+			return fmt.Errorf("allowed-origins: %q must use http or https", origin)
+		}
+		host = rest
+	}
+	host = strings.TrimPrefix(host, "*.")
+	if host == "" || strings.ContainsAny(host, "*/?#@ ") {
+		return fmt.Errorf("allowed-origins: %q is not a valid origin", origin)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "battlebit.yaml")
+	if err := os.WriteFile(file, []byte("port: 7000\nlog-level: warn\nlimit-games: 7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		env   map[string]string
+		args  []string
+		check func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != 8080 || cfg.Hub.LimitGames != 5 || cfg.Server.AnonymousRole != "spectator" {
+					t.Errorf("unexpected defaults %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "file over defaults",
+			args: []string{"--config", file},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != 7000 || cfg.Log.Level != "warn" || cfg.Hub.LimitGames != 7 {
+					t.Errorf("file not applied %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "env over file",
+			env:  map[string]string{"BB_CONFIG": file, "BB_PORT": "7100", "BB_SNAPSHOT_INTERVAL": "1m"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != 7100 || cfg.Log.Level != "warn" || cfg.Hub.SnapshotInterval != time.Minute {
+					t.Errorf("env not applied over the file %+v", cfg)
+				}
+			},
+		},
+		{
+			name: "flags over env",
+			env:  map[string]string{"BB_PORT": "7100", "BB_ALLOWED_ORIGINS": "https://a.example"},
+			args: []string{"--port", "7200", "--allowed-origins", "https://b.example, https://c.example"},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.Server.Port != 7200 {
+					t.Errorf("port %d, want 7200", cfg.Server.Port)
+				}
+				if want := []string{"https://b.example", "https://c.example"}; !slices.Equal(cfg.Server.AllowedOrigins, want) {
+					t.Errorf("origins %v, want %v", cfg.Server.AllowedOrigins, want)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BB_CONFIG", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cfg, _, err := Load(tt.args, io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	ini := filepath.Join(t.TempDir(), "battlebit.ini")
+	if err := os.WriteFile(ini, []byte("port=9090\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want string
+	}{
+		{name: "bad integer", env: map[string]string{"BB_PORT": "eighty"}, want: `invalid integer "eighty"`},
+		{name: "bad duration", args: []string{"--drain-timeout", "soon"}, want: `invalid duration "soon"`},
+		{name: "bad boolean", env: map[string]string{"BB_REQUIRE_AUTH": "maybe"}, want: `invalid boolean "maybe"`},
+		{name: "unknown extension", args: []string{"--config", ini}, want: "unsupported extension"},
+		{name: "invalid value", args: []string{"--port", "70000"}, want: "port must be between 1 and 65535"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("BB_CONFIG", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			_, _, err := Load(tt.args, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(cfg *Config)
+		want   string
+	}{
+		{name: "defaults", change: func(cfg *Config) {}},
+		{name: "log level", change: func(cfg *Config) { cfg.Log.Level = "loud" }, want: "log-level must be"},
+		{name: "tls pair", change: func(cfg *Config) { cfg.Server.TLSCertFile = "cert.pem" }, want: "tls-cert-file and tls-key-file"},
+		{name: "require auth without secret", change: func(cfg *Config) { cfg.Server.RequireAuth = true }, want: "require-auth needs auth-secret"},
+		{name: "short secret", change: func(cfg *Config) { cfg.Server.AuthSecret = "short" }, want: "at least 32 bytes"},
+		{name: "origin scheme", change: func(cfg *Config) { cfg.Server.AllowedOrigins = []string{"ftp://a.example"} }, want: "must use http or https"},
+		{name: "wildcard origin", change: func(cfg *Config) { cfg.Server.AllowedOrigins = []string{"https://*.example.com"} }},
+		{name: "anonymous player", change: func(cfg *Config) { cfg.Server.AnonymousRole = "player" }},
+		{name: "anonymous host", change: func(cfg *Config) { cfg.Server.AnonymousRole = "host" }},
+		{name: "anonymous admin", change: func(cfg *Config) { cfg.Server.AnonymousRole = "admin" }, want: "anonymous-role must not be admin"},
+		{name: "unknown anonymous role", change: func(cfg *Config) { cfg.Server.AnonymousRole = "root" }, want: "anonymous-role:"},
+		{name: "limit games", change: func(cfg *Config) { cfg.Hub.LimitGames = 0 }, want: "limit-games must be positive"},
+		{name: "game size range", change: func(cfg *Config) { cfg.Hub.MinGameSize, cfg.Hub.MaxGameSize = 10, 5 }, want: "max-game-size must not be below"},
+		{name: "spectator delay", change: func(cfg *Config) { cfg.Hub.SpectatorDelay = 2 * time.Hour }, want: "spectator-delay must be between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.change(cfg)
+			err := cfg.Validate()
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("error %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
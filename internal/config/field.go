@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+type field struct {
+	key    string
+	help   string
+	secret bool
+	value  reflect.Value
+}
+
+func fieldsOf(cfg *Config) []*field {
+	fields := make([]*field, 0)
+	collectFields(reflect.ValueOf(cfg).Elem(), &fields)
+	return fields
+}
+
+func collectFields(v reflect.Value, fields *[]*field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+			collectFields(v.Field(i), fields)
+			continue
+		}
+		key := sf.Tag.Get("config")
+		if key == "" {
+			continue
+		}
+		*fields = append(*fields, &field{
+			key:    key,
+			help:   sf.Tag.Get("help"),
+			secret: sf.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+}
+
+func (f *field) env() string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(f.key, "-", "_"))
+}
+
+func (f *field) set(s string) error {
+	s = strings.TrimSpace(s)
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", s)
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		f.value.SetInt(int64(n))
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		f.value.SetBool(b)
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(s)
+	case f.value.Kind() == reflect.Slice && f.value.Type().Elem().Kind() == reflect.String:
+		items := make([]string, 0)
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+func (f *field) format() string {
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	if f.value.Kind() == reflect.Slice {
+		return strings.Join(f.value.Interface().([]string), ",")
+	}
+	if f.value.Kind() == reflect.String && f.value.String() == "" {
+		return `""`
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f *field) jsonValue() interface{} {
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	return f.value.Interface()
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const envPrefix = "BB_"
+
+// Load builds the configuration from defaults, the optional config file, the
+// BB_* environment variables and the command line flags, each one overriding
+// the previous. It returns flag.ErrHelp when -h is requested.
+func Load(args []string, output io.Writer) (*Config, bool, error) {
+	cfg := Default()
+	fields := fieldsOf(cfg)
+
+	fs := flag.NewFlagSet("battlebit", flag.ContinueOnError)
+	fs.SetOutput(output)
+	configPath := fs.String("config", os.Getenv(envPrefix+"CONFIG"), "path to a JSON, YAML or TOML config file (env BB_CONFIG)")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	values := make(map[string]*string, len(fields))
+	for _, f := range fields {
+		values[f.key] = fs.String(f.key, "", fmt.Sprintf("%s (env %s, default %s)", f.help, f.env(), f.format()))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if *configPath != "" {
+		fileValues, err := readFile(*configPath)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := apply(fields, fileValues, "config file "+*configPath); err != nil {
+			return nil, false, err
+		}
+	}
+
+	envValues := make(map[string]string)
+	for _, f := range fields {
+		if v, ok := os.LookupEnv(f.env()); ok {
+			envValues[f.key] = v
+		}
+	}
+	if err := apply(fields, envValues, "environment"); err != nil {
+		return nil, false, err
+	}
+
+	flagValues := make(map[string]string)
+	fs.Visit(func(fl *flag.Flag) {
+		if v, ok := values[fl.Name]; ok {
+			flagValues[fl.Name] = *v
+		}
+	})
+	if err := apply(fields, flagValues, "flags"); err != nil {
+		return nil, false, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, false, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return cfg, *printConfig, nil
+}
+
+// Print writes the configuration as a flat JSON object that can be used as a
+// config file. Secrets are redacted.
+func (c *Config) Print(w io.Writer) error {
+	out := make(map[string]interface{})
+	for _, f := range fieldsOf(c) {
+		if f.secret && !f.value.IsZero() {
+			out[f.key] = "<redacted>"
+			continue
+		}
+		out[f.key] = f.jsonValue()
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+func apply(fields []*field, values map[string]string, source string) error {
+	byKey := make(map[string]*field, len(fields))
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, key := range keys {
+		f, ok := byKey[normalizeKey(key)]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", source, key))
+			continue
+		}
+		if err := f.set(values[key]); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", source, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func readFile(path string) (map[string]string, error) {
+	p, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var values map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		values, err = parseJSON(p)
+	case ".yaml", ".yml":
+		values, err = parseYAML(p)
+	case ".toml":
+		values, err = parseTOML(p)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported extension, use .json, .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+func normalizeKey(key string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(key), "_", "-"))
+}
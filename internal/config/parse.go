@@ -0,0 +1,150 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The config files are flat: one key per setting, named like the flags.
+// YAML and TOML support the subset needed for that: scalars, quoted strings,
+// inline lists and, for YAML, block lists.
+
+func parseJSON(p []byte) (map[string]string, error) {
+	raw := make(map[string]interface{})
+	if err := json.Unmarshal(p, &raw); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(raw))
+	for key, v := range raw {
+		switch v := v.(type) {
+		case []interface{}:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			values[key] = strings.Join(items, ",")
+		case map[string]interface{}:
+			return nil, fmt.Errorf("key %q: nested objects are not supported", key)
+		case float64:
+			values[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return values, nil
+}
+
+func parseYAML(p []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	listKey := ""
+	scanner := bufio.NewScanner(bytes.NewReader(p))
+	for n := 1; scanner.Scan(); n++ {
+		line := stripComment(scanner.Text())
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if item, ok := strings.CutPrefix(trimmed, "- "); ok && listKey != "" {
+			values[listKey] = joinItem(values[listKey], unquote(item))
+			continue
+		}
+		if line != strings.TrimLeft(line, " \t") {
+			return nil, fmt.Errorf("line %d: nested keys are not supported", n)
+		}
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value", n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		listKey = ""
+		if value == "" {
+			listKey = key
+			values[key] = ""
+			continue
+		}
+		v, err := parseValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		values[key] = v
+	}
+	return values, scanner.Err()
+}
+
+func parseTOML(p []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(p))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("line %d: tables are not supported", n)
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		v, err := parseValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		values[strings.TrimSpace(key)] = v
+	}
+	return values, scanner.Err()
+}
+
+func parseValue(value string) (string, error) {
+	if strings.HasPrefix(value, "[") {
+		if !strings.HasSuffix(value, "]") {
+			return "", fmt.Errorf("unterminated list %q", value)
+		}
+		items := ""
+		for _, item := range strings.Split(strings.TrimSuffix(strings.TrimPrefix(value, "["), "]"), ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = joinItem(items, unquote(item))
+			}
+		}
+		return items, nil
+	}
+	return unquote(value), nil
+}
+
+func joinItem(items string, item string) string {
+	if items == "" {
+		return item
+	}
+	return items + "," + item
+}
+
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 && (s[0] == '"' && s[len(s)-1] == '"' || s[0] == '\'' && s[len(s)-1] == '\'') {
+		if unquoted, err := strconv.Unquote(`"` + s[1:len(s)-1] + `"`); err == nil {
+			return unquoted
+		}
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// stripComment drops a # comment that is not inside a quoted string.
+func stripComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == 0 && c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
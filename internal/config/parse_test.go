@@ -0,0 +1,107 @@
+package config
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func([]byte) (map[string]string, error)
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "json scalars and lists",
+			parse: parseJSON,
+			input: `{"port": 9090, "log-level": "debug", "require-auth": true, "allowed-origins": ["https://a.example", "*"], "drain-timeout": "5s"}`,
+			want: map[string]string{
+				"port":            "9090",
+				"log-level":       "debug",
+				"require-auth":    "true",
+				"allowed-origins": "https://a.example,*",
+				"drain-timeout":   "5s",
+			},
+		},
+		{
+			name:  "json large number",
+			parse: parseJSON,
+			input: `{"max-game-size": 16777216}`,
+			want:  map[string]string{"max-game-size": "16777216"},
+		},
+		{
+			name:    "json nested object",
+			parse:   parseJSON,
+			input:   `{"server": {"port": 1}}`,
+			wantErr: true,
+		},
+		{
+			name:  "yaml scalars, comments and quotes",
+			parse: parseYAML,
+			input: "---\nport: 9090 # the port\nlog-level: 'warn'\nadmin-token: \"a#b\"\n",
+			want:  map[string]string{"port": "9090", "log-level": "warn", "admin-token": "a#b"},
+		},
+		{
+			name:  "yaml block and inline lists",
+			parse: parseYAML,
+			input: "allowed-origins:\n  - https://a.example\n  - \"https://b.example\"\nchat-blocked-words: [foo, 'bar']\n",
+			want: map[string]string{
+				"allowed-origins":    "https://a.example,https://b.example",
+				"chat-blocked-words": "foo,bar",
+			},
+		},
+		{
+			name:    "yaml nested keys",
+			parse:   parseYAML,
+			input:   "server:\n  port: 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "yaml without colon",
+			parse:   parseYAML,
+			input:   "port 9090\n",
+			wantErr: true,
+		},
+		{
+			name:  "toml",
+			parse: parseTOML,
+			input: "# settings\nport = 9090\nlog-level = \"error\" # quiet\nallowed-origins = [\"https://a.example\", \"https://b.example\"]\n",
+			want: map[string]string{
+				"port":            "9090",
+				"log-level":       "error",
+				"allowed-origins": "https://a.example,https://b.example",
+			},
+		},
+		{
+			name:    "toml table",
+			parse:   parseTOML,
+			input:   "[server]\nport = 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "toml unterminated list",
+			parse:   parseTOML,
+			input:   "allowed-origins = [\"a\"\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.parse([]byte(tt.input))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
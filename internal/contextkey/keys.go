@@ -5,4 +5,5 @@ type ContextKey int
 const (
 	SlogCtx ContextKey = iota // 0
 	ReqIdCtx
+	IdentityCtx
 )
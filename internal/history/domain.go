@@ -0,0 +1,34 @@
+package history
+
+import (
+	"battlebit/internal/bb"
+	"time"
+)
+
+type Persister interface {
+	AppendHistory(entry *Entry) error
+	LoadHistory() ([]*Entry, error)
+}
+
+type Entry struct {
+	GameId     string           `json:"gameId"`
+	FinishedAt time.Time        `json:"finishedAt"`
+	CreatorId  string           `json:"creatorId,omitempty"`
+	Config     bb.GameConfig    `json:"config"`
+	Result     *bb.GameFinished `json:"result"`
+	Players    []bb.Participant `json:"players"`
+}
+
+type ListHistory struct {
+	Player string     `json:"player"`
+	Mode   string     `json:"mode"`
+	From   *time.Time `json:"from"`
+	To     *time.Time `json:"to"`
+	Offset int        `json:"offset"`
+	Limit  int        `json:"limit"`
+}
+
+type Page struct {
+	Total int      `json:"total"`
+	Games []*Entry `json:"games"`
+}
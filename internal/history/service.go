@@ -0,0 +1,134 @@
+package history
+
+import (
+	"battlebit/internal/bb"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const DefaultLimit = 20
+const MaxLimit = 100
+
+type Store struct {
+	mutex     sync.RWMutex
+	entries   []*Entry
+	persister Persister
+}
+
+// NewStore loads the history from persister, which may be nil to keep it in
+// memory only.
+func NewStore(persister Persister) *Store {
+	s := &Store{entries: make([]*Entry, 0), persister: persister}
+	if persister == nil {
+		return s
+	}
+	entries, err := persister.LoadHistory()
+	if err != nil {
+		slog.Error("Error loading history", "error", err.Error())
+		return s
+	}
+	s.entries = append(s.entries, entries...)
+	sort.SliceStable(s.entries, func(i, j int) bool { return s.entries[i].FinishedAt.Before(s.entries[j].FinishedAt) })
+	slog.Debug("History loaded", "games", len(entries))
+	return s
+}
+
+func (s *Store) Add(result *bb.GameResult) {
+	entry := &Entry{
+		GameId:     result.GameId,
+		FinishedAt: result.FinishedAt,
+		CreatorId:  result.CreatorId,
+		Config:     result.Config,
+		Result:     result.Finished,
+		Players:    result.Players,
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// results arrive from concurrent finish hooks, keep them ordered
+	i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].FinishedAt.After(entry.FinishedAt) })
+	s.entries = slices.Insert(s.entries, i, entry)
+	if s.persister == nil {
+		return
+	}
+	if err := s.persister.AppendHistory(entry); err != nil {
+		slog.Error("Error saving history", "gameId", entry.GameId, "error", err.Error())
+	}
+}
+
+func (s *Store) Get(gameId string) (*Entry, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for _, e := range s.entries {
+		if e.GameId == gameId {
+			return e, true
+		}
+	}
+	return nil, false
+}
+
+// List returns the matching games newest first. visible hides games the
+// caller is not allowed to see.
+func (s *Store) List(q ListHistory, visible func(*Entry) bool) *Page {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	q.Limit = min(q.Limit, MaxLimit)
+	q.Offset = max(q.Offset, 0)
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	page := &Page{Games: make([]*Entry, 0)}
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if !q.matches(e) || !visible(e) {
+			continue
+		}
+		if page.Total >= q.Offset && len(page.Games) < q.Limit {
+			page.Games = append(page.Games, e)
+		}
+		page.Total++
+	}
+	return page
+}
+
+func (q *ListHistory) matches(e *Entry) bool {
+	if q.Mode != "" && e.Config.Mode != q.Mode {
+		return false
+	}
+	if q.From != nil && e.FinishedAt.Before(*q.From) {
+		return false
+	}
+	if q.To != nil && !e.FinishedAt.Before(*q.To) {
+		return false
+	}
+	if q.Player == "" {
+		return true
+	}
+	for _, p := range e.Players {
+		if p.AccountId == q.Player || p.PlayerId == q.Player || strings.EqualFold(p.PlayerName, q.Player) {
+			return true
+		}
+	}
+	return false
+}
+
+// GameResult rebuilds the result the entry was recorded from.
+func (e *Entry) GameResult() *bb.GameResult {
+	if e.Result == nil {
+		return nil
+	}
+	return &bb.GameResult{
+		GameId:     e.GameId,
+		Reason:     e.Result.Reason,
+		WinnerId:   e.Result.WinnerId,
+		Duration:   e.Result.Duration,
+		FinishedAt: e.FinishedAt,
+		CreatorId:  e.CreatorId,
+		Finished:   e.Result,
+		Config:     e.Config,
+		Players:    e.Players,
+	}
+}
@@ -1,10 +1,85 @@
 package hub
 
+import (
+	"errors"
+	"time"
+)
+
+var ErrShuttingDown = errors.New("server is shutting down")
+var ErrHubFull = errors.New("hub is full")
+
 type CrateNewGame struct {
-	Size       int `json:"size"`
-	Autopilots int `json:"autopilots"`
+	Size           int    `json:"size"`
+	Autopilots     int    `json:"autopilots"`
+	Seed           *int64 `json:"seed,omitempty"`
+	Visibility     string `json:"visibility,omitempty"`
+	Password       string `json:"password,omitempty"`
+	SpectatorDelay *int   `json:"spectatorDelay,omitempty"`
+	CreatorId      string `json:"-"`
+	Mode           string `json:"-"`
+}
+
+const (
+	ModeDuel       = "duel"
+	ModeSquad      = "squad"
+	ModeFreeForAll = "ffa"
+)
+
+type Enqueue struct {
+	Mode       string `json:"mode"`
+	Size       int    `json:"size"`
+	PlayerName string `json:"playerName"`
+	AccountId  string `json:"-"`
+	Connection string `json:"-"`
+	Owner      string `json:"-"`
+}
+
+type Dequeue struct {
+	TicketId string `json:"ticketId"`
+}
+
+type Ticket struct {
+	TicketId   string    `json:"ticketId"`
+	Mode       string    `json:"mode"`
+	Size       int       `json:"size"`
+	PlayerName string    `json:"playerName"`
+	EnqueuedAt time.Time `json:"enqueuedAt"`
+	Position   int       `json:"position"`
+	accountId  string
+	connection string
+	owner      string
+	notify     func(*MatchFound)
+}
+
+type MatchFound struct {
+	TicketId   string `json:"ticketId"`
+	Mode       string `json:"mode"`
+	Size       int    `json:"size"`
+	GameId     string `json:"gameId,omitempty"`
+	PlayerId   string `json:"playerId,omitempty"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Players    int    `json:"players"`
+	AutoPilots int    `json:"autoPilots"`
+	Error      string `json:"error,omitempty"`
 }
 
 type GameId struct {
 	ID string `json:"id"`
 }
+
+// GetGame finds the game by id, or by invite code for unlisted games.
+type GetGame struct {
+	ID         string `json:"id"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+type Stats struct {
+	LimitGames       int `json:"limitGames"`
+	PendingGames     int `json:"pendingGames"`
+	RunningGames     int `json:"runningGames"`
+	FinishedGames    int `json:"finishedGames"`
+	HumanPlayers     int `json:"humanPlayers"`
+	AutoPilotPlayers int `json:"autoPilotPlayers"`
+	QueuedPlayers    int `json:"queuedPlayers"`
+}
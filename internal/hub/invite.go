@@ -0,0 +1,52 @@
+package hub
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/log"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// inviteAlphabet leaves out 0/O and 1/I so codes survive being read aloud.
+const inviteAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+const inviteLength = 8
+
+// newInviteCode must be called with gamesMutex held.
+func (h *Hub) newInviteCode() (string, error) {
+	buf := make([]byte, inviteLength)
+	for {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for i, b := range buf {
+			buf[i] = inviteAlphabet[int(b)%len(inviteAlphabet)]
+		}
+		code := string(buf[:4]) + "-" + string(buf[4:])
+		if _, taken := h.invites[code]; !taken {
+			return code, nil
+		}
+	}
+}
+
+func (h *Hub) GameByInvite(ctx context.Context, code string) (*bb.Game, error) {
+	slog := log.GetLogger(ctx)
+	code = normalizeInvite(code)
+	h.gamesMutex.RLock()
+	gameId, ok := h.invites[code]
+	h.gamesMutex.RUnlock()
+	if !ok {
+		slog.Debug("Invite code not found", "inviteCode", code)
+		return nil, fmt.Errorf("invite code not found")
+	}
+	return h.GetGame(ctx, GameId{ID: gameId})
+}
+
+func normalizeInvite(code string) string {
+	code = strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	if len(code) != inviteLength {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
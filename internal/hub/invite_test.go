@@ -0,0 +1,123 @@
+package hub
+
+import (
+	"battlebit/internal/config"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func newTestHub(t *testing.T, storageDir string) *Hub {
+	t.Helper()
+	cfg := config.Default().Hub
+	cfg.StorageDir = storageDir
+	return NewHub(cfg)
+}
+
+func TestNormalizeInvite(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{code: "R8GN-QVX6", want: "R8GN-QVX6"},
+		{code: "r8gnqvx6", want: "R8GN-QVX6"},
+		{code: " r8gn qvx6 ", want: "R8GN-QVX6"},
+		{code: "r8-gn-qv-x6", want: "R8GN-QVX6"},
+		{code: "r8gn", want: "R8GN"},
+		{code: "r8gnqvx6x", want: "R8GNQVX6X"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if got := normalizeInvite(tt.code); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewInviteCode(t *testing.T) {
+	h := newTestHub(t, "")
+	format := regexp.MustCompile("^[" + inviteAlphabet + "]{4}-[" + inviteAlphabet + "]{4}$")
+	h.gamesMutex.Lock()
+	defer h.gamesMutex.Unlock()
+	for i := 0; i < 1000; i++ {
+		code, err := h.newInviteCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !format.MatchString(code) {
+			t.Fatalf("code %q does not match %s", code, format)
+		}
+		if _, taken := h.invites[code]; taken {
+			t.Fatalf("code %q handed out twice", code)
+		}
+		h.invites[code] = "game"
+	}
+}
+
+func TestGameByInvite(t *testing.T) {
+	ctx := context.Background()
+	h := newTestHub(t, "")
+	game, err := h.CreateNewGame(ctx, CrateNewGame{Size: 64, Visibility: "unlisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := game.InviteCode()
+	tests := []struct {
+		name    string
+		code    string
+		wantErr bool
+	}{
+		{name: "as handed out", code: code},
+		{name: "lower case without dash", code: strings.ToLower(strings.ReplaceAll(code, "-", ""))},
+		// O and 0 are not in the alphabet, so the code is never handed out
+		{name: "unknown", code: "OOOO-0000", wantErr: true},
+		{name: "empty", code: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := h.GameByInvite(ctx, tt.code)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("found game %s", got.GameId)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.GameId != game.GameId {
+				t.Errorf("found game %s, want %s", got.GameId, game.GameId)
+			}
+		})
+	}
+
+	h.RemoveGame(ctx, GameId{ID: game.GameId})
+	if _, err := h.GameByInvite(ctx, code); err == nil {
+		t.Error("invite code still works after the game was removed")
+	}
+}
+
+func TestInviteSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	h := newTestHub(t, dir)
+	game, err := h.CreateNewGame(ctx, CrateNewGame{Size: 64, Visibility: "unlisted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.StartGame(ctx)
+	if err := h.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newTestHub(t, dir)
+	got, err := restarted.GameByInvite(ctx, game.InviteCode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GameId != game.GameId {
+		t.Errorf("found game %s, want %s", got.GameId, game.GameId)
+	}
+}
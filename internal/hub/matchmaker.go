@@ -0,0 +1,243 @@
+package hub
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/log"
+	"battlebit/internal/player"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const matchmakerId = "matchmaker"
+
+// modePlayers is the number of players a match of the mode waits for.
+func (h *Hub) modePlayers(mode string) (int, error) {
+	players := 0
+	switch mode {
+	case ModeDuel:
+		players = 2
+	case ModeSquad:
+		players = 4
+	case ModeFreeForAll:
+		players = h.MaxPlayers
+	default:
+		return 0, fmt.Errorf("unknown mode %q, want duel, squad or ffa", mode)
+	}
+	return min(players, h.MaxPlayers), nil
+}
+
+func (h *Hub) Enqueue(ctx context.Context, eq Enqueue, notify func(*MatchFound)) (*Ticket, error) {
+	slog := log.GetLogger(ctx)
+
+	if h.closing.Load() {
+		return nil, ErrShuttingDown
+	}
+	if eq.Mode == "" {
+		eq.Mode = ModeFreeForAll
+	}
+	if _, err := h.modePlayers(eq.Mode); err != nil {
+		return nil, err
+	}
+	if err := h.checkSize(eq.Size); err != nil {
+		return nil, err
+	}
+	h.queueMutex.Lock()
+	defer h.queueMutex.Unlock()
+	for _, t := range h.queue {
+		if t.owner == eq.Owner || (eq.AccountId != "" && t.accountId == eq.AccountId) {
+			return nil, fmt.Errorf("already queued with ticket %s", t.TicketId)
+		}
+	}
+	ticket := &Ticket{
+		TicketId:   uuid.New().String(),
+		Mode:       eq.Mode,
+		Size:       eq.Size,
+		PlayerName: eq.PlayerName,
+		EnqueuedAt: time.Now(),
+		accountId:  eq.AccountId,
+		connection: eq.Connection,
+		owner:      eq.Owner,
+		notify:     notify,
+	}
+	h.queue = append(h.queue, ticket)
+	ticket.Position = h.position(ticket)
+	slog.Debug("Player queued", "ticketId", ticket.TicketId, "mode", ticket.Mode, "size", ticket.Size, "position", ticket.Position)
+	return ticket, nil
+}
+
+// position must be called with queueMutex held.
+func (h *Hub) position(ticket *Ticket) int {
+	position := 0
+	for _, t := range h.queue {
+		if t.Mode == ticket.Mode && t.Size == ticket.Size {
+			position++
+		}
+		if t == ticket {
+			break
+		}
+	}
+	return position
+}
+
+// Dequeue removes the ticket if it belongs to owner.
+func (h *Hub) Dequeue(ctx context.Context, ticketId string, owner string) error {
+	h.queueMutex.Lock()
+	defer h.queueMutex.Unlock()
+	for i, t := range h.queue {
+		if t.TicketId == ticketId && t.owner == owner {
+			h.queue = append(h.queue[:i], h.queue[i+1:]...)
+			log.GetLogger(ctx).Debug("Player dequeued", "ticketId", ticketId)
+			return nil
+		}
+	}
+	return fmt.Errorf("ticket not found")
+}
+
+// DequeueOwner drops every ticket of a connection that went away.
+func (h *Hub) DequeueOwner(owner string) {
+	h.queueMutex.Lock()
+	defer h.queueMutex.Unlock()
+	queue := h.queue[:0]
+	for _, t := range h.queue {
+		if t.owner != owner {
+			queue = append(queue, t)
+		}
+	}
+	clear(h.queue[len(queue):])
+	h.queue = queue
+}
+
+func (h *Hub) Queued() int {
+	h.queueMutex.Lock()
+	defer h.queueMutex.Unlock()
+	return len(h.queue)
+}
+
+func (h *Hub) matchmake(interval time.Duration, fillAfter time.Duration) {
+	ctx := context.Background()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if h.closing.Load() {
+			slog.Debug("Matchmaker stopped")
+			return
+		}
+		for _, match := range h.takeMatches(time.Now(), fillAfter) {
+			h.startMatch(ctx, match)
+		}
+	}
+}
+
+type match struct {
+	tickets    []*Ticket
+	autoPilots int
+}
+
+// takeMatches groups queued tickets by mode and size, oldest first. A group
+// short of players is filled with autopilots once its oldest ticket waited
+// fillAfter.
+func (h *Hub) takeMatches(now time.Time, fillAfter time.Duration) []*match {
+	h.queueMutex.Lock()
+	defer h.queueMutex.Unlock()
+
+	groups := make(map[string][]*Ticket)
+	keys := make([]string, 0)
+	for _, t := range h.queue {
+		key := fmt.Sprintf("%s/%d", t.Mode, t.Size)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], t)
+	}
+	matches := make([]*match, 0)
+	taken := make(map[*Ticket]bool)
+	for _, key := range keys {
+		group := groups[key]
+		players, _ := h.modePlayers(group[0].Mode)
+		for len(group) >= players {
+			matches = append(matches, &match{tickets: group[:players]})
+			group = group[players:]
+		}
+		if len(group) > 0 && now.Sub(group[0].EnqueuedAt) >= fillAfter {
+			matches = append(matches, &match{tickets: group, autoPilots: players - len(group)})
+		}
+	}
+	for _, m := range matches {
+		for _, t := range m.tickets {
+			taken[t] = true
+		}
+	}
+	queue := make([]*Ticket, 0, len(h.queue)-len(taken))
+	for _, t := range h.queue {
+		if !taken[t] {
+			queue = append(queue, t)
+		}
+	}
+	h.queue = queue
+	return matches
+}
+
+// requeue puts back the tickets of a match that could not start, in the order
+// they were first queued so they keep their turn.
+func (h *Hub) requeue(tickets []*Ticket) {
+	h.queueMutex.Lock()
+	defer h.queueMutex.Unlock()
+	h.queue = append(h.queue, tickets...)
+	slices.SortStableFunc(h.queue, func(a, b *Ticket) int { return a.EnqueuedAt.Compare(b.EnqueuedAt) })
+}
+
+// startMatch adds the queued players before starting the game so they are in
+// it when the autopilots begin to move.
+func (h *Hub) startMatch(ctx context.Context, m *match) {
+	first := m.tickets[0]
+	game, err := h.CreateNewGame(ctx, CrateNewGame{
+		Size:       first.Size,
+		Autopilots: m.autoPilots,
+		Visibility: bb.VisibilityUnlisted,
+		CreatorId:  matchmakerId,
+		Mode:       first.Mode,
+	})
+	if errors.Is(err, ErrHubFull) {
+		slog.Warn("Hub is full, requeueing match", "mode", first.Mode, "size", first.Size, "players", len(m.tickets))
+		h.requeue(m.tickets)
+		return
+	}
+	if err != nil {
+		slog.Error("Error creating matched game", "mode", first.Mode, "error", err.Error())
+		for _, t := range m.tickets {
+			t.notify(&MatchFound{TicketId: t.TicketId, Mode: t.Mode, Size: t.Size, Error: err.Error()})
+		}
+		return
+	}
+	found := make([]*MatchFound, 0, len(m.tickets))
+	for _, t := range m.tickets {
+		p := player.NewPlayer(t.PlayerName, t.connection)
+		p.AccountId = t.accountId
+		mf := &MatchFound{
+			TicketId:   t.TicketId,
+			Mode:       t.Mode,
+			Size:       t.Size,
+			GameId:     game.GameId,
+			InviteCode: game.InviteCode(),
+			Players:    len(m.tickets),
+			AutoPilots: m.autoPilots,
+		}
+		if _, err := game.AddPlayer(ctx, p); err != nil {
+			mf.Error = err.Error()
+		} else {
+			mf.PlayerId = p.PlayerId
+		}
+		found = append(found, mf)
+	}
+	game.StartGame(ctx)
+	for i, t := range m.tickets {
+		t.notify(found[i])
+	}
+	slog.Info("Match started", "gameId", game.GameId, "mode", first.Mode, "size", first.Size, "players", len(m.tickets), "autoPilots", m.autoPilots)
+}
@@ -0,0 +1,69 @@
+package hub
+
+import (
+	"battlebit/internal/config"
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartMatchRequeuesWhenFull(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default().Hub
+	cfg.LimitGames = 1
+	cfg.MatchInterval = time.Hour
+	h := NewHub(cfg)
+
+	blocking, err := h.CreateNewGame(ctx, CrateNewGame{Size: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutex sync.Mutex
+	found := make(map[string]*MatchFound)
+	notify := func(mf *MatchFound) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		found[mf.TicketId] = mf
+	}
+	tickets := make([]*Ticket, 0, 2)
+	for _, name := range []string{"alice", "bob"} {
+		ticket, err := h.Enqueue(ctx, Enqueue{Mode: ModeDuel, Size: 64, PlayerName: name, Owner: name}, notify)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tickets = append(tickets, ticket)
+	}
+
+	for _, m := range h.takeMatches(time.Now(), time.Hour) {
+		h.startMatch(ctx, m)
+	}
+	if len(found) != 0 {
+		t.Fatalf("players were notified while the hub was full: %+v", found)
+	}
+	h.queueMutex.Lock()
+	queue := append([]*Ticket(nil), h.queue...)
+	h.queueMutex.Unlock()
+	if len(queue) != len(tickets) {
+		t.Fatalf("%d tickets queued, want %d", len(queue), len(tickets))
+	}
+	for i, ticket := range tickets {
+		if queue[i] != ticket || !queue[i].EnqueuedAt.Equal(ticket.EnqueuedAt) {
+			t.Errorf("ticket %d is %s queued at %v, want %s queued at %v", i, queue[i].TicketId, queue[i].EnqueuedAt, ticket.TicketId, ticket.EnqueuedAt)
+		}
+	}
+
+	h.RemoveGame(ctx, GameId{ID: blocking.GameId})
+	for _, m := range h.takeMatches(time.Now(), time.Hour) {
+		h.startMatch(ctx, m)
+	}
+	for _, ticket := range tickets {
+		mf := found[ticket.TicketId]
+		if mf == nil || mf.Error != "" || mf.GameId == "" {
+			t.Errorf("ticket %s: match %+v, want a game", ticket.TicketId, mf)
+		}
+	}
+	if h.Queued() != 0 {
+		t.Errorf("%d tickets still queued", h.Queued())
+	}
+}
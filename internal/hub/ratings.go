@@ -0,0 +1,41 @@
+package hub
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/history"
+	"battlebit/internal/rating"
+)
+
+const defaultLeaderboardLimit = 20
+const maxLeaderboardLimit = 100
+
+// recordResult runs as the finish hook with the game locked, so history and
+// ratings are saved on their own goroutine.
+func (h *Hub) recordResult(result *bb.GameResult) {
+	h.results.Add(1)
+	go func() {
+		defer h.results.Done()
+		h.history.Add(result)
+		h.ratings.Record(result)
+		h.advanceTournament(result)
+	}()
+}
+
+func (h *Hub) ListHistory(q history.ListHistory, visible func(*history.Entry) bool) *history.Page {
+	return h.history.List(q, visible)
+}
+
+func (h *Hub) HistoryEntry(gameId string) (*history.Entry, bool) {
+	return h.history.Get(gameId)
+}
+
+func (h *Hub) Leaderboard(gl rating.GetLeaderboard) *rating.Leaderboard {
+	if gl.Limit <= 0 {
+		gl.Limit = defaultLeaderboardLimit
+	}
+	return h.ratings.Leaderboard(max(gl.Offset, 0), min(gl.Limit, maxLeaderboardLimit))
+}
+
+func (h *Hub) PlayerProfile(accountId string) (*rating.Profile, error) {
+	return h.ratings.Profile(accountId)
+}
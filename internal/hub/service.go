@@ -2,53 +2,228 @@ package hub
 
 import (
 	"battlebit/internal/bb"
+	"battlebit/internal/config"
+	"battlebit/internal/history"
 	"battlebit/internal/log"
+	"battlebit/internal/rating"
 	"battlebit/internal/status"
+	"battlebit/internal/storage"
+	"battlebit/internal/tournament"
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
-	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Hub struct {
-	LimitGames int
-	Games      map[string]*bb.Game
+	LimitGames     int
+	MinGameSize    int
+	MaxGameSize    int
+	MaxPlayers     int
+	SpectatorDelay time.Duration
+	Games          map[string]*bb.Game
+	invites        map[string]string
+	gamesMutex     sync.RWMutex
+	queue          []*Ticket
+	queueMutex     sync.Mutex
+	store          storage.Store
+	ratings        *rating.Book
+	history        *history.Store
+	results        sync.WaitGroup
+	closing        atomic.Bool
+	stopSnapshots  chan struct{}
+	snapshotsDone  chan struct{}
+
+	tournaments   *tournament.Registry
+	watchers      []*watcher
+	watchersMutex sync.Mutex
 }
 
-func NewHub() *Hub {
-	limitGame := getLimitGame()
-	slog.Debug("Created Hub", "limitGames", limitGame)
-	return &Hub{
-		LimitGames: limitGame,
-		Games:      make(map[string]*bb.Game),
+func NewHub(cfg config.Hub) *Hub {
+	slog.Debug("Created Hub", "limitGames", cfg.LimitGames, "maxPlayers", cfg.MaxPlayers)
+	h := &Hub{
+		LimitGames:     cfg.LimitGames,
+		MinGameSize:    cfg.MinGameSize,
+		MaxGameSize:    cfg.MaxGameSize,
+		MaxPlayers:     cfg.MaxPlayers,
+		SpectatorDelay: cfg.SpectatorDelay,
+		Games:          make(map[string]*bb.Game),
+		invites:        make(map[string]string),
+	}
+	go h.matchmake(cfg.MatchInterval, cfg.MatchFillAfter)
+	if cfg.StorageDir == "" {
+		h.ratings = rating.NewBook(nil)
+		h.history = history.NewStore(nil)
+		h.tournaments = tournament.NewRegistry(nil)
+		return h
+	}
+	store, err := storage.NewFileStore(cfg.StorageDir)
+	if err != nil {
+		slog.Error("Error opening storage, games will not be persisted", "dir", cfg.StorageDir, "error", err.Error())
+		h.ratings = rating.NewBook(nil)
+		h.history = history.NewStore(nil)
+		h.tournaments = tournament.NewRegistry(nil)
+		return h
 	}
+	h.store = store
+	h.ratings = rating.NewBook(store)
+	h.history = history.NewStore(store)
+	h.tournaments = tournament.NewRegistry(store)
+	h.restoreGames(context.Background())
+	h.reconcileTournaments()
+	// matches whose game was aborted before the restart
+	h.startTournamentMatches(context.Background())
+	h.stopSnapshots = make(chan struct{})
+	h.snapshotsDone = make(chan struct{})
+	go h.snapshotGames(cfg.SnapshotInterval)
+	return h
 }
 
-func (h *Hub) CreateNewGame(ctx context.Context, ng CrateNewGame) *bb.Game {
+func (h *Hub) CreateNewGame(ctx context.Context, ng CrateNewGame) (*bb.Game, error) {
 	slog := log.GetLogger(ctx)
 
+	if h.closing.Load() {
+		slog.Debug("Rejecting new game, hub is shutting down")
+		return nil, ErrShuttingDown
+	}
+	if err := h.checkSize(ng.Size); err != nil {
+		return nil, err
+	}
 	status := status.NewGameStatus(ng.Size)
 	game := bb.NewGame(status, ng.Autopilots)
+	game.MaxPlayers = h.MaxPlayers
+	game.CreatorId = ng.CreatorId
+	game.SpectatorDelay = h.SpectatorDelay
+	if ng.SpectatorDelay != nil {
+		delay := time.Duration(*ng.SpectatorDelay) * time.Second
+		if delay < 0 || delay > bb.MaxSpectatorDelay {
+			return nil, fmt.Errorf("spectatorDelay must be between 0 and %d seconds", int(bb.MaxSpectatorDelay.Seconds()))
+		}
+		game.SpectatorDelay = delay
+	}
+	if ng.Mode != "" {
+		game.Mode = ng.Mode
+	}
+	if err := game.SetAccess(ng.Visibility, ng.Password); err != nil {
+		return nil, err
+	}
+	if ng.Seed != nil {
+		game.SetSeed(*ng.Seed)
+	}
+	if h.store != nil {
+		game.SetJournal(h.store)
+	}
+	game.SetFinishHook(h.recordResult)
+	h.gamesMutex.Lock()
+	if h.unfinishedGames() >= h.LimitGames {
+		h.gamesMutex.Unlock()
+		slog.Debug("Rejecting new game, hub is full", "limitGames", h.LimitGames)
+		return nil, ErrHubFull
+	}
+	code, err := h.newInviteCode()
+	if err != nil {
+		h.gamesMutex.Unlock()
+		return nil, err
+	}
+	game.SetInviteCode(code)
+	h.invites[code] = game.GameId
 	h.Games[game.GameId] = game
+	h.gamesMutex.Unlock()
 	slog.Debug("Game created", "gameId", game.GameId, "size", ng.Size)
-	return game
+	return game, nil
+}
+
+func (h *Hub) checkSize(size int) error {
+	if size < h.MinGameSize || size > h.MaxGameSize {
+		return fmt.Errorf("size must be between %d and %d", h.MinGameSize, h.MaxGameSize)
+	}
+	return nil
+}
+
+// unfinishedGames must be called with gamesMutex held.
+func (h *Hub) unfinishedGames() int {
+	n := 0
+	for _, g := range h.Games {
+		if !g.HasFinished() {
+			n++
+		}
+	}
+	return n
 }
 
 func (h *Hub) ListGames(ctx context.Context) []*bb.GameMetrics {
 	slog := log.GetLogger(ctx)
 
 	games := make([]*bb.GameMetrics, 0)
+	h.gamesMutex.RLock()
 	for _, g := range h.Games {
 		games = append(games, g.Metrics(ctx))
 	}
+	h.gamesMutex.RUnlock()
 	slog.Debug("List games", "games", len(games))
 	return games
 }
 
+func (h *Hub) Stats() *Stats {
+	stats := &Stats{LimitGames: h.LimitGames, QueuedPlayers: h.Queued()}
+	h.gamesMutex.RLock()
+	defer h.gamesMutex.RUnlock()
+	for _, g := range h.Games {
+		switch {
+		case g.HasFinished():
+			stats.FinishedGames++
+		case g.HasStarted():
+			stats.RunningGames++
+		default:
+			stats.PendingGames++
+		}
+		humans, autoPilots := g.PlayerCounts()
+		stats.HumanPlayers += humans
+		stats.AutoPilotPlayers += autoPilots
+	}
+	return stats
+}
+
+func (h *Hub) Ready(ctx context.Context) error {
+	if h.closing.Load() {
+		return ErrShuttingDown
+	}
+	locked := make(chan struct{})
+	go func() {
+		h.gamesMutex.RLock()
+		h.gamesMutex.RUnlock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		return fmt.Errorf("hub is not responding")
+	}
+	if h.store != nil {
+		if err := h.store.Ping(); err != nil {
+			return fmt.Errorf("storage is not available: %w", err)
+		}
+	}
+	return nil
+}
+
+func (h *Hub) AllGames() []*bb.Game {
+	h.gamesMutex.RLock()
+	defer h.gamesMutex.RUnlock()
+	games := make([]*bb.Game, 0, len(h.Games))
+	for _, g := range h.Games {
+		games = append(games, g)
+	}
+	return games
+}
+
 func (h *Hub) GetGame(ctx context.Context, gameId GameId) (*bb.Game, error) {
 	slog := log.GetLogger(ctx)
+	h.gamesMutex.RLock()
 	g, ok := h.Games[gameId.ID]
+	h.gamesMutex.RUnlock()
 	if !ok {
 		slog.Debug("Game not found", "gameId", gameId.ID)
 		return nil, fmt.Errorf("game not found")
@@ -57,22 +232,160 @@ func (h *Hub) GetGame(ctx context.Context, gameId GameId) (*bb.Game, error) {
 	return g, nil
 }
 
+func (h *Hub) HasGame(gameId string) bool {
+	h.gamesMutex.RLock()
+	defer h.gamesMutex.RUnlock()
+	_, ok := h.Games[gameId]
+	return ok
+}
+
+func (h *Hub) GameEvents(ctx context.Context, gameId GameId) ([]*bb.Event, error) {
+	slog := log.GetLogger(ctx)
+	g, err := h.GetGame(ctx, gameId)
+	if err == nil {
+		return g.Events(0), nil
+	}
+	if h.store == nil {
+		return nil, err
+	}
+	events, err := h.store.LoadEvents(gameId.ID)
+	if err != nil {
+		slog.Debug("Game events not found in storage", "gameId", gameId.ID, "error", err.Error())
+		return nil, fmt.Errorf("game not found")
+	}
+	return events, nil
+}
+
 func (h *Hub) RemoveGame(ctx context.Context, gameId GameId) {
 	slog := log.GetLogger(ctx)
-	delete(h.Games, gameId.ID)
+	h.dropGame(ctx, gameId.ID)
+	if h.tournaments.ReleaseGame(gameId.ID) {
+		slog.Info("Tournament match released", "gameId", gameId.ID)
+	}
+	// matches that found the hub full can take the slot
+	h.startTournamentMatches(ctx)
 	slog.Debug("Game removed", "gameId", gameId.ID)
 }
 
-func getLimitGame() int {
-	limitGameString, ok := os.LookupEnv("BB_LIMIT_GAMES")
+// dropGame forgets a game and deletes its log.
+func (h *Hub) dropGame(ctx context.Context, gameId string) {
+	slog := log.GetLogger(ctx)
+	h.gamesMutex.Lock()
+	g, ok := h.Games[gameId]
+	if ok {
+		delete(h.invites, g.InviteCode())
+	}
+	delete(h.Games, gameId)
+	h.gamesMutex.Unlock()
 	if ok {
-		limitGameInt, err := strconv.Atoi(limitGameString)
-		if err != nil {
-			slog.Error("Error parsing BB_LIMIT_GAMES", "error", err.Error())
-			limitGameInt = 5
-			slog.Debug("Using default value", "BB_LIMIT_GAMES", limitGameInt)
+		// the autopilots would keep appending to the log after it is deleted
+		if err := g.StopAutoPilots(ctx); err != nil {
+			slog.Error("Timed out stopping autopilots", "gameId", gameId, "error", err.Error())
+		}
+		g.SetJournal(nil)
+	}
+	if h.store != nil {
+		if err := h.store.RemoveGame(gameId); err != nil {
+			slog.Error("Error removing game from storage", "gameId", gameId, "error", err.Error())
+		}
+	}
+}
+
+func (h *Hub) StopAccepting() {
+	h.closing.Store(true)
+}
+
+// Shutdown stops every autopilot loop, then persists the unfinished games when
+// storage is configured or aborts them otherwise.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	slog := log.GetLogger(ctx)
+
+	h.StopAccepting()
+	games := h.AllGames()
+	for _, g := range games {
+		if err := g.StopAutoPilots(ctx); err != nil {
+			slog.Error("Timed out stopping autopilots", "gameId", g.GameId, "error", err.Error())
+		}
+	}
+	persisted, aborted := 0, 0
+	for _, g := range games {
+		if ctx.Err() != nil {
+			break
+		}
+		if g.HasFinished() {
+			continue
+		}
+		if h.store != nil {
+			if err := h.store.SaveSnapshot(g.Snapshot()); err != nil {
+				slog.Error("Error saving snapshot", "gameId", g.GameId, "error", err.Error())
+				continue
+			}
+			persisted++
+			continue
+		}
+		g.AbortGame(ctx, bb.FinishReasonShutdown)
+		aborted++
+	}
+	h.results.Wait()
+	if h.store != nil {
+		close(h.stopSnapshots)
+		select {
+		case <-h.snapshotsDone:
+		case <-ctx.Done():
+		}
+		if err := h.store.Close(); err != nil {
+			slog.Error("Error closing storage", "error", err.Error())
+		}
+	}
+	slog.Info("Hub shut down", "games", len(games), "persisted", persisted, "aborted", aborted)
+	return ctx.Err()
+}
+
+func (h *Hub) Persistent() bool {
+	return h.store != nil
+}
+
+func (h *Hub) restoreGames(ctx context.Context) {
+	slog := log.GetLogger(ctx)
+
+	records, err := h.store.LoadGames()
+	if err != nil {
+		slog.Error("Error loading games from storage", "error", err.Error())
+		return
+	}
+	for _, record := range records {
+		game := bb.RestoreGame(ctx, record.Snapshot, record.Events)
+		if game.HasFinished() {
+			slog.Debug("Skipping finished game", "gameId", game.GameId)
+			continue
+		}
+		game.SetJournal(h.store)
+		game.SetFinishHook(h.recordResult)
+		h.gamesMutex.Lock()
+		h.Games[game.GameId] = game
+		if code := game.InviteCode(); code != "" {
+			h.invites[code] = game.GameId
+		}
+		h.gamesMutex.Unlock()
+		game.ResumeGame(ctx)
+		slog.Info("Game restored", "gameId", game.GameId, "size", game.Game.Size, "players", len(game.Players))
+	}
+}
+
+func (h *Hub) snapshotGames(interval time.Duration) {
+	defer close(h.snapshotsDone)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.stopSnapshots:
+			return
+		case <-ticker.C:
+		}
+		games := h.AllGames()
+		for _, g := range games {
+			g.Persist(context.Background())
 		}
-		return limitGameInt
+		slog.Debug("Games snapshotted", "games", len(games))
 	}
-	return 5
 }
@@ -0,0 +1,203 @@
+package hub
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/log"
+	"battlebit/internal/player"
+	"battlebit/internal/rating"
+	"battlebit/internal/tournament"
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+type watcher struct {
+	tournamentId string
+	sessionId    string
+	notify       func(*tournament.Event)
+}
+
+func (h *Hub) CreateTournament(ctx context.Context, ct tournament.CreateTournament) (*tournament.Tournament, error) {
+	if h.closing.Load() {
+		return nil, ErrShuttingDown
+	}
+	if err := h.checkSize(ct.Size); err != nil {
+		return nil, err
+	}
+	t, err := h.tournaments.Create(ct)
+	if err != nil {
+		return nil, err
+	}
+	log.GetLogger(ctx).Info("Tournament created", "tournamentId", t.TournamentId, "format", t.Format, "size", t.Size)
+	return t, nil
+}
+
+func (h *Hub) GetTournament(tournamentId string) (*tournament.Tournament, error) {
+	return h.tournaments.Get(tournamentId)
+}
+
+func (h *Hub) ListTournaments() []*tournament.Summary {
+	return h.tournaments.List()
+}
+
+func (h *Hub) TournamentCreator(tournamentId string) (string, error) {
+	return h.tournaments.CreatorId(tournamentId)
+}
+
+// RegisterEntrant registers the caller and has its connection watch the
+// tournament, so it hears about its matches.
+func (h *Hub) RegisterEntrant(ctx context.Context, reg tournament.Register, sessionId string, notify func(*tournament.Event)) (*tournament.Entrant, error) {
+	entrant, events, err := h.tournaments.Register(reg)
+	if err != nil {
+		return nil, err
+	}
+	h.WatchTournament(reg.TournamentId, sessionId, notify)
+	h.broadcast(events)
+	log.GetLogger(ctx).Debug("Entrant registered", "tournamentId", reg.TournamentId, "entrantId", entrant.EntrantId)
+	return entrant, nil
+}
+
+func (h *Hub) WithdrawEntrant(ctx context.Context, tournamentId string, owner string) error {
+	events, err := h.tournaments.Withdraw(tournamentId, owner)
+	if err != nil {
+		return err
+	}
+	h.broadcast(events)
+	return nil
+}
+
+// StartTournament seeds the bracket by rating and starts the first matches.
+func (h *Hub) StartTournament(ctx context.Context, tournamentId string) (*tournament.Tournament, error) {
+	if h.closing.Load() {
+		return nil, ErrShuttingDown
+	}
+	events, err := h.tournaments.Start(tournamentId, func(accountId string) float64 {
+		if p, err := h.ratings.Profile(accountId); err == nil {
+			return p.Rating
+		}
+		return rating.InitialRating
+	})
+	if err != nil {
+		return nil, err
+	}
+	h.broadcast(events)
+	h.startTournamentMatches(ctx)
+	log.GetLogger(ctx).Info("Tournament started", "tournamentId", tournamentId)
+	return h.tournaments.Get(tournamentId)
+}
+
+func (h *Hub) WatchTournament(tournamentId string, sessionId string, notify func(*tournament.Event)) {
+	h.watchersMutex.Lock()
+	defer h.watchersMutex.Unlock()
+	for _, w := range h.watchers {
+		if w.tournamentId == tournamentId && w.sessionId == sessionId {
+			return
+		}
+	}
+	h.watchers = append(h.watchers, &watcher{tournamentId: tournamentId, sessionId: sessionId, notify: notify})
+}
+
+// Unwatch drops the watches of a connection that went away.
+func (h *Hub) Unwatch(sessionId string) {
+	h.watchersMutex.Lock()
+	defer h.watchersMutex.Unlock()
+	watchers := h.watchers[:0]
+	for _, w := range h.watchers {
+		if w.sessionId != sessionId {
+			watchers = append(watchers, w)
+		}
+	}
+	clear(h.watchers[len(watchers):])
+	h.watchers = watchers
+}
+
+func (h *Hub) broadcast(events []*tournament.Event) {
+	if len(events) == 0 {
+		return
+	}
+	h.watchersMutex.Lock()
+	watchers := append([]*watcher(nil), h.watchers...)
+	h.watchersMutex.Unlock()
+	for _, e := range events {
+		for _, w := range watchers {
+			if w.tournamentId == e.TournamentId {
+				w.notify(e)
+			}
+		}
+	}
+}
+
+// advanceTournament runs from the finish hook goroutine.
+func (h *Hub) advanceTournament(result *bb.GameResult) {
+	events, ok := h.tournaments.Report(result)
+	if !ok {
+		return
+	}
+	h.broadcast(events)
+	h.startTournamentMatches(context.Background())
+}
+
+// reconcileTournaments settles the matches whose game was not restored, from
+// the history when it finished before the restart.
+func (h *Hub) reconcileTournaments() {
+	live := func(gameId string) bool {
+		h.gamesMutex.RLock()
+		defer h.gamesMutex.RUnlock()
+		_, ok := h.Games[gameId]
+		return ok
+	}
+	result := func(gameId string) *bb.GameResult {
+		if e, ok := h.history.Get(gameId); ok {
+			return e.GameResult()
+		}
+		return nil
+	}
+	h.broadcast(h.tournaments.Reconcile(live, result))
+}
+
+// startTournamentMatches creates the game of every match ready to be played,
+// with both entrants in it before it starts.
+func (h *Hub) startTournamentMatches(ctx context.Context) {
+	for _, p := range h.tournaments.Pairings() {
+		if h.closing.Load() {
+			h.tournaments.Release(p)
+			continue
+		}
+		game, err := h.CreateNewGame(ctx, CrateNewGame{
+			Size:       p.Size,
+			Autopilots: p.AutoPilots,
+			Visibility: bb.VisibilityUnlisted,
+			CreatorId:  p.CreatorId,
+			Mode:       tournament.GameMode,
+		})
+		if err != nil {
+			slog.Error("Error creating tournament game", "tournamentId", p.TournamentId, "matchId", p.MatchId, "error", err.Error())
+			h.tournaments.Release(p)
+			continue
+		}
+		playerIds, err := addEntrants(ctx, game, p)
+		if err != nil {
+			// the match waits for the next round of pairings
+			slog.Error("Error adding entrant, match aborted", "tournamentId", p.TournamentId, "matchId", p.MatchId, "error", err.Error())
+			h.dropGame(ctx, game.GameId)
+			h.tournaments.Release(p)
+			continue
+		}
+		h.broadcast(h.tournaments.Assign(p, game.GameId, playerIds))
+		game.StartGame(ctx)
+		slog.Info("Tournament match started", "tournamentId", p.TournamentId, "matchId", p.MatchId, "gameId", game.GameId)
+	}
+}
+
+func addEntrants(ctx context.Context, game *bb.Game, p *tournament.Pairing) ([2]string, error) {
+	var playerIds [2]string
+	for i, e := range p.Entrants {
+		pl := player.NewPlayer(e.Name, e.Connection)
+		pl.AccountId = e.AccountId
+		if _, err := game.AddPlayer(ctx, pl); err != nil {
+			return playerIds, fmt.Errorf("entrant %s: %w", e.EntrantId, err)
+		}
+		playerIds[i] = pl.PlayerId
+	}
+	return playerIds, nil
+}
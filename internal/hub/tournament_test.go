@@ -0,0 +1,42 @@
+package hub
+
+import (
+	"battlebit/internal/config"
+	"battlebit/internal/tournament"
+	"context"
+	"testing"
+)
+
+func TestTournamentMatchAbortedWhenEntrantCannotJoin(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.Default().Hub
+	cfg.MaxPlayers = 1
+	h := NewHub(cfg)
+
+	created, err := h.CreateTournament(ctx, tournament.CreateTournament{Name: "cup", Size: 64, CreatorId: "host"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"alice", "bob"} {
+		reg := tournament.Register{TournamentId: created.TournamentId, PlayerName: name, Connection: name, Owner: name}
+		if _, err := h.RegisterEntrant(ctx, reg, name, func(*tournament.Event) {}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	started, err := h.StartTournament(ctx, created.TournamentId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range started.Matches {
+		if m.GameId != "" || m.Status == tournament.MatchPlaying {
+			t.Errorf("match %s is %s in game %q, want it waiting", m.MatchId, m.Status, m.GameId)
+		}
+	}
+	h.gamesMutex.RLock()
+	games := len(h.Games)
+	h.gamesMutex.RUnlock()
+	if games != 0 {
+		t.Errorf("%d games left in the hub, want the aborted one removed", games)
+	}
+}
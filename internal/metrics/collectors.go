@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http"
+	"runtime"
+)
+
+var Default = NewRegistry()
+
+var (
+	ActiveConnections = Default.NewGaugeVec("battlebit_active_connections", "Open websocket connections.").WithLabelValues()
+	Moves             = Default.NewCounterVec("battlebit_moves_total", "Player moves applied, by kind of player.", "kind")
+	HumanMoves        = Moves.WithLabelValues("human")
+	AutoPilotMoves    = Moves.WithLabelValues("autopilot")
+	RPCDuration       = Default.NewHistogramVec("battlebit_rpc_duration_seconds", "JSON-RPC request latency by method.", DefaultBuckets, "method")
+	RPCErrors         = Default.NewCounterVec("battlebit_rpc_errors_total", "JSON-RPC error responses by method and code.", "method", "code")
+)
+
+func init() {
+	Default.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() []Sample {
+		return []Sample{{Value: float64(runtime.NumGoroutine())}}
+	})
+	Default.register(&memStats{stats: []memStat{
+		{family{name: "go_memstats_heap_alloc_bytes", help: "Bytes of allocated heap objects.", kind: "gauge"}, func(m *runtime.MemStats) float64 { return float64(m.HeapAlloc) }},
+		{family{name: "go_memstats_heap_sys_bytes", help: "Bytes of heap memory obtained from the OS.", kind: "gauge"}, func(m *runtime.MemStats) float64 { return float64(m.HeapSys) }},
+		{family{name: "go_memstats_heap_objects", help: "Number of allocated heap objects.", kind: "gauge"}, func(m *runtime.MemStats) float64 { return float64(m.HeapObjects) }},
+		{family{name: "go_memstats_sys_bytes", help: "Bytes of memory obtained from the OS.", kind: "gauge"}, func(m *runtime.MemStats) float64 { return float64(m.Sys) }},
+		{family{name: "go_memstats_gc_cycles_total", help: "Number of completed GC cycles.", kind: "counter"}, func(m *runtime.MemStats) float64 { return float64(m.NumGC) }},
+		{family{name: "go_memstats_gc_pause_seconds_total", help: "Cumulative seconds spent in GC stop-the-world pauses.", kind: "counter"}, func(m *runtime.MemStats) float64 { return float64(m.PauseTotalNs) / 1e9 }},
+	}})
+}
+
+type memStat struct {
+	family
+	read func(*runtime.MemStats) float64
+}
+
+// memStats reads the runtime stats once per scrape for all of its metrics,
+// since every read stops the world.
+type memStats struct {
+	stats []memStat
+}
+
+func (m *memStats) write(w *bufio.Writer) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	for _, s := range m.stats {
+		s.header(w)
+		s.sample(w, "", nil, "", s.read(&memStats))
+	}
+}
+
+func Handler() http.Handler {
+	return Default.Handler()
+}
@@ -0,0 +1,16 @@
+package metrics
+
+import rtmetrics "runtime/metrics"
+
+const heapObjects = "/memory/classes/heap/objects:bytes"
+
+// HeapBytes returns the bytes of live and not yet swept heap objects, the
+// same as MemStats.HeapAlloc without stopping the world.
+func HeapBytes() uint64 {
+	sample := []rtmetrics.Sample{{Name: heapObjects}}
+	rtmetrics.Read(sample)
+	if sample[0].Value.Kind() != rtmetrics.KindUint64 {
+		return 0
+	}
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,311 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+type Registry struct {
+	mutex      sync.Mutex
+	collectors []collector
+}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		r.mutex.Lock()
+		collectors := append([]collector(nil), r.collectors...)
+		r.mutex.Unlock()
+		for _, c := range collectors {
+			c.write(bw)
+		}
+		bw.Flush()
+	})
+}
+
+type family struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+func (f *family) header(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.kind)
+}
+
+func (f *family) sample(w *bufio.Writer, suffix string, values []string, extra string, v float64) {
+	w.WriteString(f.name)
+	w.WriteString(suffix)
+	pairs := make([]string, 0, len(values)+1)
+	for i, value := range values {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", f.labels[i], escapeLabel(value)))
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+type value struct {
+	bits atomic.Uint64
+}
+
+func (v *value) Add(delta float64) {
+	for {
+		old := v.bits.Load()
+		if v.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+func (v *value) Set(f float64) {
+	v.bits.Store(math.Float64bits(f))
+}
+
+func (v *value) get() float64 {
+	return math.Float64frombits(v.bits.Load())
+}
+
+type Counter struct {
+	value
+}
+
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+type Gauge struct {
+	value
+}
+
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+type vec[T any] struct {
+	family
+	mutex  sync.Mutex
+	values map[string]*T
+	keys   map[string][]string
+}
+
+func (v *vec[T]) with(values []string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	t, ok := v.values[key]
+	if !ok {
+		t = new(T)
+		v.values[key] = t
+		v.keys[key] = append([]string(nil), values...)
+	}
+	return t
+}
+
+func (v *vec[T]) each(fn func(values []string, t *T)) {
+	v.mutex.Lock()
+	keys := make([]string, 0, len(v.values))
+	for key := range v.values {
+		keys = append(keys, key)
+	}
+	v.mutex.Unlock()
+	sort.Strings(keys)
+	for _, key := range keys {
+		v.mutex.Lock()
+		t, values := v.values[key], v.keys[key]
+		v.mutex.Unlock()
+		fn(values, t)
+	}
+}
+
+func newVec[T any](name, help, kind string, labels []string) *vec[T] {
+	return &vec[T]{
+		family: family{name: name, help: help, kind: kind, labels: labels},
+		values: make(map[string]*T),
+		keys:   make(map[string][]string),
+	}
+}
+
+type CounterVec struct {
+	*vec[Counter]
+}
+
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{newVec[Counter](name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+func (c *CounterVec) WithLabelValues(values ...string) *Counter {
+	return c.with(values)
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.header(w)
+	c.each(func(values []string, counter *Counter) {
+		c.sample(w, "", values, "", counter.get())
+	})
+}
+
+type GaugeVec struct {
+	*vec[Gauge]
+}
+
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{newVec[Gauge](name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeVec) WithLabelValues(values ...string) *Gauge {
+	return g.with(values)
+}
+
+func (g *GaugeVec) write(w *bufio.Writer) {
+	g.header(w)
+	g.each(func(values []string, gauge *Gauge) {
+		g.sample(w, "", values, "", gauge.get())
+	})
+}
+
+type Histogram struct {
+	mutex   sync.Mutex
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+func (h *Histogram) Observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+type HistogramVec struct {
+	*vec[Histogram]
+	buckets []float64
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{newVec[Histogram](name, help, "histogram", labels), buckets}
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) WithLabelValues(values ...string) *Histogram {
+	histogram := h.with(values)
+	histogram.mutex.Lock()
+	if histogram.buckets == nil {
+		histogram.buckets = h.buckets
+		histogram.counts = make([]uint64, len(h.buckets))
+	}
+	histogram.mutex.Unlock()
+	return histogram
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.header(w)
+	h.each(func(values []string, histogram *Histogram) {
+		histogram.mutex.Lock()
+		counts := append([]uint64(nil), histogram.counts...)
+		sum, count := histogram.sum, histogram.count
+		histogram.mutex.Unlock()
+		for i, upper := range h.buckets {
+			h.sample(w, "_bucket", values, fmt.Sprintf("le=\"%s\"", formatFloat(upper)), float64(counts[i]))
+		}
+		h.sample(w, "_bucket", values, "le=\"+Inf\"", float64(count))
+		h.sample(w, "_sum", values, "", sum)
+		h.sample(w, "_count", values, "", float64(count))
+	})
+}
+
+type GaugeFunc struct {
+	family
+	collect func() []Sample
+}
+
+// NewGaugeFunc registers a gauge whose samples are computed by collect on
+// every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, collect func() []Sample, labels ...string) *GaugeFunc {
+	g := &GaugeFunc{family{name: name, help: help, kind: "gauge", labels: labels}, collect}
+	r.register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.header(w)
+	for _, s := range g.collect() {
+		g.sample(w, "", s.LabelValues, "", s.Value)
+	}
+}
+
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type %q", ct)
+	}
+	return rec.Body.String()
+}
+
+func TestExposition(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(r *Registry)
+		want  string
+	}{
+		{
+			name: "counter without labels",
+			build: func(r *Registry) {
+				r.NewCounterVec("moves_total", "Moves.").WithLabelValues().Add(3)
+			},
+			want: "# HELP moves_total Moves.\n# TYPE moves_total counter\nmoves_total 3\n",
+		},
+		{
+			name: "counter labels sorted",
+			build: func(r *Registry) {
+				c := r.NewCounterVec("errors_total", "Errors.", "method", "code")
+				c.WithLabelValues("move", "500").Inc()
+				c.WithLabelValues("join", "404").Inc()
+				c.WithLabelValues("join", "404").Inc()
+			},
+			want: "# HELP errors_total Errors.\n# TYPE errors_total counter\n" +
+				"errors_total{method=\"join\",code=\"404\"} 2\n" +
+				"errors_total{method=\"move\",code=\"500\"} 1\n",
+		},
+		{
+			name: "gauge",
+			build: func(r *Registry) {
+				g := r.NewGaugeVec("connections", "Connections.").WithLabelValues()
+				g.Inc()
+				g.Inc()
+				g.Dec()
+			},
+			want: "# HELP connections Connections.\n# TYPE connections gauge\nconnections 1\n",
+		},
+		{
+			name: "escaping",
+			build: func(r *Registry) {
+				r.NewGaugeVec("names", "Help with \\ and\nnewline.", "name").WithLabelValues("a \"b\"\n").Set(0.5)
+			},
+			want: "# HELP names Help with \\\\ and\\nnewline.\n# TYPE names gauge\n" +
+				"names{name=\"a \\\"b\\\"\\n\"} 0.5\n",
+		},
+		{
+			name: "histogram",
+			build: func(r *Registry) {
+				h := r.NewHistogramVec("latency_seconds", "Latency.", []float64{.1, 1}, "method").WithLabelValues("move")
+				h.Observe(.05)
+				h.Observe(.5)
+				h.Observe(2)
+			},
+			want: "# HELP latency_seconds Latency.\n# TYPE latency_seconds histogram\n" +
+				"latency_seconds_bucket{method=\"move\",le=\"0.1\"} 1\n" +
+				"latency_seconds_bucket{method=\"move\",le=\"1\"} 2\n" +
+				"latency_seconds_bucket{method=\"move\",le=\"+Inf\"} 3\n" +
+				"latency_seconds_sum{method=\"move\"} 2.55\n" +
+				"latency_seconds_count{method=\"move\"} 3\n",
+		},
+		{
+			name: "gauge func",
+			build: func(r *Registry) {
+				r.NewGaugeFunc("games", "Games by state.", func() []Sample {
+					return []Sample{{LabelValues: []string{"running"}, Value: 2}, {LabelValues: []string{"paused"}, Value: 1}}
+				}, "state")
+			},
+			want: "# HELP games Games by state.\n# TYPE games gauge\n" +
+				"games{state=\"running\"} 2\ngames{state=\"paused\"} 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.build(r)
+			if got := scrape(t, r); got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuntimeMetrics(t *testing.T) {
+	out := scrape(t, Default)
+	tests := []struct {
+		name string
+		kind string
+	}{
+		{"go_goroutines", "gauge"},
+		{"go_memstats_heap_alloc_bytes", "gauge"},
+		{"go_memstats_sys_bytes", "gauge"},
+		{"go_memstats_gc_cycles_total", "counter"},
+		{"go_memstats_gc_pause_seconds_total", "counter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(out, "# TYPE "+tt.name+" "+tt.kind+"\n") {
+				t.Errorf("no %s %s in\n%s", tt.kind, tt.name, out)
+			}
+			if !strings.Contains(out, "\n"+tt.name+" ") {
+				t.Errorf("no sample of %s", tt.name)
+			}
+		})
+	}
+}
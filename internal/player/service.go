@@ -5,7 +5,9 @@ import "github.com/google/uuid"
 type Player struct {
 	PlayerId         string
 	PlayerName       string
+	AccountId        string
 	PlayerConnection string
+	AutoPilot        bool
 }
 
 func NewPlayer(playerName string, playerConnection string) *Player {
@@ -15,3 +17,9 @@ func NewPlayer(playerName string, playerConnection string) *Player {
 		PlayerConnection: playerConnection,
 	}
 }
+
+func NewAutoPilot(playerName string, playerConnection string) *Player {
+	autoPilot := NewPlayer(playerName, playerConnection)
+	autoPilot.AutoPilot = true
+	return autoPilot
+}
@@ -0,0 +1,71 @@
+package rating
+
+import "time"
+
+const InitialRating = 1500.0
+const KFactor = 32.0
+const maxHistory = 50
+
+type Persister interface {
+	LoadRatings() (*Snapshot, error)
+	SaveRatings(snapshot *Snapshot) error
+}
+
+type Snapshot struct {
+	Profiles  map[string]*Profile `json:"profiles"`
+	AutoPilot AutoPilotRecord     `json:"autoPilot"`
+}
+
+type Profile struct {
+	AccountId         string         `json:"accountId"`
+	Name              string         `json:"name"`
+	Rating            float64        `json:"rating"`
+	Games             int            `json:"games"`
+	Wins              int            `json:"wins"`
+	Losses            int            `json:"losses"`
+	UnratedWins       int            `json:"unratedWins"`
+	AutoPilotLosses   int            `json:"autoPilotLosses"`
+	WinTime           time.Duration  `json:"winTime"`
+	AverageFinishTime string         `json:"averageFinishTime,omitempty"`
+	History           []RatingChange `json:"history"`
+}
+
+type RatingChange struct {
+	GameId string    `json:"gameId"`
+	Time   time.Time `json:"time"`
+	Won    bool      `json:"won"`
+	Rating float64   `json:"rating"`
+	Delta  float64   `json:"delta"`
+}
+
+// AutoPilotRecord counts games with autopilots, kept apart from player
+// ratings.
+type AutoPilotRecord struct {
+	Games int `json:"games"`
+	Wins  int `json:"wins"`
+}
+
+type LeaderboardEntry struct {
+	Rank      int     `json:"rank"`
+	AccountId string  `json:"accountId"`
+	Name      string  `json:"name"`
+	Rating    float64 `json:"rating"`
+	Games     int     `json:"games"`
+	Wins      int     `json:"wins"`
+	Losses    int     `json:"losses"`
+}
+
+type Leaderboard struct {
+	Total     int                 `json:"total"`
+	Players   []*LeaderboardEntry `json:"players"`
+	AutoPilot AutoPilotRecord     `json:"autoPilot"`
+}
+
+type GetLeaderboard struct {
+	Offset int `json:"offset"`
+	Limit  int `json:"limit"`
+}
+
+type GetPlayerProfile struct {
+	AccountId string `json:"accountId"`
+}
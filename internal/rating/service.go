@@ -0,0 +1,203 @@
+package rating
+
+import (
+	"battlebit/internal/bb"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+type Book struct {
+	mutex     sync.Mutex
+	profiles  map[string]*Profile
+	autoPilot AutoPilotRecord
+	store     Persister
+}
+
+// NewBook loads the ratings from store, which may be nil to keep them in
+// memory only.
+func NewBook(store Persister) *Book {
+	b := &Book{profiles: make(map[string]*Profile), store: store}
+	if store == nil {
+		return b
+	}
+	snapshot, err := store.LoadRatings()
+	if err != nil {
+		slog.Error("Error loading ratings", "error", err.Error())
+		return b
+	}
+	if snapshot != nil && snapshot.Profiles != nil {
+		b.profiles = snapshot.Profiles
+		b.autoPilot = snapshot.AutoPilot
+	}
+	slog.Debug("Ratings loaded", "players", len(b.profiles))
+	return b
+}
+
+// Record updates the profiles of the authenticated players of a completed
+// game. Ratings only move between authenticated humans, so wins by autopilots
+// are kept in the autopilot record and wins against nobody rated are unrated.
+func (b *Book) Record(result *bb.GameResult) {
+	if result.Reason != bb.FinishReasonCompleted {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	var winner *bb.Participant
+	rated := make([]*Profile, 0)
+	seen := make(map[string]bool)
+	autoPilots := false
+	for i, p := range result.Players {
+		if p.PlayerId == result.WinnerId {
+			winner = &result.Players[i]
+		}
+		if p.AutoPilot {
+			autoPilots = true
+			continue
+		}
+		if p.AccountId == "" || seen[p.AccountId] {
+			continue
+		}
+		seen[p.AccountId] = true
+		rated = append(rated, b.profile(p.AccountId, p.PlayerName))
+	}
+	if winner == nil {
+		return
+	}
+	if autoPilots {
+		b.autoPilot.Games++
+	}
+	if winner.AutoPilot {
+		b.autoPilot.Wins++
+	}
+
+	var winning *Profile
+	for _, p := range rated {
+		p.Games++
+		if p.AccountId == winner.AccountId && !winner.AutoPilot {
+			winning = p
+			p.Wins++
+			p.WinTime += result.Duration
+			continue
+		}
+		p.Losses++
+		if winner.AutoPilot {
+			p.AutoPilotLosses++
+		}
+	}
+	if winning == nil {
+		return
+	}
+	if len(rated) == 1 {
+		winning.UnratedWins++
+		b.save()
+		return
+	}
+
+	// every loser plays a pairwise ELO match against the winner, with K split
+	// between them so a win is worth the same whatever the game size
+	k := KFactor / float64(len(rated)-1)
+	deltas := make(map[*Profile]float64)
+	for _, p := range rated {
+		if p == winning {
+			continue
+		}
+		expected := 1 / (1 + math.Pow(10, (p.Rating-winning.Rating)/400))
+		delta := k * (1 - expected)
+		deltas[winning] += delta
+		deltas[p] -= delta
+	}
+	for p, delta := range deltas {
+		p.Rating += delta
+		p.History = append(p.History, RatingChange{
+			GameId: result.GameId,
+			Time:   result.FinishedAt,
+			Won:    p == winning,
+			Rating: round(p.Rating),
+			Delta:  round(delta),
+		})
+		if len(p.History) > maxHistory {
+			p.History = p.History[len(p.History)-maxHistory:]
+		}
+	}
+	slog.Debug("Ratings updated", "gameId", result.GameId, "players", len(rated), "winner", winning.AccountId)
+	b.save()
+}
+
+// profile must be called with mutex held.
+func (b *Book) profile(accountId string, name string) *Profile {
+	p, ok := b.profiles[accountId]
+	if !ok {
+		p = &Profile{AccountId: accountId, Rating: InitialRating, History: make([]RatingChange, 0)}
+		b.profiles[accountId] = p
+	}
+	p.Name = name
+	return p
+}
+
+// save must be called with mutex held.
+func (b *Book) save() {
+	if b.store == nil {
+		return
+	}
+	if err := b.store.SaveRatings(&Snapshot{Profiles: b.profiles, AutoPilot: b.autoPilot}); err != nil {
+		slog.Error("Error saving ratings", "error", err.Error())
+	}
+}
+
+func (b *Book) Profile(accountId string) (*Profile, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	p, ok := b.profiles[accountId]
+	if !ok {
+		return nil, fmt.Errorf("player profile not found")
+	}
+	profile := *p
+	profile.Rating = round(p.Rating)
+	profile.History = append([]RatingChange(nil), p.History...)
+	if p.Wins > 0 {
+		profile.AverageFinishTime = (p.WinTime / time.Duration(p.Wins)).String()
+	}
+	return &profile, nil
+}
+
+func (b *Book) Leaderboard(offset int, limit int) *Leaderboard {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	entries := make([]*LeaderboardEntry, 0, len(b.profiles))
+	for _, p := range b.profiles {
+		entries = append(entries, &LeaderboardEntry{
+			AccountId: p.AccountId,
+			Name:      p.Name,
+			Rating:    round(p.Rating),
+			Games:     p.Games,
+			Wins:      p.Wins,
+			Losses:    p.Losses,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Rating != entries[j].Rating {
+			return entries[i].Rating > entries[j].Rating
+		}
+		if entries[i].Wins != entries[j].Wins {
+			return entries[i].Wins > entries[j].Wins
+		}
+		return entries[i].AccountId < entries[j].AccountId
+	})
+	for i, e := range entries {
+		e.Rank = i + 1
+	}
+	board := &Leaderboard{Total: len(entries), Players: make([]*LeaderboardEntry, 0), AutoPilot: b.autoPilot}
+	if offset < len(entries) {
+		board.Players = entries[offset:min(offset+limit, len(entries))]
+	}
+	return board
+}
+
+func round(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,198 @@
+package rating
+
+import (
+	"battlebit/internal/bb"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+type memoryStore struct {
+	saved []byte
+}
+
+func (s *memoryStore) SaveRatings(snapshot *Snapshot) error {
+	p, err := json.Marshal(snapshot)
+	s.saved = p
+	return err
+}
+
+func (s *memoryStore) LoadRatings() (*Snapshot, error) {
+	if s.saved == nil {
+		return nil, nil
+	}
+	snapshot := new(Snapshot)
+	return snapshot, json.Unmarshal(s.saved, snapshot)
+}
+
+func human(accountId string) bb.Participant {
+	return bb.Participant{PlayerId: "p-" + accountId, PlayerName: accountId, AccountId: accountId}
+}
+
+func result(winnerId string, players ...bb.Participant) *bb.GameResult {
+	return &bb.GameResult{
+		GameId:     "game",
+		Reason:     bb.FinishReasonCompleted,
+		WinnerId:   winnerId,
+		Duration:   time.Minute,
+		FinishedAt: time.Now(),
+		Players:    players,
+	}
+}
+
+func TestRecord(t *testing.T) {
+	autoPilot := bb.Participant{PlayerId: "bot", PlayerName: "bot", AutoPilot: true}
+	anonymous := bb.Participant{PlayerId: "anon", PlayerName: "anon"}
+	tests := []struct {
+		name          string
+		result        *bb.GameResult
+		wantRatings   map[string]float64
+		wantUnrated   int
+		wantAutoPilot AutoPilotRecord
+	}{
+		{
+			name:        "one on one",
+			result:      result("p-alice", human("alice"), human("bob")),
+			wantRatings: map[string]float64{"alice": 1516, "bob": 1484},
+		},
+		{
+			name:        "k split between the losers",
+			result:      result("p-alice", human("alice"), human("bob"), human("carol"), human("dave")),
+			wantRatings: map[string]float64{"alice": 1516, "bob": 1494.7, "carol": 1494.7, "dave": 1494.7},
+		},
+		{
+			name:        "same account twice counts once",
+			result:      result("p-alice", human("alice"), human("bob"), bb.Participant{PlayerId: "p-bob-2", AccountId: "bob"}),
+			wantRatings: map[string]float64{"alice": 1516, "bob": 1484},
+		},
+		{
+			name:        "win against nobody rated",
+			result:      result("p-alice", human("alice"), anonymous),
+			wantRatings: map[string]float64{"alice": InitialRating},
+			wantUnrated: 1,
+		},
+		{
+			name:          "autopilot wins",
+			result:        result("bot", human("alice"), human("bob"), autoPilot),
+			wantRatings:   map[string]float64{"alice": InitialRating, "bob": InitialRating},
+			wantAutoPilot: AutoPilotRecord{Games: 1, Wins: 1},
+		},
+		{
+			name:          "human beats autopilot",
+			result:        result("p-alice", human("alice"), human("bob"), autoPilot),
+			wantRatings:   map[string]float64{"alice": 1516, "bob": 1484},
+			wantAutoPilot: AutoPilotRecord{Games: 1},
+		},
+		{
+			name:        "aborted",
+			result:      &bb.GameResult{GameId: "game", Reason: bb.FinishReasonShutdown, WinnerId: "p-alice", Players: []bb.Participant{human("alice"), human("bob")}},
+			wantRatings: map[string]float64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBook(nil)
+			b.Record(tt.result)
+			board := b.Leaderboard(0, 100)
+			if board.Total != len(tt.wantRatings) {
+				t.Errorf("%d profiles, want %d", board.Total, len(tt.wantRatings))
+			}
+			for accountId, want := range tt.wantRatings {
+				p, err := b.Profile(accountId)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if p.Rating != want {
+					t.Errorf("%s rated %v, want %v", accountId, p.Rating, want)
+				}
+				if p.Games != 1 {
+					t.Errorf("%s played %d games, want 1", accountId, p.Games)
+				}
+				if accountId == "alice" && p.UnratedWins != tt.wantUnrated {
+					t.Errorf("%d unrated wins, want %d", p.UnratedWins, tt.wantUnrated)
+				}
+			}
+			if board.AutoPilot != tt.wantAutoPilot {
+				t.Errorf("autopilot record %+v, want %+v", board.AutoPilot, tt.wantAutoPilot)
+			}
+		})
+	}
+}
+
+func TestRecordConservesRating(t *testing.T) {
+	b := NewBook(nil)
+	players := []bb.Participant{human("alice"), human("bob"), human("carol")}
+	for i := 0; i < 20; i++ {
+		b.Record(result(players[i%3].PlayerId, players...))
+		b.Record(result(players[0].PlayerId, players...))
+	}
+	total := 0.0
+	for _, e := range b.Leaderboard(0, 100).Players {
+		total += e.Rating
+	}
+	if math.Abs(total-3*InitialRating) > 0.5 {
+		t.Errorf("ratings add up to %v, want %v", total, 3*InitialRating)
+	}
+	p, err := b.Profile("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.History) != 40 || p.Wins != 27 || p.AverageFinishTime != "1m0s" {
+		t.Errorf("%d changes, %d wins, average %s", len(p.History), p.Wins, p.AverageFinishTime)
+	}
+}
+
+func TestHistoryIsCapped(t *testing.T) {
+	b := NewBook(nil)
+	for i := 0; i < maxHistory+10; i++ {
+		b.Record(result("p-alice", human("alice"), human("bob")))
+	}
+	p, _ := b.Profile("bob")
+	if len(p.History) != maxHistory {
+		t.Errorf("%d changes kept, want %d", len(p.History), maxHistory)
+	}
+}
+
+func TestLeaderboard(t *testing.T) {
+	b := NewBook(nil)
+	b.Record(result("p-alice", human("alice"), human("bob")))
+	b.Record(result("p-alice", human("alice"), human("carol")))
+	b.Record(result("p-dave", human("dave"), human("erin")))
+
+	board := b.Leaderboard(0, 2)
+	if board.Total != 5 || len(board.Players) != 2 {
+		t.Fatalf("%d of %d players, want 2 of 5", len(board.Players), board.Total)
+	}
+	if first := board.Players[0]; first.AccountId != "alice" || first.Rank != 1 || first.Wins != 2 {
+		t.Errorf("first is %+v, want alice with 2 wins", first)
+	}
+	if second := board.Players[1]; second.AccountId != "dave" || second.Rank != 2 {
+		t.Errorf("second is %+v, want dave", second)
+	}
+	if page := b.Leaderboard(4, 10); len(page.Players) != 1 || page.Players[0].Rank != 5 {
+		t.Errorf("last page has %d players", len(page.Players))
+	}
+	if page := b.Leaderboard(10, 10); len(page.Players) != 0 {
+		t.Errorf("page past the end has %d players", len(page.Players))
+	}
+}
+
+func TestRatingsSurviveRestart(t *testing.T) {
+	store := &memoryStore{}
+	b := NewBook(store)
+	b.Record(result("bot", human("alice"), human("bob"), bb.Participant{PlayerId: "bot", AutoPilot: true}))
+	b.Record(result("p-alice", human("alice"), human("bob")))
+
+	restarted := NewBook(store)
+	p, err := restarted.Profile("alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Rating != 1516 || p.Games != 2 || p.AutoPilotLosses != 1 {
+		t.Errorf("alice restored as %+v", p)
+	}
+	if got := restarted.Leaderboard(0, 10).AutoPilot; got != (AutoPilotRecord{Games: 1, Wins: 1}) {
+		t.Errorf("autopilot record restored as %+v", got)
+	}
+}
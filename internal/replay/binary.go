@@ -0,0 +1,395 @@
+package replay
+
+import (
+	"battlebit/internal/bb"
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The binary format is a 4 byte magic and the game id followed by one record
+// per event. Each record stores the event type, the seq and time as deltas of
+// the previous record, and a type specific payload. Player ids are interned in
+// a table on their player_added record and referenced by position afterwards.
+// Every field of the events is kept, so a binary replay rebuilds the same game
+// as a JSONL one.
+var magic = []byte("BBR\x03")
+
+const (
+	codeGameStarted byte = iota + 1
+	codeGameFinished
+	codePlayerAdded
+	codePlayerRemoved
+	codePlayerMoved
+	codePlayerKicked
+	codeGamePaused
+	codeGameResumed
+	codeChatMessage
+	codePlayerMuted
+)
+
+const (
+	flagInProcess byte = 1 << iota
+	flagFinished
+)
+
+type binaryWriter struct {
+	w       *bufio.Writer
+	buf     [binary.MaxVarintLen64]byte
+	players map[string]uint64
+	count   uint64
+}
+
+func WriteBinary(w io.Writer, gameId string, events []*bb.Event) error {
+	bw := &binaryWriter{w: bufio.NewWriter(w), players: make(map[string]uint64)}
+	bw.w.Write(magic)
+	bw.string(gameId)
+	var lastSeq uint64
+	var lastTime int64
+	for _, event := range events {
+		if err := bw.event(event, lastSeq, lastTime); err != nil {
+			return err
+		}
+		lastSeq = event.Seq
+		lastTime = event.Time.UnixNano()
+	}
+	return bw.w.Flush()
+}
+
+func (bw *binaryWriter) event(event *bb.Event, lastSeq uint64, lastTime int64) error {
+	switch event.Type {
+	case bb.EventGameStarted:
+		if event.GameStarted == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codeGameStarted, event, lastSeq, lastTime)
+		bw.uvarint(uint64(event.GameStarted.SizeGame))
+		bw.uvarint(uint64(event.GameStarted.NumberAutoPilots))
+		bw.uvarint(uint64(event.GameStarted.DelayAutoPilots))
+		bw.varint(event.GameStarted.InitTime.UnixNano())
+		bw.varint(event.GameStarted.Seed)
+		bw.string(event.GameStarted.CreatorId)
+		bw.string(event.GameStarted.Mode)
+		bw.uvarint(uint64(event.GameStarted.MaxPlayers))
+		bw.string(event.GameStarted.Visibility)
+		bw.varint(int64(event.GameStarted.SpectatorDelay))
+	case bb.EventGameFinished:
+		if event.GameFinished == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codeGameFinished, event, lastSeq, lastTime)
+		bw.uvarint(uint64(event.GameFinished.SizeGame))
+		bw.uvarint(uint64(event.GameFinished.NumberAutoPilots))
+		bw.varint(event.GameFinished.InitTime.UnixNano())
+		bw.player(event.GameFinished.WinnerId)
+		bw.string(event.GameFinished.WinnerName)
+		bw.varint(int64(event.GameFinished.Duration))
+		bw.string(event.GameFinished.Reason)
+	case bb.EventPlayerAdded:
+		if event.PlayerAdded == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codePlayerAdded, event, lastSeq, lastTime)
+		bw.string(event.PlayerAdded.PlayerId)
+		bw.string(event.PlayerAdded.PlayerName)
+		bw.string(event.PlayerAdded.AccountId)
+		bw.bool(event.PlayerAdded.AutoPilot)
+		bw.count++
+		bw.players[event.PlayerAdded.PlayerId] = bw.count
+	case bb.EventPlayerRemoved:
+		if event.PlayerRemoved == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codePlayerRemoved, event, lastSeq, lastTime)
+		bw.player(event.PlayerRemoved.PlayerId)
+	case bb.EventPlayerMoved:
+		if event.PlayerMoved == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codePlayerMoved, event, lastSeq, lastTime)
+		bw.player(event.PlayerMoved.PlayerId)
+		bw.uvarint(uint64(event.PlayerMoved.Index))
+		var flags byte
+		if event.PlayerMoved.GameStatus.IsInProcess {
+			flags |= flagInProcess
+		}
+		if event.PlayerMoved.GameStatus.IsFinished {
+			flags |= flagFinished
+		}
+		bw.w.WriteByte(flags)
+		bw.varint(event.PlayerMoved.TimeMove.UnixNano() - event.Time.UnixNano())
+		bw.uvarint(event.RandomDraws)
+	case bb.EventPlayerKicked:
+		if event.PlayerKicked == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codePlayerKicked, event, lastSeq, lastTime)
+		bw.player(event.PlayerKicked.PlayerId)
+		bw.string(event.PlayerKicked.Reason)
+		bw.bool(event.PlayerKicked.Banned)
+		bw.string(event.PlayerKicked.AccountId)
+		bw.string(event.PlayerKicked.Connection)
+	case bb.EventGamePaused:
+		bw.header(codeGamePaused, event, lastSeq, lastTime)
+	case bb.EventGameResumed:
+		if event.GameResumed == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codeGameResumed, event, lastSeq, lastTime)
+		bw.varint(int64(event.GameResumed.PausedFor))
+	case bb.EventChatMessage:
+		if event.ChatMessage == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codeChatMessage, event, lastSeq, lastTime)
+		bw.player(event.ChatMessage.PlayerId)
+		bw.string(event.ChatMessage.SenderId)
+		bw.string(event.ChatMessage.SenderName)
+		bw.string(event.ChatMessage.Text)
+		bw.bool(event.ChatMessage.Filtered)
+	case bb.EventPlayerMuted:
+		if event.PlayerMuted == nil {
+			return fmt.Errorf("event %d has no payload", event.Seq)
+		}
+		bw.header(codePlayerMuted, event, lastSeq, lastTime)
+		bw.player(event.PlayerMuted.PlayerId)
+		bw.string(event.PlayerMuted.AccountId)
+		bw.bool(event.PlayerMuted.Muted)
+	default:
+		return fmt.Errorf("event %d has unknown type %q", event.Seq, event.Type)
+	}
+	return nil
+}
+
+func (bw *binaryWriter) header(code byte, event *bb.Event, lastSeq uint64, lastTime int64) {
+	bw.w.WriteByte(code)
+	bw.uvarint(event.Seq - lastSeq)
+	bw.varint(event.Time.UnixNano() - lastTime)
+}
+
+func (bw *binaryWriter) player(playerId string) {
+	ref, ok := bw.players[playerId]
+	if !ok {
+		bw.uvarint(0)
+		bw.string(playerId)
+		return
+	}
+	bw.uvarint(ref)
+}
+
+func (bw *binaryWriter) uvarint(v uint64) {
+	n := binary.PutUvarint(bw.buf[:], v)
+	bw.w.Write(bw.buf[:n])
+}
+
+func (bw *binaryWriter) varint(v int64) {
+	n := binary.PutVarint(bw.buf[:], v)
+	bw.w.Write(bw.buf[:n])
+}
+
+func (bw *binaryWriter) string(s string) {
+	bw.uvarint(uint64(len(s)))
+	bw.w.WriteString(s)
+}
+
+func (bw *binaryWriter) bool(b bool) {
+	if b {
+		bw.w.WriteByte(1)
+		return
+	}
+	bw.w.WriteByte(0)
+}
+
+type binaryReader struct {
+	r       *bufio.Reader
+	gameId  string
+	players []string
+	err     error
+}
+
+func ReadBinary(r io.Reader) (string, []*bb.Event, error) {
+	br := &binaryReader{r: bufio.NewReader(r)}
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br.r, header); err != nil || string(header) != string(magic) {
+		return "", nil, fmt.Errorf("not a battlebit replay")
+	}
+	br.gameId = br.string()
+	events := make([]*bb.Event, 0)
+	var lastSeq uint64
+	var lastTime int64
+	for {
+		code, err := br.r.ReadByte()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		event := &bb.Event{Seq: lastSeq + br.uvarint()}
+		lastTime += br.varint()
+		event.Time = time.Unix(0, lastTime).UTC()
+		br.event(code, event)
+		if br.err != nil {
+			return "", nil, fmt.Errorf("decoding event %d: %w", len(events)+1, br.err)
+		}
+		events = append(events, event)
+		lastSeq = event.Seq
+	}
+	return br.gameId, events, nil
+}
+
+func (br *binaryReader) event(code byte, event *bb.Event) {
+	switch code {
+	case codeGameStarted:
+		event.Type = bb.EventGameStarted
+		event.GameStarted = &bb.GameStarted{
+			GameId:           br.gameId,
+			SizeGame:         int(br.uvarint()),
+			NumberAutoPilots: int(br.uvarint()),
+			DelayAutoPilots:  int(br.uvarint()),
+			InitTime:         time.Unix(0, br.varint()).UTC(),
+			Seed:             br.varint(),
+			CreatorId:        br.string(),
+			Mode:             br.string(),
+			MaxPlayers:       int(br.uvarint()),
+			Visibility:       br.string(),
+			SpectatorDelay:   time.Duration(br.varint()),
+		}
+	case codeGameFinished:
+		event.Type = bb.EventGameFinished
+		event.GameFinished = &bb.GameFinished{
+			GameId:           br.gameId,
+			SizeGame:         int(br.uvarint()),
+			NumberAutoPilots: int(br.uvarint()),
+			InitTime:         time.Unix(0, br.varint()).UTC(),
+			WinnerId:         br.player(),
+			WinnerName:       br.string(),
+			Duration:         time.Duration(br.varint()),
+			Reason:           br.string(),
+		}
+	case codePlayerAdded:
+		added := &bb.PlayerAdded{
+			GameId:     br.gameId,
+			PlayerId:   br.string(),
+			PlayerName: br.string(),
+			AccountId:  br.string(),
+			AutoPilot:  br.bool(),
+		}
+		event.Type = bb.EventPlayerAdded
+		event.PlayerAdded = added
+		br.players = append(br.players, added.PlayerId)
+	case codePlayerRemoved:
+		event.Type = bb.EventPlayerRemoved
+		event.PlayerRemoved = &bb.PlayerRemoved{
+			GameId:   br.gameId,
+			PlayerId: br.player(),
+		}
+	case codePlayerMoved:
+		moved := &bb.PlayerMoved{
+			GameId:   br.gameId,
+			PlayerId: br.player(),
+			Index:    int(br.uvarint()),
+		}
+		flags := br.byte()
+		moved.GameStatus.IsInProcess = flags&flagInProcess != 0
+		moved.GameStatus.IsFinished = flags&flagFinished != 0
+		moved.TimeMove = event.Time.Add(time.Duration(br.varint()))
+		event.RandomDraws = br.uvarint()
+		event.Type = bb.EventPlayerMoved
+		event.PlayerMoved = moved
+	case codePlayerKicked:
+		event.Type = bb.EventPlayerKicked
+		event.PlayerKicked = &bb.PlayerKicked{
+			GameId:     br.gameId,
+			PlayerId:   br.player(),
+			Reason:     br.string(),
+			Banned:     br.bool(),
+			AccountId:  br.string(),
+			Connection: br.string(),
+		}
+	case codeGamePaused:
+		event.Type = bb.EventGamePaused
+		event.GamePaused = &bb.GamePaused{GameId: br.gameId}
+	case codeGameResumed:
+		event.Type = bb.EventGameResumed
+		event.GameResumed = &bb.GameResumed{
+			GameId:    br.gameId,
+			PausedFor: time.Duration(br.varint()),
+		}
+	case codeChatMessage:
+		event.Type = bb.EventChatMessage
+		event.ChatMessage = &bb.ChatMessage{
+			GameId:     br.gameId,
+			PlayerId:   br.player(),
+			SenderId:   br.string(),
+			SenderName: br.string(),
+			Text:       br.string(),
+			Filtered:   br.bool(),
+		}
+	case codePlayerMuted:
+		event.Type = bb.EventPlayerMuted
+		event.PlayerMuted = &bb.PlayerMuted{
+			GameId:    br.gameId,
+			PlayerId:  br.player(),
+			AccountId: br.string(),
+			Muted:     br.bool(),
+		}
+	default:
+		br.fail(fmt.Errorf("unknown record type %d", code))
+	}
+}
+
+func (br *binaryReader) player() string {
+	ref := br.uvarint()
+	if ref == 0 {
+		return br.string()
+	}
+	if ref > uint64(len(br.players)) {
+		br.fail(fmt.Errorf("unknown player reference %d", ref))
+		return ""
+	}
+	return br.players[ref-1]
+}
+
+func (br *binaryReader) uvarint() uint64 {
+	v, err := binary.ReadUvarint(br.r)
+	br.fail(err)
+	return v
+}
+
+func (br *binaryReader) varint() int64 {
+	v, err := binary.ReadVarint(br.r)
+	br.fail(err)
+	return v
+}
+
+func (br *binaryReader) string() string {
+	n := br.uvarint()
+	if br.err != nil || n > 1<<20 {
+		br.fail(fmt.Errorf("invalid string length %d", n))
+		return ""
+	}
+	p := make([]byte, n)
+	_, err := io.ReadFull(br.r, p)
+	br.fail(err)
+	return string(p)
+}
+
+func (br *binaryReader) byte() byte {
+	b, err := br.r.ReadByte()
+	br.fail(err)
+	return b
+}
+
+func (br *binaryReader) bool() bool {
+	return br.byte() != 0
+}
+
+func (br *binaryReader) fail(err error) {
+	if br.err == nil && err != nil {
+		br.err = err
+	}
+}
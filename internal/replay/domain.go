@@ -0,0 +1,65 @@
+package replay
+
+import "battlebit/internal/bb"
+
+type ExportReplay struct {
+	GameId     string `json:"gameId"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Password   string `json:"password,omitempty"`
+	Format     string `json:"format"`
+}
+
+type ExportedReplay struct {
+	GameId   string `json:"gameId"`
+	Format   string `json:"format"`
+	Events   int    `json:"events"`
+	Encoding string `json:"encoding"`
+	Data     string `json:"data"`
+}
+
+type StartReplay struct {
+	GameId     string  `json:"gameId"`
+	InviteCode string  `json:"inviteCode,omitempty"`
+	Password   string  `json:"password,omitempty"`
+	Speed      float64 `json:"speed"`
+	FromSeq    uint64  `json:"fromSeq"`
+}
+
+type ReplayStarted struct {
+	ReplayId string  `json:"replayId"`
+	GameId   string  `json:"gameId"`
+	Events   int     `json:"events"`
+	Speed    float64 `json:"speed"`
+}
+
+type SeekReplay struct {
+	ReplayId string  `json:"replayId"`
+	Seq      *uint64 `json:"seq,omitempty"`
+	OffsetMs *int64  `json:"offsetMs,omitempty"`
+}
+
+type ReplaySpeed struct {
+	ReplayId string  `json:"replayId"`
+	Speed    float64 `json:"speed"`
+}
+
+type ReplayId struct {
+	ReplayId string `json:"replayId"`
+}
+
+type ReplayEvent struct {
+	ReplayId string    `json:"replayId"`
+	Event    *bb.Event `json:"event"`
+}
+
+type ReplaySeeked struct {
+	ReplayId string       `json:"replayId"`
+	Seq      uint64       `json:"seq"`
+	State    *bb.GameView `json:"state,omitempty"`
+}
+
+type ReplayFinished struct {
+	ReplayId string `json:"replayId"`
+	Seq      uint64 `json:"seq"`
+	Events   int    `json:"events"`
+}
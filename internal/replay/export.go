@@ -0,0 +1,59 @@
+package replay
+
+import (
+	"battlebit/internal/bb"
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const FORMAT_JSONL = "jsonl"
+const FORMAT_BINARY = "binary"
+
+func Export(format string, gameId string, events []*bb.Event) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	var err error
+	switch format {
+	case FORMAT_JSONL, "":
+		err = WriteJSONL(buf, events)
+	case FORMAT_BINARY:
+		err = WriteBinary(buf, gameId, events)
+	default:
+		err = fmt.Errorf("unknown replay format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func WriteJSONL(w io.Writer, events []*bb.Event) error {
+	encoder := json.NewEncoder(w)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ReadJSONL(r io.Reader) ([]*bb.Event, error) {
+	events := make([]*bb.Event, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		event := new(bb.Event)
+		if err := json.Unmarshal([]byte(line), event); err != nil {
+			return nil, fmt.Errorf("decoding event %d: %w", len(events)+1, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
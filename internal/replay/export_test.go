@@ -0,0 +1,94 @@
+package replay
+
+import (
+	"battlebit/internal/bb"
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// gameEvents has one event of every type, with every field set.
+func gameEvents() []*bb.Event {
+	const gameId = "game-1"
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	return []*bb.Event{
+		{Seq: 1, Time: at(0), Type: bb.EventGameStarted, GameStarted: &bb.GameStarted{GameId: gameId, SizeGame: 64, InitTime: start, NumberAutoPilots: 1, DelayAutoPilots: 250, Seed: -42, CreatorId: "account-1", Mode: "duel", MaxPlayers: 2, Visibility: bb.VisibilityUnlisted, SpectatorDelay: 5 * time.Second}},
+		{Seq: 2, Time: at(1), Type: bb.EventPlayerAdded, PlayerAdded: &bb.PlayerAdded{GameId: gameId, PlayerId: "bot", PlayerName: "autopilot", AutoPilot: true}},
+		{Seq: 3, Time: at(2), Type: bb.EventPlayerAdded, PlayerAdded: &bb.PlayerAdded{GameId: gameId, PlayerId: "alice", PlayerName: "alice", AccountId: "account-2"}},
+		{Seq: 4, Time: at(3), Type: bb.EventPlayerMoved, RandomDraws: 7, PlayerMoved: &bb.PlayerMoved{GameId: gameId, PlayerId: "bot", Index: 63, TimeMove: at(3), GameStatus: bb.GameStatus{IsInProcess: true}}},
+		{Seq: 5, Time: at(4), Type: bb.EventChatMessage, ChatMessage: &bb.ChatMessage{GameId: gameId, PlayerId: "alice", SenderId: "account-2", SenderName: "alice", Text: "gg ✓", Filtered: true}},
+		{Seq: 6, Time: at(5), Type: bb.EventChatMessage, ChatMessage: &bb.ChatMessage{GameId: gameId, SenderId: "account-1", SenderName: "host", Text: "behave"}},
+		{Seq: 7, Time: at(6), Type: bb.EventPlayerMuted, PlayerMuted: &bb.PlayerMuted{GameId: gameId, PlayerId: "alice", AccountId: "account-2", Muted: true}},
+		{Seq: 8, Time: at(7), Type: bb.EventGamePaused, GamePaused: &bb.GamePaused{GameId: gameId}},
+		{Seq: 9, Time: at(9), Type: bb.EventGameResumed, GameResumed: &bb.GameResumed{GameId: gameId, PausedFor: 2 * time.Second}},
+		{Seq: 10, Time: at(10), Type: bb.EventPlayerKicked, PlayerKicked: &bb.PlayerKicked{GameId: gameId, PlayerId: "alice", Reason: "spam", Banned: true, AccountId: "account-2", Connection: "session-2"}},
+		{Seq: 11, Time: at(11), Type: bb.EventPlayerRemoved, PlayerRemoved: &bb.PlayerRemoved{GameId: gameId, PlayerId: "ghost"}},
+		{Seq: 13, Time: at(12), Type: bb.EventPlayerMoved, PlayerMoved: &bb.PlayerMoved{GameId: gameId, PlayerId: "bot", Index: 0, TimeMove: at(12).Add(-time.Millisecond), GameStatus: bb.GameStatus{IsFinished: true}}},
+		{Seq: 14, Time: at(12), Type: bb.EventGameFinished, GameFinished: &bb.GameFinished{GameId: gameId, SizeGame: 64, InitTime: start, NumberAutoPilots: 1, WinnerId: "bot", WinnerName: "autopilot", Duration: 10 * time.Second, Reason: bb.FinishReasonCompleted}},
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	events := gameEvents()
+	data, err := Export(FORMAT_BINARY, "game-1", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gameId, got, err := ReadBinary(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gameId != "game-1" {
+		t.Errorf("game id %q", gameId)
+	}
+	compare(t, got, events)
+}
+
+func TestJSONLRoundTrip(t *testing.T) {
+	events := gameEvents()
+	data, err := Export(FORMAT_JSONL, "game-1", events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadJSONL(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compare(t, got, events)
+}
+
+func TestReadBinaryRejects(t *testing.T) {
+	data, err := Export(FORMAT_BINARY, "game-1", gameEvents())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "not a replay", data: []byte("{}")},
+		{name: "truncated", data: data[:len(data)-3]},
+		{name: "unknown record", data: append(append([]byte(nil), data...), 0xff, 1, 0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ReadBinary(bytes.NewReader(tt.data)); err == nil {
+				t.Error("read without error")
+			}
+		})
+	}
+}
+
+func compare(t *testing.T, got []*bb.Event, want []*bb.Event) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%d events, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("event %d\n got %+v\nwant %+v", want[i].Seq, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package replay
+
+import (
+	"battlebit/internal/bb"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const MaxSpeed = 1000.0
+
+// Gaps shorter than this are streamed back to back instead of sleeping.
+const minWait = time.Millisecond
+
+type Playback struct {
+	ReplayId string
+	GameId   string
+	events   []*bb.Event
+	mutex    sync.Mutex
+	position int
+	speed    float64
+	changed  chan struct{}
+}
+
+func NewPlayback(replayId string, gameId string, events []*bb.Event, speed float64) (*Playback, error) {
+	if len(events) == 0 {
+		return nil, fmt.Errorf("game has no events")
+	}
+	if err := validateSpeed(speed); err != nil {
+		return nil, err
+	}
+	return &Playback{
+		ReplayId: replayId,
+		GameId:   gameId,
+		events:   events,
+		speed:    speed,
+		changed:  make(chan struct{}, 1),
+	}, nil
+}
+
+// Run streams the events through emit until ctx is done. Reaching the end
+// calls finished and waits for a seek, so a finished replay can be rewound.
+func (p *Playback) Run(ctx context.Context, emit func(*bb.Event), finished func()) {
+	atEnd := false
+	for {
+		p.mutex.Lock()
+		if p.position >= len(p.events) {
+			p.mutex.Unlock()
+			if !atEnd {
+				atEnd = true
+				finished()
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-p.changed:
+				continue
+			}
+		}
+		atEnd = false
+		position := p.position
+		wait := p.waitLocked(position)
+		p.mutex.Unlock()
+
+		if wait >= minWait {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-p.changed:
+				timer.Stop()
+				continue
+			case <-timer.C:
+			}
+		} else {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+		}
+
+		p.mutex.Lock()
+		if p.position != position {
+			p.mutex.Unlock()
+			continue
+		}
+		p.position++
+		p.mutex.Unlock()
+		emit(p.events[position])
+	}
+}
+
+// SeekSeq moves the playback to the first event after seq and returns the
+// events already played, so the caller can rebuild the board at that point.
+func (p *Playback) SeekSeq(seq uint64) []*bb.Event {
+	i := sort.Search(len(p.events), func(i int) bool { return p.events[i].Seq > seq })
+	return p.seek(i)
+}
+
+func (p *Playback) SeekOffset(offset time.Duration) []*bb.Event {
+	at := p.events[0].Time.Add(offset)
+	i := sort.Search(len(p.events), func(i int) bool { return !p.events[i].Time.Before(at) })
+	return p.seek(i)
+}
+
+func (p *Playback) SetSpeed(speed float64) error {
+	if err := validateSpeed(speed); err != nil {
+		return err
+	}
+	p.mutex.Lock()
+	p.speed = speed
+	p.mutex.Unlock()
+	p.signal()
+	return nil
+}
+
+func (p *Playback) Position() (uint64, int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.position == 0 {
+		return 0, len(p.events)
+	}
+	return p.events[p.position-1].Seq, len(p.events)
+}
+
+func (p *Playback) seek(i int) []*bb.Event {
+	p.mutex.Lock()
+	p.position = i
+	p.mutex.Unlock()
+	p.signal()
+	return p.events[:i]
+}
+
+func (p *Playback) signal() {
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (p *Playback) waitLocked(position int) time.Duration {
+	if position == 0 {
+		return 0
+	}
+	gap := p.events[position].Time.Sub(p.events[position-1].Time)
+	return time.Duration(float64(gap) / p.speed)
+}
+
+func validateSpeed(speed float64) error {
+	if speed <= 0 || speed > MaxSpeed {
+		return fmt.Errorf("speed must be greater than 0 and at most %v", MaxSpeed)
+	}
+	return nil
+}
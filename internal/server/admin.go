@@ -0,0 +1,99 @@
+package server
+
+import (
+	"battlebit/internal/log"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+const readyTimeout = 2 * time.Second
+
+func (gs *GameServer) HealthEndpoint(w http.ResponseWriter, r *http.Request) {
+	writeJSON(r.Context(), w, http.StatusOK, &ProbeStatus{Status: "ok"})
+}
+
+func (gs *GameServer) ReadyEndpoint(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := gs.hub.Ready(ctx); err != nil {
+		log.GetLogger(ctx).Warn("Readiness check failed", "error", err.Error())
+		writeJSON(ctx, w, http.StatusServiceUnavailable, &ProbeStatus{Status: "unavailable", Error: err.Error()})
+		return
+	}
+	writeJSON(ctx, w, http.StatusOK, &ProbeStatus{Status: "ok"})
+}
+
+func (gs *GameServer) AdminEndpoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := log.GetLogger(ctx)
+	if !gs.isAdmin(r) {
+		log.Warn("Unauthorized admin request", "path", r.URL.Path)
+		writeJSON(ctx, w, http.StatusUnauthorized, &ProbeStatus{Status: "unauthorized"})
+		return
+	}
+	writeJSON(ctx, w, http.StatusOK, gs.adminStatus(ctx))
+}
+
+func (gs *GameServer) isAdmin(r *http.Request) bool {
+	if gs.adminToken == "" {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(gs.adminToken)) == 1
+}
+
+func (gs *GameServer) adminStatus(ctx context.Context) *AdminStatus {
+	sessions := gs.allSessions()
+	connections := make([]*SessionInfo, 0, len(sessions))
+	replays := make(map[string]int)
+	for _, sess := range sessions {
+		connections = append(connections, sess.info())
+		for gameId, n := range sess.replaysByGame() {
+			replays[gameId] += n
+		}
+	}
+	sort.Slice(connections, func(i, j int) bool { return connections[i].ConnectedAt.Before(connections[j].ConnectedAt) })
+
+	games := make([]*GameInfo, 0)
+	for _, g := range gs.hub.AllGames() {
+		autoPilot := g.AutoPilotState()
+		goroutines := replays[g.GameId]
+		if autoPilot.Running {
+			goroutines++
+		}
+		games = append(games, &GameInfo{
+			GameId:     g.GameId,
+			Goroutines: goroutines,
+			Replays:    replays[g.GameId],
+			AutoPilot:  autoPilot,
+			Metrics:    g.Metrics(ctx),
+		})
+	}
+	sort.Slice(games, func(i, j int) bool { return games[i].GameId < games[j].GameId })
+
+	return &AdminStatus{
+		Goroutines:  runtime.NumGoroutine(),
+		Hub:         gs.hub.Stats(),
+		Games:       games,
+		Connections: connections,
+	}
+}
+
+func writeJSON(ctx context.Context, w http.ResponseWriter, status int, v interface{}) {
+	log := log.GetLogger(ctx)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error("Failed to write JSON response", "error", err.Error())
+	}
+}
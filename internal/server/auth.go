@@ -0,0 +1,94 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/log"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var errAuthDisabled = errors.New("authentication is disabled")
+
+// requestIdentity verifies the token sent on the upgrade request, either as a
+// bearer Authorization header or a token query parameter for browsers.
+func (gs *GameServer) requestIdentity(r *http.Request) (*auth.Identity, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		token = r.URL.Query().Get("token")
+	}
+	if token == "" {
+		return nil, nil
+	}
+	return gs.verifyToken(token)
+}
+
+func (gs *GameServer) verifyToken(token string) (*auth.Identity, error) {
+	if gs.signer == nil {
+		return nil, errAuthDisabled
+	}
+	return gs.signer.Verify(token)
+}
+
+func (gs *GameServer) authRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_AUTHENTICATE:
+		a := new(Authenticate)
+		err := json.Unmarshal(req.Params, a)
+		if err != nil {
+			log.Error("Failed to unmarshal Authenticate", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		id, err := gs.verifyToken(a.Token)
+		if err != nil {
+			log.Warn("Authentication failed", "error", err.Error())
+			return responseError(req, 401, err)
+		}
+		sess.setIdentity(id)
+		log.Info("Session authenticated", "accountId", id.AccountId)
+		return responseResult(req, id)
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
+
+// TokenEndpoint lets an operator or a trusted account service holding the
+// admin token mint player tokens.
+func (gs *GameServer) TokenEndpoint(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := log.GetLogger(ctx)
+	if !gs.isAdmin(r) {
+		log.Warn("Unauthorized token request", "path", r.URL.Path)
+		writeJSON(ctx, w, http.StatusUnauthorized, &ProbeStatus{Status: "unauthorized"})
+		return
+	}
+	if gs.signer == nil {
+		writeJSON(ctx, w, http.StatusNotFound, &ProbeStatus{Status: "unavailable", Error: errAuthDisabled.Error()})
+		return
+	}
+	tr := new(TokenRequest)
+	if err := json.NewDecoder(r.Body).Decode(tr); err != nil {
+		writeJSON(ctx, w, http.StatusBadRequest, &ProbeStatus{Status: "invalid", Error: err.Error()})
+		return
+	}
+	role := auth.RolePlayer
+	if tr.Role != "" {
+		r, err := auth.ParseRole(tr.Role)
+		if err != nil {
+			writeJSON(ctx, w, http.StatusBadRequest, &ProbeStatus{Status: "invalid", Error: err.Error()})
+			return
+		}
+		role = r
+	}
+	token, id, err := gs.signer.Issue(tr.AccountId, tr.Name, role)
+	if err != nil {
+		writeJSON(ctx, w, http.StatusBadRequest, &ProbeStatus{Status: "invalid", Error: err.Error()})
+		return
+	}
+	log.Info("Token issued", "accountId", id.AccountId, "role", id.Role)
+	writeJSON(ctx, w, http.StatusOK, &TokenResponse{Token: token, Identity: id})
+}
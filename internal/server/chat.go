@@ -0,0 +1,192 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/bb"
+	"battlebit/internal/chat"
+	"battlebit/internal/hub"
+	"battlebit/internal/log"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SetChatFilter replaces the blocked words filter, to plug in a profanity
+// filter service.
+func (gs *GameServer) SetChatFilter(filter chat.Filter) {
+	gs.chatFilter = filter
+}
+
+func (gs *GameServer) chatRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_SEND_CHAT:
+		log.Info("Sending chat", "params", string(req.Params))
+		sc := new(chat.SendChat)
+		if err := json.Unmarshal(req.Params, sc); err != nil {
+			log.Error("Failed to unmarshal SendChat", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		if sc.Channel == "" {
+			sc.Channel = chat.ChannelGame
+		}
+		if sc.Channel != chat.ChannelGame && sc.Channel != chat.ChannelLobby {
+			return responseError(req, 400, fmt.Errorf("unknown channel %q, want game or lobby", sc.Channel))
+		}
+		sc.Text = strings.TrimSpace(sc.Text)
+		if sc.Text == "" {
+			return responseError(req, 400, fmt.Errorf("text is required"))
+		}
+		if n := utf8.RuneCountInString(sc.Text); n > gs.chatMaxLength {
+			return responseError(req, 400, fmt.Errorf("text is %d characters, the limit is %d", n, gs.chatMaxLength))
+		}
+		senderId, _ := gs.principal(ctx, sess)
+		if !gs.chatLimiter.Allow(senderId, time.Now()) {
+			log.Warn("Chat rate limited", "senderId", senderId)
+			return responseError(req, 429, fmt.Errorf("too many messages, slow down"))
+		}
+		text, filtered := gs.chatFilter(sc.Text)
+		msg := &chat.Message{SenderId: senderId, Text: text, Filtered: filtered}
+		if sc.Channel == chat.ChannelLobby {
+			return gs.sendLobbyChat(ctx, req, msg)
+		}
+		return gs.sendGameChat(ctx, req, sess, sc, msg)
+	case METHOD_GET_CHAT:
+		log.Info("Getting chat", "params", string(req.Params))
+		gc := new(chat.GetChat)
+		if err := json.Unmarshal(req.Params, gc); err != nil {
+			log.Error("Failed to unmarshal GetChat", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		if gc.Channel == chat.ChannelLobby {
+			if gs.lobby == nil {
+				return responseError(req, 404, fmt.Errorf("lobby chat is disabled"))
+			}
+			return responseResult(req, gs.lobby.History(gc.AfterSeq))
+		}
+		game, denied := gs.readableGame(ctx, req, sess, gc.GameId, gc.InviteCode, gc.Password)
+		if denied != nil {
+			return denied
+		}
+		if game == nil {
+			return responseError(req, 404, fmt.Errorf("game not found"))
+		}
+		history := game.ChatHistory(gc.AfterSeq)
+		if !gs.inGame(ctx, sess, game) {
+			history = game.DelayedChat(gc.AfterSeq, time.Now())
+		}
+		messages := make([]*chat.Message, 0)
+		for _, event := range history {
+			messages = append(messages, gameMessage(event))
+		}
+		return responseResult(req, messages)
+	case METHOD_MUTE_PLAYER, METHOD_UNMUTE_PLAYER:
+		log.Info("Muting player", "method", req.Method, "params", string(req.Params))
+		mc := new(chat.MuteChat)
+		if err := json.Unmarshal(req.Params, mc); err != nil {
+			log.Error("Failed to unmarshal MuteChat", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		muted := req.Method == METHOD_MUTE_PLAYER
+		if mc.GameId == "" {
+			if gs.lobby == nil {
+				return responseError(req, 404, fmt.Errorf("lobby chat is disabled"))
+			}
+			if _, role := gs.principal(ctx, sess); role != auth.RoleAdmin {
+				return responseError(req, 403, fmt.Errorf("%w: only an admin can mute in the lobby", errForbidden))
+			}
+			if mc.SenderId == "" {
+				return responseError(req, 400, fmt.Errorf("senderId is required"))
+			}
+			gs.lobby.Mute(mc.SenderId, muted)
+			return responseResult(req, mc)
+		}
+		game, resp := gs.managedGame(ctx, req, sess, mc.GameId)
+		if resp != nil {
+			return resp
+		}
+		pm, err := game.MutePlayer(ctx, mc.PlayerId, muted)
+		if err != nil {
+			log.Error("Failed to mute player", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		return responseResult(req, pm)
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
+
+// sendLobbyChat only lets accounts post, since lobby mutes are by sender and
+// an anonymous sender would shed them by reconnecting.
+func (gs *GameServer) sendLobbyChat(ctx context.Context, req *JSONRPCRequest, msg *chat.Message) *JSONRPCResponse {
+	if gs.lobby == nil {
+		return responseError(req, 404, fmt.Errorf("lobby chat is disabled"))
+	}
+	id, ok := auth.GetIdentity(ctx)
+	if !ok {
+		return responseError(req, 401, fmt.Errorf("authentication required to chat in the lobby"))
+	}
+	msg.SenderName = id.Name
+	if err := gs.lobby.Post(msg); err != nil {
+		return responseError(req, 403, err)
+	}
+	for _, s := range gs.allSessions() {
+		sendNotification(ctx, s, NOTIFICATION_CHAT_MESSAGE, msg)
+	}
+	return responseResult(req, msg)
+}
+
+// sendGameChat posts as one of the caller's players, or as a host when the
+// caller manages the game and sends no player id.
+func (gs *GameServer) sendGameChat(ctx context.Context, req *JSONRPCRequest, sess *session, sc *chat.SendChat, msg *chat.Message) *JSONRPCResponse {
+	game, err := gs.hub.GetGame(ctx, hub.GameId{ID: sc.GameId})
+	if err != nil {
+		return responseError(req, 404, err)
+	}
+	cm := &bb.ChatMessage{PlayerId: sc.PlayerId, SenderId: msg.SenderId, Text: msg.Text, Filtered: msg.Filtered}
+	if sc.PlayerId == "" {
+		if !gs.canManage(ctx, sess, game) {
+			return responseError(req, 403, fmt.Errorf("%w: playerId is required to chat in a game you do not manage", errForbidden))
+		}
+		cm.SenderName = "host"
+		if id, ok := auth.GetIdentity(ctx); ok {
+			cm.SenderName = id.Name
+		}
+	} else if !gs.canActAs(ctx, sess, game, sc.PlayerId) {
+		return responseError(req, 403, fmt.Errorf("%w: player belongs to someone else", errForbidden))
+	}
+	event, err := game.SendChat(ctx, cm)
+	if errors.Is(err, bb.ErrPlayerMuted) {
+		return responseError(req, 403, err)
+	}
+	if err != nil {
+		return responseError(req, 404, err)
+	}
+	sess.joinChannel(game.GameId)
+	msg = gameMessage(event)
+	for _, s := range gs.allSessions() {
+		if s.inChannel(game.GameId) {
+			sendNotification(ctx, s, NOTIFICATION_CHAT_MESSAGE, msg)
+		}
+	}
+	return responseResult(req, msg)
+}
+
+func gameMessage(event *bb.Event) *chat.Message {
+	return &chat.Message{
+		Channel:    chat.ChannelGame,
+		GameId:     event.ChatMessage.GameId,
+		Seq:        event.Seq,
+		Time:       event.Time,
+		PlayerId:   event.ChatMessage.PlayerId,
+		SenderId:   event.ChatMessage.SenderId,
+		SenderName: event.ChatMessage.SenderName,
+		Text:       event.ChatMessage.Text,
+		Filtered:   event.ChatMessage.Filtered,
+	}
+}
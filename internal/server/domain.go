@@ -1,6 +1,12 @@
 package server
 
-import "encoding/json"
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/bb"
+	"battlebit/internal/hub"
+	"encoding/json"
+	"time"
+)
 
 type JSONRPCRequest struct {
 	JSONRPC string          `json:"jsonrpc"`
@@ -21,3 +27,81 @@ type JSONRPCError struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"` // Data can be anything, so use interface{}
 }
+
+type JSONRPCNotification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type SessionInfo struct {
+	SessionId   string    `json:"sessionId"`
+	RemoteAddr  string    `json:"remoteAddr"`
+	ConnectedAt time.Time `json:"connectedAt"`
+	Requests    uint64    `json:"requests"`
+	Replays     []string  `json:"replays"`
+	AccountId   string    `json:"accountId,omitempty"`
+}
+
+type GameInfo struct {
+	GameId     string             `json:"gameId"`
+	Goroutines int                `json:"goroutines"`
+	Replays    int                `json:"replays"`
+	AutoPilot  *bb.AutoPilotState `json:"autoPilot"`
+	Metrics    *bb.GameMetrics    `json:"metrics"`
+}
+
+type AdminStatus struct {
+	Goroutines  int            `json:"goroutines"`
+	Hub         *hub.Stats     `json:"hub"`
+	Games       []*GameInfo    `json:"games"`
+	Connections []*SessionInfo `json:"connections"`
+}
+
+type ProbeStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type GameCreated struct {
+	*bb.GameStarted
+	Visibility string `json:"visibility"`
+	InviteCode string `json:"inviteCode"`
+}
+
+type SpectateStarted struct {
+	GameId  string       `json:"gameId"`
+	DelayMs int64        `json:"delayMs"`
+	Seq     uint64       `json:"seq"`
+	State   *bb.GameView `json:"state,omitempty"`
+}
+
+type SpectatorEvent struct {
+	GameId string    `json:"gameId"`
+	Event  *bb.Event `json:"event"`
+}
+
+type SpectateFinished struct {
+	GameId string `json:"gameId"`
+	Seq    uint64 `json:"seq"`
+}
+
+type Authenticate struct {
+	Token string `json:"token"`
+}
+
+type TokenRequest struct {
+	AccountId string `json:"accountId"`
+	Name      string `json:"name"`
+	Role      string `json:"role"`
+}
+
+type TokenResponse struct {
+	Token    string         `json:"token"`
+	Identity *auth.Identity `json:"identity"`
+}
+
+type ShutdownNotice struct {
+	Message string `json:"message"`
+	Games   string `json:"games"`
+}
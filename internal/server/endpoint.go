@@ -1,41 +1,86 @@
 package server
 
 import (
+	"battlebit/internal/auth"
 	"battlebit/internal/bb"
+	"battlebit/internal/chat"
+	"battlebit/internal/config"
 	"battlebit/internal/hub"
 	"battlebit/internal/log"
+	"battlebit/internal/metrics"
 	"battlebit/internal/player"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 type GameServer struct {
-	hub      *hub.Hub
-	upgrader websocket.Upgrader
+	hub           *hub.Hub
+	upgrader      websocket.Upgrader
+	adminToken    string
+	signer        *auth.Signer
+	requireAuth   bool
+	anonymousRole auth.Role
+	sessions      map[string]*session
+	sessionsMutex sync.Mutex
+	lobby         *chat.Lobby
+	chatFilter    chat.Filter
+	chatLimiter   *chat.Limiter
+	chatMaxLength int
+	web           http.Handler
 }
 
-func NewGameServer(h *hub.Hub) *GameServer {
+func NewGameServer(h *hub.Hub, cfg config.Server) *GameServer {
+	var signer *auth.Signer
+	if cfg.AuthSecret != "" {
+		signer = auth.NewSigner(cfg.AuthSecret, cfg.TokenTTL)
+	}
+	var lobby *chat.Lobby
+	if cfg.ChatLobby {
+		lobby = chat.NewLobby()
+	}
 	return &GameServer{
-		hub: h,
+		hub:           h,
+		adminToken:    cfg.AdminToken,
+		signer:        signer,
+		requireAuth:   cfg.RequireAuth,
+		anonymousRole: auth.Role(cfg.AnonymousRole),
+		sessions:      make(map[string]*session),
+		lobby:         lobby,
+		chatFilter:    chat.NewWordFilter(cfg.ChatBlocked),
+		chatLimiter:   chat.NewLimiter(cfg.ChatRateLimit, cfg.ChatRateWindow),
+		chatMaxLength: cfg.ChatMaxLength,
+		web:           webHandler(),
 		upgrader: websocket.Upgrader{
-			ReadBufferSize:  1024,
-			WriteBufferSize: 1024,
-			CheckOrigin:     func(r *http.Request) bool { return true },
+			ReadBufferSize:  cfg.ReadBufferSize,
+			WriteBufferSize: cfg.WriteBufferSize,
+			CheckOrigin:     newOriginChecker(cfg.AllowedOrigins),
 		},
 	}
 }
 
+// HomePage serves the embedded web client.
 func (gs *GameServer) HomePage(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "Home HTTP")
+	gs.web.ServeHTTP(w, r)
 }
+
 func (gs *GameServer) WsEndpoint(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := log.GetLogger(ctx)
+	identity, err := gs.requestIdentity(r)
+	if err != nil {
+		log.Warn("Rejected websocket token", "error", err.Error())
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
 	ws, err := gs.upgrader.Upgrade(w, r, nil)
 
 	if err != nil {
@@ -43,15 +88,23 @@ func (gs *GameServer) WsEndpoint(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	log.Info("Client connected", "remoteAddr", ws.RemoteAddr())
+	metrics.ActiveConnections.Inc()
+	defer metrics.ActiveConnections.Dec()
 
+	sess := newSession(ws)
+	sess.setIdentity(identity)
+	gs.addSession(sess)
+	defer gs.removeSession(sess)
+	go sess.writeLoop(ctx)
 	mssgBytes := []byte("Hi Client!")
-	err = ws.WriteMessage(1, mssgBytes)
-	if err != nil {
-		log.Error("Failed to write message to client", "error", err.Error())
-	}
+	sess.write(ctx, mssgBytes)
 	// listen indefinitely for new messages coming
 	// through on our WebSocket connection
-	gs.messageProcessor(ctx, ws)
+	gs.messageProcessor(ctx, sess)
+	gs.hub.DequeueOwner(sess.id)
+	gs.hub.Unwatch(sess.id)
+	gs.chatLimiter.Forget("anonymous:" + sess.id)
+	sess.close()
 	slog.Info("Client disconnected", slog.String("remoteAddr", ws.RemoteAddr().String()))
 	err = ws.Close()
 	if err != nil {
@@ -59,48 +112,115 @@ func (gs *GameServer) WsEndpoint(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (gs *GameServer) messageProcessor(ctx context.Context, conn *websocket.Conn) {
+func (gs *GameServer) addSession(sess *session) {
+	gs.sessionsMutex.Lock()
+	defer gs.sessionsMutex.Unlock()
+	gs.sessions[sess.id] = sess
+}
+
+func (gs *GameServer) removeSession(sess *session) {
+	gs.sessionsMutex.Lock()
+	defer gs.sessionsMutex.Unlock()
+	delete(gs.sessions, sess.id)
+}
+
+func (gs *GameServer) allSessions() []*session {
+	gs.sessionsMutex.Lock()
+	defer gs.sessionsMutex.Unlock()
+	sessions := make([]*session, 0, len(gs.sessions))
+	for _, sess := range gs.sessions {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
+
+// Shutdown tells every connected client the server is going away and closes
+// their connections, so no moves arrive while the hub drains.
+func (gs *GameServer) Shutdown(ctx context.Context) {
+	log := log.GetLogger(ctx)
+
+	notice := &ShutdownNotice{Message: hub.ErrShuttingDown.Error(), Games: "aborted"}
+	if gs.hub.Persistent() {
+		notice.Games = "persisted"
+	}
+	sessions := gs.allSessions()
+	for _, sess := range sessions {
+		sendNotification(ctx, sess, NOTIFICATION_SERVER_SHUTDOWN, notice)
+		sess.closeConn(notice.Message)
+	}
+	log.Info("Clients notified of shutdown", "connections", len(sessions))
+}
+
+func (gs *GameServer) messageProcessor(ctx context.Context, sess *session) {
 	log := log.GetLogger(ctx)
 	counterReceived := 0
 	for {
 		// read in a message
-		messageType, p, err := conn.ReadMessage()
+		messageType, p, err := sess.conn.ReadMessage()
 		if err != nil {
+			// errors returned by ReadMessage are permanent, the connection is gone
 			log.Error("Failed to read message", "error", err.Error(), "counterReceived", counterReceived, "messageType", messageType)
-			continue
+			return
 		}
 		// print out that message for clarity
 		msgTxt := string(p)
 		log.Info("Received message", "message", msgTxt)
 		counterReceived++
+		sess.requests.Add(1)
 
 		jsonRPCRequest := new(JSONRPCRequest)
 		err = json.Unmarshal(p, jsonRPCRequest)
 		if err != nil {
 			log.Error("Failed to unmarshal JSONRPCRequest", "error", err.Error(), "counterReceived", counterReceived, "messageType", messageType)
 			resp := responseError(jsonRPCRequest, 400, err)
-			sendMsg(ctx, conn, resp)
+			metrics.RPCErrors.WithLabelValues("unknown", "400").Inc()
+			sendMsg(ctx, sess, resp)
 			continue
 		} else {
-			resp := gs.processRequest(ctx, jsonRPCRequest, conn)
-			sendMsg(ctx, conn, resp)
+			start := time.Now()
+			resp := gs.processRequest(sess.withIdentity(ctx), jsonRPCRequest, sess)
+			observeRequest(jsonRPCRequest, resp, time.Since(start))
+			sendMsg(ctx, sess, resp)
 		}
 	}
 }
-func (gs *GameServer) processRequest(ctx context.Context, req *JSONRPCRequest, conn *websocket.Conn) *JSONRPCResponse {
+func (gs *GameServer) processRequest(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	if err := gs.authorize(ctx, req, sess); err != nil {
+		log.GetLogger(ctx).Warn("Request denied", "method", req.Method, "error", err.Error())
+		return responseError(req, 403, err)
+	}
 	switch req.Method {
+	case METHOD_AUTHENTICATE:
+		return gs.authRouter(ctx, req, sess)
 	case METHOD_CREATE_GAME, METHOD_LIST_GAMES, METHOD_GET_GAME, METHOD_REMOVE_GAME:
-		return gs.hubRouter(ctx, req)
+		return gs.hubRouter(ctx, req, sess)
 	case METHOD_JOIN_GAME, METHOD_LEAVE_GAME, METHOD_PLAYER_MOVE:
-		return gs.gameRouter(ctx, req, conn)
+		return gs.gameRouter(ctx, req, sess)
 	case METHOD_GAME_METRICS:
-		return gs.metricRouter(ctx, req)
+		return gs.metricRouter(ctx, req, sess)
+	case METHOD_GET_LEADERBOARD, METHOD_GET_PLAYER_PROFILE:
+		return gs.ratingRouter(ctx, req)
+	case METHOD_LIST_HISTORY:
+		return gs.historyRouter(ctx, req, sess)
+	case METHOD_CREATE_TOURNAMENT, METHOD_LIST_TOURNAMENTS, METHOD_GET_TOURNAMENT, METHOD_WATCH_TOURNAMENT,
+		METHOD_REGISTER_TOURNAMENT, METHOD_WITHDRAW_TOURNAMENT, METHOD_START_TOURNAMENT:
+		return gs.tournamentRouter(ctx, req, sess)
+	case METHOD_SPECTATE_GAME, METHOD_STOP_SPECTATING:
+		return gs.spectatorRouter(ctx, req, sess)
+	case METHOD_SEND_CHAT, METHOD_GET_CHAT, METHOD_MUTE_PLAYER, METHOD_UNMUTE_PLAYER:
+		return gs.chatRouter(ctx, req, sess)
+	case METHOD_ENQUEUE, METHOD_DEQUEUE:
+		return gs.queueRouter(ctx, req, sess)
+	case METHOD_KICK_PLAYER, METHOD_BAN_PLAYER, METHOD_PAUSE_GAME, METHOD_RESUME_GAME:
+		return gs.moderationRouter(ctx, req, sess)
+	case METHOD_EXPORT_REPLAY, METHOD_REPLAY, METHOD_REPLAY_SEEK, METHOD_REPLAY_SPEED, METHOD_REPLAY_STOP:
+		return gs.replayRouter(ctx, req, sess)
 	default:
 		return responseResult(req, map[string]string{"message": "method not found"})
 	}
 }
 
-func (gs *GameServer) hubRouter(ctx context.Context, req *JSONRPCRequest) *JSONRPCResponse {
+func (gs *GameServer) hubRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
 	log := log.GetLogger(ctx)
 	switch req.Method {
 	case METHOD_CREATE_GAME:
@@ -111,26 +231,46 @@ func (gs *GameServer) hubRouter(ctx context.Context, req *JSONRPCRequest) *JSONR
 			log.Error("Failed to unmarshal CrateNewGame", "error", err.Error())
 			return responseError(req, 400, err)
 		}
-		game := gs.hub.CreateNewGame(ctx, *ng)
+		ng.CreatorId, _ = gs.principal(ctx, sess)
+		game, err := gs.hub.CreateNewGame(ctx, *ng)
+		if errors.Is(err, hub.ErrShuttingDown) || errors.Is(err, hub.ErrHubFull) {
+			log.Error("Failed to create game", "error", err.Error())
+			return responseError(req, 503, err)
+		}
+		if err != nil {
+			log.Error("Failed to create game", "error", err.Error())
+			return responseError(req, 400, err)
+		}
 		started := game.StartGame(ctx)
-		return responseResult(req, started)
+		sess.joinChannel(game.GameId)
+		return responseResult(req, &GameCreated{GameStarted: started, Visibility: game.Visibility, InviteCode: game.InviteCode()})
 	case METHOD_LIST_GAMES:
 		log.Info("Listing games")
-		return responseResult(req, gs.hub.ListGames(ctx))
+		return responseResult(req, gs.listedGames(ctx, sess))
 	case METHOD_GET_GAME:
 		log.Info("Getting game", "params", string(req.Params))
-		gId := new(hub.GameId)
-		err := json.Unmarshal(req.Params, gId)
+		gg := new(hub.GetGame)
+		err := json.Unmarshal(req.Params, gg)
 		if err != nil {
 			log.Error("Failed to unmarshal GetGame", "error", err.Error())
 			return responseError(req, 400, err)
 		}
-		g, err := gs.hub.GetGame(ctx, *gId)
+		g, denied := gs.readableGame(ctx, req, sess, gg.ID, gg.InviteCode, gg.Password)
+		if denied != nil {
+			return denied
+		}
+		if g == nil {
+			return responseError(req, 404, fmt.Errorf("game not found"))
+		}
+		if gs.inGame(ctx, sess, g) {
+			return responseResult(req, g.View())
+		}
+		view, err := g.DelayedView(ctx, time.Now())
 		if err != nil {
-			log.Error("Failed to get game", "error", err.Error())
-			return responseError(req, 404, err)
+			log.Error("Failed to rebuild delayed game", "gameId", g.GameId, "error", err.Error())
+			return responseError(req, 500, err)
 		}
-		return responseResult(req, g)
+		return responseResult(req, view)
 	case METHOD_REMOVE_GAME:
 		log.Info("Removing game", "params", string(req.Params))
 		gId := new(hub.GameId)
@@ -139,6 +279,15 @@ func (gs *GameServer) hubRouter(ctx context.Context, req *JSONRPCRequest) *JSONR
 			log.Error("Failed to unmarshal RemoveGame", "error", err.Error())
 			return responseError(req, 400, err)
 		}
+		game, err := gs.hub.GetGame(ctx, *gId)
+		if err != nil {
+			log.Error("Failed to get game", "error", err.Error())
+			return responseError(req, 404, err)
+		}
+		if !gs.canManage(ctx, sess, game) {
+			log.Warn("Remove game denied", "gameId", gId.ID)
+			return responseError(req, 403, fmt.Errorf("%w: only the creator or an admin can remove the game", errForbidden))
+		}
 		gs.hub.RemoveGame(ctx, *gId)
 		return responseResult(req, map[string]string{"message": "game removed"})
 	default:
@@ -147,7 +296,7 @@ func (gs *GameServer) hubRouter(ctx context.Context, req *JSONRPCRequest) *JSONR
 	}
 }
 
-func (gs *GameServer) gameRouter(ctx context.Context, req *JSONRPCRequest, conn *websocket.Conn) *JSONRPCResponse {
+func (gs *GameServer) gameRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
 	log := log.GetLogger(ctx)
 	switch req.Method {
 	case METHOD_JOIN_GAME:
@@ -158,17 +307,30 @@ func (gs *GameServer) gameRouter(ctx context.Context, req *JSONRPCRequest, conn
 			log.Error("Failed to unmarshal PlayerJoin", "error", err.Error())
 			return responseError(req, 400, err)
 		}
-		game, err := gs.hub.GetGame(ctx, hub.GameId{ID: pj.GameId})
-		if err != nil {
-			log.Error("Failed to get game", "error", err.Error())
-			return responseError(req, 404, err)
+		identity, authenticated := auth.GetIdentity(ctx)
+		if !authenticated && gs.requireAuth {
+			log.Warn("Unauthenticated join rejected", "gameId", pj.GameId)
+			return responseError(req, 401, fmt.Errorf("authentication required"))
+		}
+		game, denied := gs.readableGame(ctx, req, sess, pj.GameId, pj.InviteCode, pj.Password)
+		if denied != nil {
+			return denied
+		}
+		if game == nil {
+			return responseError(req, 404, fmt.Errorf("game not found"))
+		}
+		player := player.NewPlayer(pj.PlayerName, sess.id)
+		if authenticated {
+			// the token decides the name so nobody can join as someone else
+			player.PlayerName = identity.Name
+			player.AccountId = identity.AccountId
 		}
-		player := player.NewPlayer(pj.PlayerName, conn.RemoteAddr().String())
 		pa, err := game.AddPlayer(ctx, player)
 		if err != nil {
 			log.Error("Failed to add player", "error", err.Error())
 			return responseError(req, 400, err)
 		}
+		sess.joinChannel(game.GameId)
 		return responseResult(req, pa)
 	case METHOD_LEAVE_GAME:
 		log.Info("Leaving game", "params", string(req.Params))
@@ -183,6 +345,9 @@ func (gs *GameServer) gameRouter(ctx context.Context, req *JSONRPCRequest, conn
 			log.Error("Failed to get game", "error", err.Error())
 			return responseError(req, 404, err)
 		}
+		if !gs.canActAs(ctx, sess, game, pj.PlayerId) {
+			return responseError(req, 403, fmt.Errorf("%w: player belongs to someone else", errForbidden))
+		}
 		return responseResult(req, game.RemovePlayer(ctx, pj.PlayerId))
 	case METHOD_PLAYER_MOVE:
 		log.Info("Player move", "params", string(req.Params))
@@ -197,6 +362,12 @@ func (gs *GameServer) gameRouter(ctx context.Context, req *JSONRPCRequest, conn
 			log.Error("Failed to get game", "error", err.Error())
 			return responseError(req, 404, err)
 		}
+		if !gs.canActAs(ctx, sess, game, pm.PlayerId) {
+			return responseError(req, 403, fmt.Errorf("%w: player belongs to someone else", errForbidden))
+		}
+		if game.IsPaused() {
+			return responseError(req, 409, fmt.Errorf("game is paused"))
+		}
 		return responseResult(req, game.PlayerMove(ctx, pm.PlayerId, pm.Index))
 	default:
 		log.Info("Method not found", "method", req.Method)
@@ -204,25 +375,47 @@ func (gs *GameServer) gameRouter(ctx context.Context, req *JSONRPCRequest, conn
 	}
 }
 
-func (gs *GameServer) metricRouter(ctx context.Context, req *JSONRPCRequest) *JSONRPCResponse {
+func (gs *GameServer) metricRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
 	log := log.GetLogger(ctx)
 	switch req.Method {
 	case METHOD_GAME_METRICS:
 		log.Info("Game metrics", "params", string(req.Params))
-		ga := new(hub.GameId)
-		err := json.Unmarshal(req.Params, ga)
+		gg := new(hub.GetGame)
+		err := json.Unmarshal(req.Params, gg)
 		if err != nil {
 			log.Error("Failed to unmarshal GameMetrics", "error", err.Error())
 			return responseError(req, 400, err)
 		}
-		game, err := gs.hub.GetGame(ctx, hub.GameId{ID: ga.ID})
-		if err != nil {
-			log.Error("Failed to get game", "error", err.Error())
-			return responseError(req, 404, err)
+		game, denied := gs.readableGame(ctx, req, sess, gg.ID, gg.InviteCode, gg.Password)
+		if denied != nil {
+			return denied
+		}
+		if game == nil {
+			return responseError(req, 404, fmt.Errorf("game not found"))
 		}
-		return responseResult(req, game.Metrics(ctx))
+		gm := game.Metrics(ctx)
+		if !gs.inGame(ctx, sess, game) {
+			gm, err = game.DelayedMetrics(ctx, time.Now())
+			if err != nil {
+				log.Error("Failed to rebuild delayed metrics", "gameId", game.GameId, "error", err.Error())
+				return responseError(req, 500, err)
+			}
+		}
+		gm.ServerHeapBytes = metrics.HeapBytes()
+		return responseResult(req, gm)
 	default:
 		log.Info("Method not found", "method", req.Method)
 		return responseResult(req, map[string]string{"message": "method not found"})
 	}
 }
+
+func observeRequest(req *JSONRPCRequest, resp *JSONRPCResponse, elapsed time.Duration) {
+	method := req.Method
+	if !knownMethods[method] {
+		method = "unknown"
+	}
+	metrics.RPCDuration.WithLabelValues(method).Observe(elapsed.Seconds())
+	if resp.Error != nil {
+		metrics.RPCErrors.WithLabelValues(method, strconv.Itoa(resp.Error.Code)).Inc()
+	}
+}
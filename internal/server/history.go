@@ -0,0 +1,46 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/bb"
+	"battlebit/internal/history"
+	"battlebit/internal/log"
+	"context"
+	"encoding/json"
+)
+
+func (gs *GameServer) historyRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_LIST_HISTORY:
+		log.Info("Listing history", "params", string(req.Params))
+		lh := new(history.ListHistory)
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, lh); err != nil {
+				log.Error("Failed to unmarshal ListHistory", "error", err.Error())
+				return responseError(req, 400, err)
+			}
+		}
+		return responseResult(req, gs.hub.ListHistory(*lh, gs.historyVisible(ctx, sess)))
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
+
+// historyVisible shows private games only to admins, their creator and the
+// accounts that played them.
+func (gs *GameServer) historyVisible(ctx context.Context, sess *session) func(*history.Entry) bool {
+	owner, role := gs.principal(ctx, sess)
+	return func(e *history.Entry) bool {
+		if e.Config.Visibility == bb.VisibilityPublic || role == auth.RoleAdmin || e.CreatorId == owner {
+			return true
+		}
+		for _, p := range e.Players {
+			if p.AccountId != "" && p.AccountId == owner {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -1,5 +1,7 @@
 package server
 
+const METHOD_AUTHENTICATE = "authenticate"
+
 const METHOD_CREATE_GAME = "create_game"
 const METHOD_LIST_GAMES = "list_game"
 const METHOD_GET_GAME = "get_game"
@@ -9,3 +11,92 @@ const METHOD_JOIN_GAME = "join_game"
 const METHOD_LEAVE_GAME = "leave_game"
 const METHOD_PLAYER_MOVE = "player_move"
 const METHOD_GAME_METRICS = "game_metrics"
+
+const METHOD_ENQUEUE = "enqueue"
+const METHOD_DEQUEUE = "dequeue"
+
+const METHOD_GET_LEADERBOARD = "get_leaderboard"
+const METHOD_GET_PLAYER_PROFILE = "get_player_profile"
+
+const METHOD_LIST_HISTORY = "list_history"
+
+const METHOD_SPECTATE_GAME = "spectate_game"
+const METHOD_STOP_SPECTATING = "stop_spectating"
+
+const METHOD_SEND_CHAT = "send_chat"
+const METHOD_GET_CHAT = "get_chat"
+const METHOD_MUTE_PLAYER = "mute_player"
+const METHOD_UNMUTE_PLAYER = "unmute_player"
+
+const METHOD_CREATE_TOURNAMENT = "create_tournament"
+const METHOD_LIST_TOURNAMENTS = "list_tournaments"
+const METHOD_GET_TOURNAMENT = "get_tournament"
+const METHOD_WATCH_TOURNAMENT = "watch_tournament"
+const METHOD_REGISTER_TOURNAMENT = "register_tournament"
+const METHOD_WITHDRAW_TOURNAMENT = "withdraw_tournament"
+const METHOD_START_TOURNAMENT = "start_tournament"
+
+const METHOD_KICK_PLAYER = "kick_player"
+const METHOD_BAN_PLAYER = "ban_player"
+const METHOD_PAUSE_GAME = "pause_game"
+const METHOD_RESUME_GAME = "resume_game"
+
+const METHOD_EXPORT_REPLAY = "export_replay"
+const METHOD_REPLAY = "replay"
+const METHOD_REPLAY_SEEK = "replay_seek"
+const METHOD_REPLAY_SPEED = "replay_speed"
+const METHOD_REPLAY_STOP = "replay_stop"
+
+const NOTIFICATION_REPLAY_EVENT = "replay_event"
+const NOTIFICATION_REPLAY_SEEKED = "replay_seeked"
+const NOTIFICATION_REPLAY_FINISHED = "replay_finished"
+
+const NOTIFICATION_MATCH_FOUND = "match_found"
+
+const NOTIFICATION_TOURNAMENT_EVENT = "tournament_event"
+
+const NOTIFICATION_CHAT_MESSAGE = "chat_message"
+
+const NOTIFICATION_SPECTATOR_EVENT = "spectator_event"
+const NOTIFICATION_SPECTATE_FINISHED = "spectate_finished"
+
+const NOTIFICATION_SERVER_SHUTDOWN = "server_shutdown"
+
+var knownMethods = map[string]bool{
+	METHOD_AUTHENTICATE:        true,
+	METHOD_CREATE_GAME:         true,
+	METHOD_LIST_GAMES:          true,
+	METHOD_GET_GAME:            true,
+	METHOD_REMOVE_GAME:         true,
+	METHOD_JOIN_GAME:           true,
+	METHOD_LEAVE_GAME:          true,
+	METHOD_PLAYER_MOVE:         true,
+	METHOD_GAME_METRICS:        true,
+	METHOD_ENQUEUE:             true,
+	METHOD_DEQUEUE:             true,
+	METHOD_GET_LEADERBOARD:     true,
+	METHOD_GET_PLAYER_PROFILE:  true,
+	METHOD_LIST_HISTORY:        true,
+	METHOD_SPECTATE_GAME:       true,
+	METHOD_STOP_SPECTATING:     true,
+	METHOD_SEND_CHAT:           true,
+	METHOD_GET_CHAT:            true,
+	METHOD_MUTE_PLAYER:         true,
+	METHOD_UNMUTE_PLAYER:       true,
+	METHOD_CREATE_TOURNAMENT:   true,
+	METHOD_LIST_TOURNAMENTS:    true,
+	METHOD_GET_TOURNAMENT:      true,
+	METHOD_WATCH_TOURNAMENT:    true,
+	METHOD_REGISTER_TOURNAMENT: true,
+	METHOD_WITHDRAW_TOURNAMENT: true,
+	METHOD_START_TOURNAMENT:    true,
+	METHOD_KICK_PLAYER:         true,
+	METHOD_BAN_PLAYER:          true,
+	METHOD_PAUSE_GAME:          true,
+	METHOD_RESUME_GAME:         true,
+	METHOD_EXPORT_REPLAY:       true,
+	METHOD_REPLAY:              true,
+	METHOD_REPLAY_SEEK:         true,
+	METHOD_REPLAY_SPEED:        true,
+	METHOD_REPLAY_STOP:         true,
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/hub"
+	"battlebit/internal/log"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+func (gs *GameServer) moderationRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_KICK_PLAYER, METHOD_BAN_PLAYER:
+		log.Info("Kicking player", "method", req.Method, "params", string(req.Params))
+		pk := new(bb.PlayerKick)
+		err := json.Unmarshal(req.Params, pk)
+		if err != nil {
+			log.Error("Failed to unmarshal PlayerKick", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		game, resp := gs.managedGame(ctx, req, sess, pk.GameId)
+		if resp != nil {
+			return resp
+		}
+		kicked, err := game.KickPlayer(ctx, pk.PlayerId, pk.Reason, req.Method == METHOD_BAN_PLAYER)
+		if err != nil {
+			log.Error("Failed to kick player", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		return responseResult(req, kicked)
+	case METHOD_PAUSE_GAME:
+		log.Info("Pausing game", "params", string(req.Params))
+		gId := new(hub.GameId)
+		err := json.Unmarshal(req.Params, gId)
+		if err != nil {
+			log.Error("Failed to unmarshal PauseGame", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		game, resp := gs.managedGame(ctx, req, sess, gId.ID)
+		if resp != nil {
+			return resp
+		}
+		paused, err := game.PauseGame(ctx)
+		if err != nil {
+			log.Error("Failed to pause game", "error", err.Error())
+			return responseError(req, 409, err)
+		}
+		return responseResult(req, paused)
+	case METHOD_RESUME_GAME:
+		log.Info("Resuming game", "params", string(req.Params))
+		gId := new(hub.GameId)
+		err := json.Unmarshal(req.Params, gId)
+		if err != nil {
+			log.Error("Failed to unmarshal ResumeGame", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		game, resp := gs.managedGame(ctx, req, sess, gId.ID)
+		if resp != nil {
+			return resp
+		}
+		resumed, err := game.UnpauseGame(ctx)
+		if err != nil {
+			log.Error("Failed to resume game", "error", err.Error())
+			return responseError(req, 409, err)
+		}
+		return responseResult(req, resumed)
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
+
+// managedGame returns the game when the caller can manage it, or the error
+// response to send otherwise.
+func (gs *GameServer) managedGame(ctx context.Context, req *JSONRPCRequest, sess *session, gameId string) (*bb.Game, *JSONRPCResponse) {
+	log := log.GetLogger(ctx)
+	game, err := gs.hub.GetGame(ctx, hub.GameId{ID: gameId})
+	if err != nil {
+		log.Error("Failed to get game", "error", err.Error())
+		return nil, responseError(req, 404, err)
+	}
+	if !gs.canManage(ctx, sess, game) {
+		log.Warn("Moderation denied", "method", req.Method, "gameId", gameId)
+		return nil, responseError(req, 403, fmt.Errorf("%w: only the creator or an admin can moderate the game", errForbidden))
+	}
+	return game, nil
+}
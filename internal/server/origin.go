@@ -0,0 +1,78 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type originPattern struct {
+	scheme string
+	host   string
+	suffix bool
+}
+
+// newOriginChecker accepts requests without an Origin header, which browsers
+// always send, and otherwise matches it against the allow-list. An empty list
+// only accepts the same origin and "*" accepts any.
+func newOriginChecker(allowed []string) func(r *http.Request) bool {
+	patterns := make([]originPattern, 0, len(allowed))
+	for _, origin := range allowed {
+		if origin == "*" {
+			return func(r *http.Request) bool { return true }
+		}
+		pattern := originPattern{host: origin}
+		if scheme, host, ok := strings.Cut(origin, "://"); ok {
+			pattern.scheme, pattern.host = scheme, host
+		}
+		if host, ok := strings.CutPrefix(pattern.host, "*."); ok {
+			pattern.host, pattern.suffix = "."+host, true
+		}
+		pattern.host = strings.ToLower(pattern.host)
+		patterns = append(patterns, pattern)
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" {
+			slog.Warn("Rejected malformed origin", "origin", origin)
+			return false
+		}
+		host := strings.ToLower(u.Host)
+		if len(patterns) == 0 {
+			if strings.EqualFold(host, r.Host) {
+				return true
+			}
+			slog.Warn("Rejected cross origin websocket", "origin", origin, "host", r.Host)
+			return false
+		}
+		for _, p := range patterns {
+			if p.matches(u.Scheme, host) {
+				return true
+			}
+		}
+		slog.Warn("Rejected origin not in allow-list", "origin", origin)
+		return false
+	}
+}
+
+func (p originPattern) matches(scheme string, host string) bool {
+	if p.scheme != "" && p.scheme != scheme {
+		return false
+	}
+	if !p.suffix {
+		return host == p.host
+	}
+	return strings.HasSuffix(host, p.host) || strings.HasSuffix(hostname(host), p.host)
+}
+
+func hostname(host string) string {
+	if i := strings.LastIndex(host, ":"); i > strings.LastIndex(host, "]") {
+		return host[:i]
+	}
+	return host
+}
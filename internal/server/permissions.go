@@ -0,0 +1,198 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/bb"
+	"battlebit/internal/hub"
+	"battlebit/internal/log"
+	"context"
+	"errors"
+	"fmt"
+)
+
+var errForbidden = errors.New("forbidden")
+
+// methodRoles is the least role allowed to call each method. Methods acting
+// on a game someone else created also need canManage.
+var methodRoles = map[string]auth.Role{
+	METHOD_AUTHENTICATE:        auth.RoleSpectator,
+	METHOD_CREATE_GAME:         auth.RoleHost,
+	METHOD_LIST_GAMES:          auth.RoleSpectator,
+	METHOD_GET_GAME:            auth.RoleSpectator,
+	METHOD_REMOVE_GAME:         auth.RoleHost,
+	METHOD_JOIN_GAME:           auth.RolePlayer,
+	METHOD_LEAVE_GAME:          auth.RolePlayer,
+	METHOD_PLAYER_MOVE:         auth.RolePlayer,
+	METHOD_GAME_METRICS:        auth.RoleSpectator,
+	METHOD_ENQUEUE:             auth.RolePlayer,
+	METHOD_DEQUEUE:             auth.RolePlayer,
+	METHOD_GET_LEADERBOARD:     auth.RoleSpectator,
+	METHOD_GET_PLAYER_PROFILE:  auth.RoleSpectator,
+	METHOD_LIST_HISTORY:        auth.RoleSpectator,
+	METHOD_SPECTATE_GAME:       auth.RoleSpectator,
+	METHOD_STOP_SPECTATING:     auth.RoleSpectator,
+	METHOD_SEND_CHAT:           auth.RolePlayer,
+	METHOD_GET_CHAT:            auth.RoleSpectator,
+	METHOD_MUTE_PLAYER:         auth.RoleHost,
+	METHOD_UNMUTE_PLAYER:       auth.RoleHost,
+	METHOD_CREATE_TOURNAMENT:   auth.RoleHost,
+	METHOD_LIST_TOURNAMENTS:    auth.RoleSpectator,
+	METHOD_GET_TOURNAMENT:      auth.RoleSpectator,
+	METHOD_WATCH_TOURNAMENT:    auth.RoleSpectator,
+	METHOD_REGISTER_TOURNAMENT: auth.RolePlayer,
+	METHOD_WITHDRAW_TOURNAMENT: auth.RolePlayer,
+	METHOD_START_TOURNAMENT:    auth.RoleHost,
+	METHOD_KICK_PLAYER:         auth.RoleHost,
+	METHOD_BAN_PLAYER:          auth.RoleHost,
+	METHOD_PAUSE_GAME:          auth.RoleHost,
+	METHOD_RESUME_GAME:         auth.RoleHost,
+	METHOD_EXPORT_REPLAY:       auth.RoleSpectator,
+	METHOD_REPLAY:              auth.RoleSpectator,
+	METHOD_REPLAY_SEEK:         auth.RoleSpectator,
+	METHOD_REPLAY_SPEED:        auth.RoleSpectator,
+	METHOD_REPLAY_STOP:         auth.RoleSpectator,
+}
+
+// principal identifies the caller. Anonymous callers own what they create for
+// as long as their connection lasts.
+func (gs *GameServer) principal(ctx context.Context, sess *session) (string, auth.Role) {
+	if id, ok := auth.GetIdentity(ctx); ok {
+		return id.AccountId, id.Role
+	}
+	return "anonymous:" + sess.id, gs.anonymousRole
+}
+
+func (gs *GameServer) authorize(ctx context.Context, req *JSONRPCRequest, sess *session) error {
+	required, ok := methodRoles[req.Method]
+	if !ok {
+		return nil
+	}
+	_, role := gs.principal(ctx, sess)
+	if !role.Allows(required) {
+		return fmt.Errorf("%w: %s requires role %s, caller is %s", errForbidden, req.Method, required, role)
+	}
+	return nil
+}
+
+func (gs *GameServer) canManage(ctx context.Context, sess *session, game *bb.Game) bool {
+	owner, role := gs.principal(ctx, sess)
+	return role == auth.RoleAdmin || (game.CreatorId != "" && game.CreatorId == owner)
+}
+
+// canActAs allows acting for players joined with the caller's account, or
+// anonymously from the caller's connection. Only game managers act for
+// anyone, autopilots included.
+func (gs *GameServer) canActAs(ctx context.Context, sess *session, game *bb.Game, playerId string) bool {
+	if gs.canManage(ctx, sess, game) {
+		return true
+	}
+	p, err := game.PlayerOwner(ctx, playerId)
+	if err != nil || p.AutoPilot {
+		return false
+	}
+	if p.AccountId == "" {
+		return p.PlayerConnection == sess.id
+	}
+	id, ok := auth.GetIdentity(ctx)
+	return ok && id.AccountId == p.AccountId
+}
+
+// listedGames hides unlisted games from everyone but their creator and admins.
+func (gs *GameServer) listedGames(ctx context.Context, sess *session) []*bb.GameMetrics {
+	owner, role := gs.principal(ctx, sess)
+	games := make([]*bb.GameMetrics, 0)
+	for _, m := range gs.hub.ListGames(ctx) {
+		if m.Visibility != bb.VisibilityUnlisted || role == auth.RoleAdmin || m.CreatorId == owner {
+			games = append(games, m)
+		}
+	}
+	return games
+}
+
+// joinableGame finds the game by invite code, falling back to its id.
+func (gs *GameServer) joinableGame(ctx context.Context, pj *bb.PlayerJoin) (*bb.Game, error) {
+	if pj.InviteCode == "" {
+		return gs.hub.GetGame(ctx, hub.GameId{ID: pj.GameId})
+	}
+	game, err := gs.hub.GameByInvite(ctx, pj.InviteCode)
+	if err != nil {
+		return nil, err
+	}
+	if pj.GameId != "" && pj.GameId != game.GameId {
+		return nil, fmt.Errorf("invite code is for another game")
+	}
+	return game, nil
+}
+
+// inGame tells whether the caller manages the game or plays in it.
+func (gs *GameServer) inGame(ctx context.Context, sess *session, game *bb.Game) bool {
+	if gs.canManage(ctx, sess, game) {
+		return true
+	}
+	account, _ := gs.principal(ctx, sess)
+	return game.HasMember(account, sess.id)
+}
+
+// readableGame applies the visibility of a game to everything read from it,
+// as historyVisible does for finished games: its managers and players always
+// get in, others need the invite code of an unlisted game and the password of
+// a password game. A game gone from the hub comes back nil, with no error,
+// when its history entry is visible to the caller.
+func (gs *GameServer) readableGame(ctx context.Context, req *JSONRPCRequest, sess *session, gameId string, inviteCode string, password string) (*bb.Game, *JSONRPCResponse) {
+	log := log.GetLogger(ctx)
+	game, err := gs.joinableGame(ctx, &bb.PlayerJoin{GameId: gameId, InviteCode: inviteCode})
+	if err != nil {
+		if e, ok := gs.hub.HistoryEntry(gameId); ok && inviteCode == "" && gs.historyVisible(ctx, sess)(e) {
+			return nil, nil
+		}
+		log.Debug("Game not readable", "gameId", gameId, "error", err.Error())
+		return nil, responseError(req, 404, err)
+	}
+	if gs.inGame(ctx, sess, game) {
+		return game, nil
+	}
+	if game.Visibility == bb.VisibilityUnlisted && inviteCode == "" {
+		// the id of an unlisted game is not enough, so it does not leak it exists
+		log.Warn("Unlisted game read without invite code", "gameId", game.GameId)
+		return nil, responseError(req, 404, fmt.Errorf("game not found"))
+	}
+	if !game.CheckPassword(password) {
+		log.Warn("Wrong game password", "gameId", game.GameId)
+		return nil, responseError(req, 403, fmt.Errorf("%w: wrong password", errForbidden))
+	}
+	return game, nil
+}
+
+// seesSeed tells whether the caller may read the seed of a game, which
+// predicts every autopilot move: its managers and players, and for a finished
+// game its creator, its players and admins.
+func (gs *GameServer) seesSeed(ctx context.Context, sess *session, game *bb.Game, gameId string) bool {
+	if game != nil {
+		return gs.inGame(ctx, sess, game)
+	}
+	owner, role := gs.principal(ctx, sess)
+	if role == auth.RoleAdmin {
+		return true
+	}
+	e, ok := gs.hub.HistoryEntry(gameId)
+	if !ok {
+		return false
+	}
+	if e.CreatorId == owner {
+		return true
+	}
+	for _, p := range e.Players {
+		if p.AccountId != "" && p.AccountId == owner {
+			return true
+		}
+	}
+	return false
+}
+
+func redactEvents(events []*bb.Event) []*bb.Event {
+	redacted := make([]*bb.Event, 0, len(events))
+	for _, event := range events {
+		redacted = append(redacted, event.Redacted())
+	}
+	return redacted
+}
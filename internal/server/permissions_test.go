@@ -0,0 +1,103 @@
+package server
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/chat"
+	"battlebit/internal/hub"
+	"context"
+	"testing"
+)
+
+func TestCanActAs(t *testing.T) {
+	gs, url := newTestServer(t, nil)
+	host, owner, other := dial(t, url), dial(t, url), dial(t, url)
+
+	created := new(GameCreated)
+	host.result(METHOD_CREATE_GAME, hub.CrateNewGame{Size: 1024, Autopilots: 1}, created)
+	added := new(bb.PlayerAdded)
+	owner.result(METHOD_JOIN_GAME, bb.PlayerJoin{GameId: created.GameId, PlayerName: "alice"}, added)
+	game, err := gs.hub.GetGame(context.Background(), hub.GameId{ID: created.GameId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var autoPilotId string
+	for _, p := range game.Players {
+		if p.AutoPilot {
+			autoPilotId = p.PlayerId
+		}
+	}
+
+	tests := []struct {
+		name     string
+		client   *testClient
+		playerId string
+		allowed  bool
+	}{
+		{name: "own anonymous player", client: owner, playerId: added.PlayerId, allowed: true},
+		{name: "anonymous player of another connection", client: other, playerId: added.PlayerId},
+		{name: "autopilot", client: other, playerId: autoPilotId},
+		{name: "unknown player", client: other, playerId: "nobody"},
+		{name: "game creator", client: host, playerId: added.PlayerId, allowed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := []struct {
+				method string
+				params any
+			}{
+				{METHOD_PLAYER_MOVE, bb.PlayerMove{GameId: created.GameId, PlayerId: tt.playerId, Index: 1}},
+				{METHOD_SEND_CHAT, chat.SendChat{Channel: chat.ChannelGame, GameId: created.GameId, PlayerId: tt.playerId, Text: "hi"}},
+			}
+			for _, c := range calls {
+				resp := tt.client.call(c.method, c.params)
+				allowed := resp.Error == nil || resp.Error.Code != 403
+				if allowed != tt.allowed {
+					t.Errorf("%s: allowed %v, want %v (%+v)", c.method, allowed, tt.allowed, resp.Error)
+				}
+			}
+		})
+	}
+
+	if resp := other.call(METHOD_LEAVE_GAME, bb.PlayerLeave{GameId: created.GameId, PlayerId: added.PlayerId}); resp.Error == nil || resp.Error.Code != 403 {
+		t.Errorf("another connection removed the player: %+v", resp.Error)
+	}
+	if resp := owner.call(METHOD_LEAVE_GAME, bb.PlayerLeave{GameId: created.GameId, PlayerId: added.PlayerId}); resp.Error != nil {
+		t.Errorf("the player can not leave: %+v", resp.Error)
+	}
+}
+
+func TestReadableGame(t *testing.T) {
+	_, url := newTestServer(t, nil)
+	host, other := dial(t, url), dial(t, url)
+
+	unlisted := new(GameCreated)
+	host.result(METHOD_CREATE_GAME, hub.CrateNewGame{Size: 64, Visibility: bb.VisibilityUnlisted}, unlisted)
+	locked := new(GameCreated)
+	host.result(METHOD_CREATE_GAME, hub.CrateNewGame{Size: 64, Visibility: bb.VisibilityPassword, Password: "s3cret"}, locked)
+
+	tests := []struct {
+		name     string
+		client   *testClient
+		params   hub.GetGame
+		wantCode int
+	}{
+		{name: "creator", client: host, params: hub.GetGame{ID: unlisted.GameId}},
+		{name: "unlisted by id", client: other, params: hub.GetGame{ID: unlisted.GameId}, wantCode: 404},
+		{name: "unlisted by invite", client: other, params: hub.GetGame{InviteCode: unlisted.InviteCode}},
+		{name: "without password", client: other, params: hub.GetGame{ID: locked.GameId}, wantCode: 403},
+		{name: "with password", client: other, params: hub.GetGame{ID: locked.GameId, Password: "s3cret"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, method := range []string{METHOD_GET_GAME, METHOD_GAME_METRICS} {
+				code := 0
+				if resp := tt.client.call(method, tt.params); resp.Error != nil {
+					code = resp.Error.Code
+				}
+				if code != tt.wantCode {
+					t.Errorf("%s: code %d, want %d", method, code, tt.wantCode)
+				}
+			}
+		})
+	}
+}
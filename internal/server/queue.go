@@ -0,0 +1,64 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/hub"
+	"battlebit/internal/log"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+func (gs *GameServer) queueRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_ENQUEUE:
+		log.Info("Enqueueing player", "params", string(req.Params))
+		eq := new(hub.Enqueue)
+		err := json.Unmarshal(req.Params, eq)
+		if err != nil {
+			log.Error("Failed to unmarshal Enqueue", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		identity, authenticated := auth.GetIdentity(ctx)
+		if !authenticated && gs.requireAuth {
+			return responseError(req, 401, fmt.Errorf("authentication required"))
+		}
+		if authenticated {
+			eq.PlayerName = identity.Name
+			eq.AccountId = identity.AccountId
+		}
+		eq.Connection = sess.id
+		eq.Owner = sess.id
+		ticket, err := gs.hub.Enqueue(ctx, *eq, func(mf *hub.MatchFound) {
+			if mf.GameId != "" {
+				sess.joinChannel(mf.GameId)
+			}
+			sendNotification(ctx, sess, NOTIFICATION_MATCH_FOUND, mf)
+		})
+		if errors.Is(err, hub.ErrShuttingDown) {
+			return responseError(req, 503, err)
+		}
+		if err != nil {
+			log.Error("Failed to enqueue player", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		return responseResult(req, ticket)
+	case METHOD_DEQUEUE:
+		log.Info("Dequeueing player", "params", string(req.Params))
+		dq := new(hub.Dequeue)
+		err := json.Unmarshal(req.Params, dq)
+		if err != nil {
+			log.Error("Failed to unmarshal Dequeue", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		if err := gs.hub.Dequeue(ctx, dq.TicketId, sess.id); err != nil {
+			return responseError(req, 404, err)
+		}
+		return responseResult(req, map[string]string{"message": "ticket removed"})
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
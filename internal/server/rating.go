@@ -0,0 +1,50 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/log"
+	"battlebit/internal/rating"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+func (gs *GameServer) ratingRouter(ctx context.Context, req *JSONRPCRequest) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_GET_LEADERBOARD:
+		log.Info("Getting leaderboard", "params", string(req.Params))
+		gl := new(rating.GetLeaderboard)
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, gl); err != nil {
+				log.Error("Failed to unmarshal GetLeaderboard", "error", err.Error())
+				return responseError(req, 400, err)
+			}
+		}
+		return responseResult(req, gs.hub.Leaderboard(*gl))
+	case METHOD_GET_PLAYER_PROFILE:
+		log.Info("Getting player profile", "params", string(req.Params))
+		gp := new(rating.GetPlayerProfile)
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, gp); err != nil {
+				log.Error("Failed to unmarshal GetPlayerProfile", "error", err.Error())
+				return responseError(req, 400, err)
+			}
+		}
+		if gp.AccountId == "" {
+			id, ok := auth.GetIdentity(ctx)
+			if !ok {
+				return responseError(req, 400, fmt.Errorf("accountId is required when not authenticated"))
+			}
+			gp.AccountId = id.AccountId
+		}
+		profile, err := gs.hub.PlayerProfile(gp.AccountId)
+		if err != nil {
+			return responseError(req, 404, err)
+		}
+		return responseResult(req, profile)
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
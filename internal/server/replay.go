@@ -0,0 +1,194 @@
+package server
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/hub"
+	"battlebit/internal/log"
+	"battlebit/internal/replay"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (gs *GameServer) replayRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_EXPORT_REPLAY:
+		log.Info("Exporting replay", "params", string(req.Params))
+		er := new(replay.ExportReplay)
+		err := json.Unmarshal(req.Params, er)
+		if err != nil {
+			log.Error("Failed to unmarshal ExportReplay", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		game, denied := gs.readableGame(ctx, req, sess, er.GameId, er.InviteCode, er.Password)
+		if denied != nil {
+			return denied
+		}
+		if game != nil {
+			er.GameId = game.GameId
+		}
+		events, err := gs.readableEvents(ctx, sess, game, er.GameId)
+		if err != nil {
+			log.Error("Failed to get game events", "error", err.Error())
+			return responseError(req, 404, err)
+		}
+		if er.Format == "" {
+			er.Format = replay.FORMAT_JSONL
+		}
+		data, err := replay.Export(er.Format, er.GameId, events)
+		if err != nil {
+			log.Error("Failed to export replay", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		exported := &replay.ExportedReplay{
+			GameId:   er.GameId,
+			Format:   er.Format,
+			Events:   len(events),
+			Encoding: "text",
+			Data:     string(data),
+		}
+		if er.Format == replay.FORMAT_BINARY {
+			exported.Encoding = "base64"
+			exported.Data = base64.StdEncoding.EncodeToString(data)
+		}
+		return responseResult(req, exported)
+	case METHOD_REPLAY:
+		log.Info("Starting replay", "params", string(req.Params))
+		sr := new(replay.StartReplay)
+		err := json.Unmarshal(req.Params, sr)
+		if err != nil {
+			log.Error("Failed to unmarshal StartReplay", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		game, denied := gs.readableGame(ctx, req, sess, sr.GameId, sr.InviteCode, sr.Password)
+		if denied != nil {
+			return denied
+		}
+		if game != nil {
+			sr.GameId = game.GameId
+		}
+		events, err := gs.readableEvents(ctx, sess, game, sr.GameId)
+		if err != nil {
+			log.Error("Failed to get game events", "error", err.Error())
+			return responseError(req, 404, err)
+		}
+		if sr.Speed == 0 {
+			sr.Speed = 1
+		}
+		pb, err := replay.NewPlayback(uuid.New().String(), sr.GameId, events, sr.Speed)
+		if err != nil {
+			log.Error("Failed to create playback", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		if sr.FromSeq > 0 {
+			gs.notifySeeked(ctx, sess, pb, pb.SeekSeq(sr.FromSeq))
+		}
+		sess.addReplay(pb)
+		sess.goBackground(ctx, pb.ReplayId, func(ctx context.Context) {
+			pb.Run(ctx, func(event *bb.Event) {
+				sendNotification(ctx, sess, NOTIFICATION_REPLAY_EVENT, &replay.ReplayEvent{ReplayId: pb.ReplayId, Event: event})
+			}, func() {
+				seq, total := pb.Position()
+				sendNotification(ctx, sess, NOTIFICATION_REPLAY_FINISHED, &replay.ReplayFinished{ReplayId: pb.ReplayId, Seq: seq, Events: total})
+			})
+		})
+		return responseResult(req, &replay.ReplayStarted{
+			ReplayId: pb.ReplayId,
+			GameId:   sr.GameId,
+			Events:   len(events),
+			Speed:    sr.Speed,
+		})
+	case METHOD_REPLAY_SEEK:
+		log.Info("Seeking replay", "params", string(req.Params))
+		sr := new(replay.SeekReplay)
+		err := json.Unmarshal(req.Params, sr)
+		if err != nil {
+			log.Error("Failed to unmarshal SeekReplay", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		pb, ok := sess.getReplay(sr.ReplayId)
+		if !ok {
+			return responseError(req, 404, fmt.Errorf("replay not found"))
+		}
+		var played []*bb.Event
+		switch {
+		case sr.Seq != nil:
+			played = pb.SeekSeq(*sr.Seq)
+		case sr.OffsetMs != nil:
+			played = pb.SeekOffset(time.Duration(*sr.OffsetMs) * time.Millisecond)
+		default:
+			return responseError(req, 400, fmt.Errorf("seq or offsetMs is required"))
+		}
+		return responseResult(req, gs.notifySeeked(ctx, sess, pb, played))
+	case METHOD_REPLAY_SPEED:
+		log.Info("Changing replay speed", "params", string(req.Params))
+		rs := new(replay.ReplaySpeed)
+		err := json.Unmarshal(req.Params, rs)
+		if err != nil {
+			log.Error("Failed to unmarshal ReplaySpeed", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		pb, ok := sess.getReplay(rs.ReplayId)
+		if !ok {
+			return responseError(req, 404, fmt.Errorf("replay not found"))
+		}
+		if err := pb.SetSpeed(rs.Speed); err != nil {
+			return responseError(req, 400, err)
+		}
+		return responseResult(req, rs)
+	case METHOD_REPLAY_STOP:
+		log.Info("Stopping replay", "params", string(req.Params))
+		ri := new(replay.ReplayId)
+		err := json.Unmarshal(req.Params, ri)
+		if err != nil {
+			log.Error("Failed to unmarshal ReplayId", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		if _, ok := sess.getReplay(ri.ReplayId); !ok {
+			return responseError(req, 404, fmt.Errorf("replay not found"))
+		}
+		sess.removeReplay(ri.ReplayId)
+		return responseResult(req, map[string]string{"message": "replay stopped"})
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
+
+// readableEvents returns the log of a game as the caller may read it: those
+// who neither manage nor play a live game only get what spectators see, and
+// only those who see the seed get it.
+func (gs *GameServer) readableEvents(ctx context.Context, sess *session, game *bb.Game, gameId string) ([]*bb.Event, error) {
+	if game != nil && !gs.inGame(ctx, sess, game) {
+		return redactEvents(game.DelayedEvents(0, time.Now())), nil
+	}
+	events, err := gs.hub.GameEvents(ctx, hub.GameId{ID: gameId})
+	if err != nil || gs.seesSeed(ctx, sess, game, gameId) {
+		return events, err
+	}
+	return redactEvents(events), nil
+}
+
+// notifySeeked sends the board rebuilt from the events played so far, so
+// subscribers can redraw it before the stream continues.
+func (gs *GameServer) notifySeeked(ctx context.Context, sess *session, pb *replay.Playback, played []*bb.Event) *replay.ReplaySeeked {
+	log := log.GetLogger(ctx)
+
+	seeked := &replay.ReplaySeeked{ReplayId: pb.ReplayId}
+	if len(played) > 0 {
+		seeked.Seq = played[len(played)-1].Seq
+		g, err := bb.Replay(ctx, played)
+		if err != nil {
+			log.Error("Failed to rebuild replay state", "replayId", pb.ReplayId, "error", err.Error())
+		} else {
+			seeked.State = g.View()
+		}
+	}
+	sendNotification(ctx, sess, NOTIFICATION_REPLAY_SEEKED, seeked)
+	return seeked
+}
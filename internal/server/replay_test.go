@@ -0,0 +1,25 @@
+package server
+
+import (
+	"battlebit/internal/hub"
+	"battlebit/internal/replay"
+	"testing"
+)
+
+func TestReplayReplacesPrevious(t *testing.T) {
+	_, url := newTestServer(t, nil)
+	host := dial(t, url)
+
+	created := new(GameCreated)
+	host.result(METHOD_CREATE_GAME, hub.CrateNewGame{Size: 64}, created)
+	first, second := new(replay.ReplayStarted), new(replay.ReplayStarted)
+	host.result(METHOD_REPLAY, replay.StartReplay{GameId: created.GameId, Speed: 1}, first)
+	host.result(METHOD_REPLAY, replay.StartReplay{GameId: created.GameId, Speed: 1}, second)
+
+	if resp := host.call(METHOD_REPLAY_SPEED, replay.ReplaySpeed{ReplayId: first.ReplayId, Speed: 2}); resp.Error == nil || resp.Error.Code != 404 {
+		t.Errorf("the replaced replay is still playing: %+v", resp.Error)
+	}
+	if resp := host.call(METHOD_REPLAY_SPEED, replay.ReplaySpeed{ReplayId: second.ReplayId, Speed: 2}); resp.Error != nil {
+		t.Errorf("the new replay is not playing: %+v", resp.Error)
+	}
+}
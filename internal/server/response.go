@@ -4,8 +4,6 @@ import (
 	"battlebit/internal/log"
 	"context"
 	"encoding/json"
-
-	"github.com/gorilla/websocket"
 )
 
 func responseError(req *JSONRPCRequest, code int, err error) *JSONRPCResponse {
@@ -26,7 +24,7 @@ func responseResult(req *JSONRPCRequest, result interface{}) *JSONRPCResponse {
 		ID:      req.ID,
 	}
 }
-func sendMsg(ctx context.Context, conn *websocket.Conn, resp *JSONRPCResponse) {
+func sendMsg(ctx context.Context, sess *session, resp *JSONRPCResponse) {
 	log := log.GetLogger(ctx)
 
 	p, err := json.Marshal(resp)
@@ -42,8 +40,20 @@ func sendMsg(ctx context.Context, conn *websocket.Conn, resp *JSONRPCResponse) {
 		}
 		p, _ = json.Marshal(subResp)
 	}
-	if err := conn.WriteMessage(1, p); err != nil {
-		log.Error("Failed to write message", "error", err.Error())
+	sess.write(ctx, p)
+}
+
+func sendNotification(ctx context.Context, sess *session, method string, params interface{}) {
+	log := log.GetLogger(ctx)
+
+	p, err := json.Marshal(&JSONRPCNotification{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+	if err != nil {
+		log.Error("Failed to marshal JSONRPCNotification", "error", err.Error(), "method", method)
 		return
 	}
+	sess.write(ctx, p)
 }
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"battlebit/internal/config"
+	"battlebit/internal/hub"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type testResponse struct {
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *JSONRPCError   `json:"error"`
+}
+
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+	id   int
+}
+
+// newTestServer serves the websocket endpoint of a game server letting
+// anonymous connections host games.
+func newTestServer(t *testing.T, change func(cfg *config.Config)) (*GameServer, string) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Server.AnonymousRole = "host"
+	if change != nil {
+		change(cfg)
+	}
+	gs := NewGameServer(hub.NewHub(cfg.Hub), cfg.Server)
+	srv := httptest.NewServer(http.HandlerFunc(gs.WsEndpoint))
+	t.Cleanup(srv.Close)
+	return gs, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dial(t *testing.T, url string) *testClient {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	// the greeting
+	if _, _, err := conn.ReadMessage(); err != nil {
+		t.Fatal(err)
+	}
+	return &testClient{t: t, conn: conn}
+}
+
+// call sends the request and returns its response, skipping notifications.
+func (c *testClient) call(method string, params any) *testResponse {
+	c.t.Helper()
+	p, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	c.id++
+	if err := c.conn.WriteJSON(&JSONRPCRequest{JSONRPC: "2.0", Method: method, Params: p, ID: c.id}); err != nil {
+		c.t.Fatal(err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		resp := new(testResponse)
+		if err := c.conn.ReadJSON(resp); err != nil {
+			c.t.Fatal(err)
+		}
+		if resp.Method == "" {
+			return resp
+		}
+	}
+}
+
+// result calls the method and decodes its result into v.
+func (c *testClient) result(method string, params any, v any) {
+	c.t.Helper()
+	resp := c.call(method, params)
+	if resp.Error != nil {
+		c.t.Fatalf("%s failed: %d %s", method, resp.Error.Code, resp.Error.Message)
+	}
+	if err := json.Unmarshal(resp.Result, v); err != nil {
+		c.t.Fatal(err)
+	}
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/log"
+	"battlebit/internal/replay"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// sendQueueSize is how many messages a session holds for a client that reads
+// slower than they arrive. A client that lets it fill up is disconnected.
+const sendQueueSize = 256
+
+// writeWait bounds every write, so a client that stopped reading cannot hold
+// up the server.
+const writeWait = 10 * time.Second
+
+// frame is a message waiting in the send queue. A close frame ends the
+// connection after the messages queued before it.
+type frame struct {
+	messageType int
+	data        []byte
+}
+
+type session struct {
+	id          string
+	conn        *websocket.Conn
+	remoteAddr  string
+	connectedAt time.Time
+	requests    atomic.Uint64
+	send        chan frame
+	done        chan struct{}
+	written     chan struct{}
+	mutex       sync.Mutex
+	cancels     map[string]context.CancelFunc
+	replays     map[string]*replay.Playback
+	identity    *auth.Identity
+	channels    map[string]bool
+}
+
+func newSession(conn *websocket.Conn) *session {
+	return &session{
+		id:          uuid.New().String(),
+		conn:        conn,
+		remoteAddr:  conn.RemoteAddr().String(),
+		connectedAt: time.Now(),
+		send:        make(chan frame, sendQueueSize),
+		done:        make(chan struct{}),
+		written:     make(chan struct{}),
+		cancels:     make(map[string]context.CancelFunc),
+		replays:     make(map[string]*replay.Playback),
+		channels:    make(map[string]bool),
+	}
+}
+
+// write queues p for writeLoop, so broadcasts never wait for a slow client.
+func (s *session) write(ctx context.Context, p []byte) {
+	log := log.GetLogger(ctx)
+
+	select {
+	case <-s.done:
+	case s.send <- frame{messageType: websocket.TextMessage, data: p}:
+	default:
+		log.Warn("Send queue full, disconnecting client", "sessionId", s.id, "remoteAddr", s.remoteAddr)
+		_ = s.conn.Close()
+	}
+}
+
+// writeLoop writes the queued messages until the session closes. A failed
+// write closes the connection, which ends the read loop as well.
+func (s *session) writeLoop(ctx context.Context) {
+	log := log.GetLogger(ctx)
+
+	defer close(s.written)
+	for {
+		var f frame
+		select {
+		case <-s.done:
+			return
+		case f = <-s.send:
+		}
+		deadline := time.Now().Add(writeWait)
+		if f.messageType == websocket.CloseMessage {
+			_ = s.conn.WriteControl(websocket.CloseMessage, f.data, deadline)
+			_ = s.conn.Close()
+			return
+		}
+		_ = s.conn.SetWriteDeadline(deadline)
+		if err := s.conn.WriteMessage(f.messageType, f.data); err != nil {
+			log.Error("Failed to write message", "sessionId", s.id, "error", err.Error())
+			_ = s.conn.Close()
+			return
+		}
+	}
+}
+
+// goBackground runs fn until it returns, the session closes or stop is called
+// with the same key.
+func (s *session) goBackground(ctx context.Context, key string, fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mutex.Lock()
+	s.cancels[key] = cancel
+	s.mutex.Unlock()
+	go func() {
+		defer s.stop(key)
+		fn(ctx)
+	}()
+}
+
+func (s *session) stop(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	cancel, ok := s.cancels[key]
+	if !ok {
+		return false
+	}
+	cancel()
+	delete(s.cancels, key)
+	return true
+}
+
+func (s *session) running(key string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	_, ok := s.cancels[key]
+	return ok
+}
+
+// closeConn closes the connection once the messages queued before are
+// written, waiting at most a second for them.
+func (s *session) closeConn(reason string) {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, reason)
+	select {
+	case s.send <- frame{messageType: websocket.CloseMessage, data: msg}:
+	default:
+		_ = s.conn.Close()
+		return
+	}
+	select {
+	case <-s.written:
+	case <-time.After(time.Second):
+		_ = s.conn.Close()
+	}
+}
+
+func (s *session) close() {
+	close(s.done)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, cancel := range s.cancels {
+		cancel()
+		delete(s.cancels, key)
+	}
+}
+
+// addReplay makes pb the replay of the session and stops the one it had, so a
+// connection plays one replay at a time.
+func (s *session) addReplay(pb *replay.Playback) {
+	s.mutex.Lock()
+	replaced := make([]string, 0, len(s.replays))
+	for replayId := range s.replays {
+		replaced = append(replaced, replayId)
+		delete(s.replays, replayId)
+	}
+	s.replays[pb.ReplayId] = pb
+	s.mutex.Unlock()
+	for _, replayId := range replaced {
+		s.stop(replayId)
+	}
+}
+
+func (s *session) getReplay(replayId string) (*replay.Playback, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	pb, ok := s.replays[replayId]
+	return pb, ok
+}
+
+func (s *session) removeReplay(replayId string) {
+	s.mutex.Lock()
+	delete(s.replays, replayId)
+	s.mutex.Unlock()
+	s.stop(replayId)
+}
+
+// joinChannel subscribes the session to the chat of a game.
+func (s *session) joinChannel(gameId string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.channels[gameId] = true
+}
+
+func (s *session) inChannel(gameId string) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.channels[gameId]
+}
+
+func (s *session) setIdentity(id *auth.Identity) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.identity = id
+}
+
+// withIdentity adds the session identity to ctx until its token expires.
+func (s *session) withIdentity(ctx context.Context) context.Context {
+	s.mutex.Lock()
+	id := s.identity
+	s.mutex.Unlock()
+	if id == nil || !time.Now().Before(id.ExpiresAt) {
+		return ctx
+	}
+	return auth.WithIdentity(ctx, id)
+}
+
+func (s *session) info() *SessionInfo {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	replays := make([]string, 0, len(s.replays))
+	for replayId := range s.replays {
+		replays = append(replays, replayId)
+	}
+	accountId := ""
+	if s.identity != nil {
+		accountId = s.identity.AccountId
+	}
+	return &SessionInfo{
+		AccountId:   accountId,
+		SessionId:   s.id,
+		RemoteAddr:  s.remoteAddr,
+		ConnectedAt: s.connectedAt,
+		Requests:    s.requests.Load(),
+		Replays:     replays,
+	}
+}
+
+func (s *session) replaysByGame() map[string]int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	counts := make(map[string]int)
+	for _, pb := range s.replays {
+		counts[pb.GameId]++
+	}
+	return counts
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/log"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const spectatorPoll = 100 * time.Millisecond
+
+func (gs *GameServer) spectatorRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	switch req.Method {
+	case METHOD_SPECTATE_GAME:
+		log.Info("Spectating game", "params", string(req.Params))
+		sg := new(bb.SpectateGame)
+		if err := json.Unmarshal(req.Params, sg); err != nil {
+			log.Error("Failed to unmarshal SpectateGame", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		game, denied := gs.readableGame(ctx, req, sess, sg.GameId, sg.InviteCode, sg.Password)
+		if denied != nil {
+			return denied
+		}
+		if game == nil {
+			return responseError(req, 404, fmt.Errorf("game not found"))
+		}
+		key := "spectate:" + game.GameId
+		if sess.running(key) {
+			return responseError(req, 409, fmt.Errorf("already spectating the game"))
+		}
+		// the first state is as delayed as the feed that follows it
+		view, err := game.DelayedView(ctx, time.Now())
+		if err != nil {
+			log.Error("Failed to rebuild delayed view", "gameId", game.GameId, "error", err.Error())
+			return responseError(req, 500, err)
+		}
+		started := &SpectateStarted{GameId: game.GameId, DelayMs: view.SpectatorDelayMs, Seq: view.Seq, State: view}
+		redact := !gs.inGame(ctx, sess, game)
+		game.AddSpectator()
+		sess.goBackground(ctx, key, func(ctx context.Context) {
+			defer game.RemoveSpectator()
+			gs.spectate(ctx, sess, game, started.Seq, redact)
+		})
+		return responseResult(req, started)
+	case METHOD_STOP_SPECTATING:
+		log.Info("Stop spectating", "params", string(req.Params))
+		sg := new(bb.SpectateGame)
+		if err := json.Unmarshal(req.Params, sg); err != nil {
+			log.Error("Failed to unmarshal SpectateGame", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		if !sess.stop("spectate:" + sg.GameId) {
+			return responseError(req, 404, fmt.Errorf("not spectating the game"))
+		}
+		return responseResult(req, map[string]string{"message": "spectating stopped"})
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
+
+// spectate streams the game events once they are older than the game's
+// spectator delay, until the game finishes or goes away. Outsiders get them
+// redacted.
+func (gs *GameServer) spectate(ctx context.Context, sess *session, game *bb.Game, afterSeq uint64, redact bool) {
+	ticker := time.NewTicker(spectatorPoll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			events, done := game.SpectatorEvents(afterSeq, now)
+			if redact {
+				events = redactEvents(events)
+			}
+			for _, event := range events {
+				sendNotification(ctx, sess, NOTIFICATION_SPECTATOR_EVENT, &SpectatorEvent{GameId: game.GameId, Event: event})
+				afterSeq = event.Seq
+			}
+			if !gs.hub.HasGame(game.GameId) {
+				done = true
+			}
+			if done {
+				sendNotification(ctx, sess, NOTIFICATION_SPECTATE_FINISHED, &SpectateFinished{GameId: game.GameId, Seq: afterSeq})
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/hub"
+	"battlebit/internal/log"
+	"battlebit/internal/tournament"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+func (gs *GameServer) tournamentRouter(ctx context.Context, req *JSONRPCRequest, sess *session) *JSONRPCResponse {
+	log := log.GetLogger(ctx)
+	notify := func(e *tournament.Event) {
+		if e.Type == tournament.EventMatchReady && e.Match != nil {
+			sess.joinChannel(e.Match.GameId)
+		}
+		sendNotification(ctx, sess, NOTIFICATION_TOURNAMENT_EVENT, e)
+	}
+	switch req.Method {
+	case METHOD_CREATE_TOURNAMENT:
+		log.Info("Creating tournament", "params", string(req.Params))
+		ct := new(tournament.CreateTournament)
+		if err := json.Unmarshal(req.Params, ct); err != nil {
+			log.Error("Failed to unmarshal CreateTournament", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		ct.CreatorId, _ = gs.principal(ctx, sess)
+		t, err := gs.hub.CreateTournament(ctx, *ct)
+		if errors.Is(err, hub.ErrShuttingDown) {
+			return responseError(req, 503, err)
+		}
+		if err != nil {
+			log.Error("Failed to create tournament", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		gs.hub.WatchTournament(t.TournamentId, sess.id, notify)
+		return responseResult(req, t)
+	case METHOD_LIST_TOURNAMENTS:
+		log.Info("Listing tournaments")
+		return responseResult(req, gs.hub.ListTournaments())
+	case METHOD_GET_TOURNAMENT, METHOD_WATCH_TOURNAMENT:
+		log.Info("Getting tournament", "method", req.Method, "params", string(req.Params))
+		tId := new(tournament.TournamentId)
+		if err := json.Unmarshal(req.Params, tId); err != nil {
+			log.Error("Failed to unmarshal TournamentId", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		t, err := gs.hub.GetTournament(tId.TournamentId)
+		if err != nil {
+			return responseError(req, 404, err)
+		}
+		if req.Method == METHOD_WATCH_TOURNAMENT {
+			gs.hub.WatchTournament(t.TournamentId, sess.id, notify)
+		}
+		return responseResult(req, t)
+	case METHOD_REGISTER_TOURNAMENT:
+		log.Info("Registering for tournament", "params", string(req.Params))
+		reg := new(tournament.Register)
+		if err := json.Unmarshal(req.Params, reg); err != nil {
+			log.Error("Failed to unmarshal Register", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		identity, authenticated := auth.GetIdentity(ctx)
+		if !authenticated && gs.requireAuth {
+			return responseError(req, 401, fmt.Errorf("authentication required"))
+		}
+		if authenticated {
+			reg.PlayerName = identity.Name
+			reg.AccountId = identity.AccountId
+		}
+		reg.Connection = sess.id
+		reg.Owner, _ = gs.principal(ctx, sess)
+		if _, err := gs.hub.GetTournament(reg.TournamentId); err != nil {
+			return responseError(req, 404, err)
+		}
+		entrant, err := gs.hub.RegisterEntrant(ctx, *reg, sess.id, notify)
+		if err != nil {
+			log.Error("Failed to register entrant", "error", err.Error())
+			return responseError(req, 409, err)
+		}
+		return responseResult(req, entrant)
+	case METHOD_WITHDRAW_TOURNAMENT:
+		log.Info("Withdrawing from tournament", "params", string(req.Params))
+		tId := new(tournament.TournamentId)
+		if err := json.Unmarshal(req.Params, tId); err != nil {
+			log.Error("Failed to unmarshal TournamentId", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		owner, _ := gs.principal(ctx, sess)
+		if err := gs.hub.WithdrawEntrant(ctx, tId.TournamentId, owner); err != nil {
+			return responseError(req, 409, err)
+		}
+		return responseResult(req, map[string]string{"message": "withdrawn"})
+	case METHOD_START_TOURNAMENT:
+		log.Info("Starting tournament", "params", string(req.Params))
+		tId := new(tournament.TournamentId)
+		if err := json.Unmarshal(req.Params, tId); err != nil {
+			log.Error("Failed to unmarshal TournamentId", "error", err.Error())
+			return responseError(req, 400, err)
+		}
+		creatorId, err := gs.hub.TournamentCreator(tId.TournamentId)
+		if err != nil {
+			return responseError(req, 404, err)
+		}
+		if owner, role := gs.principal(ctx, sess); role != auth.RoleAdmin && creatorId != owner {
+			return responseError(req, 403, fmt.Errorf("%w: only the creator or an admin can start the tournament", errForbidden))
+		}
+		t, err := gs.hub.StartTournament(ctx, tId.TournamentId)
+		if errors.Is(err, hub.ErrShuttingDown) {
+			return responseError(req, 503, err)
+		}
+		if err != nil {
+			log.Error("Failed to start tournament", "error", err.Error())
+			return responseError(req, 409, err)
+		}
+		return responseResult(req, t)
+	default:
+		log.Info("Method not found", "method", req.Method)
+		return responseResult(req, map[string]string{"message": "method not found"})
+	}
+}
@@ -0,0 +1,21 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// webHandler serves the single page client in web/, which talks to /ws like
+// any other client.
+func webHandler() http.Handler {
+	root, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		// the directory is embedded at build time
+		panic(err)
+	}
+	return http.FileServer(http.FS(root))
+}
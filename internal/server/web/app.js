@@ -0,0 +1,398 @@
+"use strict";
+
+// Rpc speaks JSON-RPC over the websocket, matching responses by id, and
+// reconnects when the connection drops.
+class Rpc {
+  constructor(url) {
+    this.url = url;
+    this.nextId = 1;
+    this.pending = new Map();
+    this.handlers = new Map();
+    this.connect();
+  }
+
+  connect() {
+    this.ws = new WebSocket(this.url);
+    this.ready = new Promise((resolve) => this.ws.addEventListener("open", resolve, { once: true }));
+    this.ws.addEventListener("open", () => this.emit("open"));
+    this.ws.addEventListener("message", (e) => this.receive(e.data));
+    this.ws.addEventListener("close", () => {
+      for (const p of this.pending.values()) p.reject(new Error("connection lost"));
+      this.pending.clear();
+      this.emit("close");
+      setTimeout(() => {
+        this.connect();
+        this.ready.then(() => this.emit("reconnected"));
+      }, 1000);
+    });
+  }
+
+  receive(data) {
+    let msg;
+    try {
+      msg = JSON.parse(data);
+    } catch {
+      return; // the server greets every connection in plain text
+    }
+    if (msg.method) {
+      this.emit(msg.method, msg.params);
+      return;
+    }
+    const p = this.pending.get(msg.id);
+    if (!p) return;
+    this.pending.delete(msg.id);
+    if (msg.error) p.reject(new Error(`${msg.error.code}: ${msg.error.message}`));
+    else p.resolve(msg.result);
+  }
+
+  async call(method, params) {
+    await this.ready;
+    const id = this.nextId++;
+    return new Promise((resolve, reject) => {
+      this.pending.set(id, { resolve, reject });
+      this.ws.send(JSON.stringify({ jsonrpc: "2.0", method, params, id }));
+    });
+  }
+
+  on(method, fn) {
+    this.handlers.set(method, fn);
+  }
+
+  emit(method, params) {
+    const fn = this.handlers.get(method);
+    if (fn) fn(params);
+  }
+}
+
+const $ = (id) => document.getElementById(id);
+const token = new URLSearchParams(location.search).get("token");
+const wsUrl = `${location.protocol === "https:" ? "wss" : "ws"}://${location.host}/ws${token ? `?token=${encodeURIComponent(token)}` : ""}`;
+const rpc = new Rpc(wsUrl);
+
+let game = null; // the game on screen
+let listTimer = null;
+
+function setStatus(text) {
+  $("status").textContent = text;
+}
+
+function fail(err) {
+  setStatus(err.message || String(err));
+}
+
+function playerName() {
+  const name = $("name").value.trim();
+  if (!name) throw new Error("enter your name first");
+  localStorage.setItem("battlebit-name", name);
+  return name;
+}
+
+function gameStatus(m) {
+  if (m.gameStatus.isFinished) return "finished";
+  if (m.gameStatus.isPaused) return "paused";
+  return m.gameStatus.isInProcess ? "running" : "waiting";
+}
+
+function button(label, onClick, secondary) {
+  const b = document.createElement("button");
+  b.type = "button";
+  b.textContent = label;
+  if (secondary) b.className = "secondary";
+  b.addEventListener("click", onClick);
+  return b;
+}
+
+// lobby
+
+async function listGames() {
+  let games;
+  try {
+    games = await rpc.call("list_game");
+  } catch (err) {
+    return fail(err);
+  }
+  const body = $("games");
+  body.replaceChildren();
+  $("no-games").hidden = games.length > 0;
+  for (const m of games) {
+    const row = body.insertRow();
+    for (const text of [m.gameId.slice(0, 8), m.sizeGame, m.players, m.autoPilots, gameStatus(m), m.currentDuration.replace(/\.\d+/, "")]) {
+      row.insertCell().textContent = text;
+    }
+    const actions = row.insertCell();
+    actions.className = "actions";
+    if (!m.gameStatus.isFinished) actions.append(button("Join", () => joinGame({ gameId: m.gameId }).catch(fail)));
+    actions.append(button("Watch", () => openGame(m.gameId, null, null).catch(fail), true));
+  }
+}
+
+function showLobby() {
+  $("game").hidden = true;
+  $("lobby").hidden = false;
+  listGames();
+  clearInterval(listTimer);
+  listTimer = setInterval(listGames, 3000);
+}
+
+async function joinGame(params) {
+  const added = await rpc.call("join_game", { ...params, playerName: playerName() });
+  await openGame(added.gameId, added, params.password);
+}
+
+$("create").addEventListener("submit", async (e) => {
+  e.preventDefault();
+  const form = new FormData(e.target);
+  try {
+    const name = playerName();
+    const created = await rpc.call("create_game", {
+      size: Number(form.get("size")),
+      autopilots: Number(form.get("autopilots")),
+      visibility: form.get("visibility"),
+      password: form.get("password") || undefined,
+    });
+    if (created.inviteCode && form.get("visibility") === "unlisted") setStatus(`invite code ${created.inviteCode}`);
+    const added = await rpc.call("join_game", { gameId: created.gameId, playerName: name, password: form.get("password") || undefined });
+    await openGame(created.gameId, added, form.get("password"));
+  } catch (err) {
+    fail(err);
+  }
+});
+
+$("invite").addEventListener("submit", (e) => {
+  e.preventDefault();
+  const form = new FormData(e.target);
+  joinGame({ gameId: "", inviteCode: form.get("inviteCode").trim(), password: form.get("password") || undefined }).catch(fail);
+});
+
+$("refresh").addEventListener("click", listGames);
+
+// game
+
+// openGame shows a game and follows its moves through the spectator feed,
+// as a player when added is set.
+async function openGame(gameId, added, password) {
+  const started = await rpc.call("spectate_game", { gameId, password: password || undefined });
+  const state = started.state;
+  const bytes = Uint8Array.from(atob(state.status || ""), (c) => c.charCodeAt(0));
+  game = {
+    id: gameId,
+    password,
+    playerId: added ? added.playerId : null,
+    size: state.sizeGame,
+    bits: new Uint8Array(Math.ceil(state.sizeGame / 8)),
+    on: 0,
+    players: new Map(),
+    finished: state.hasFinished,
+    winner: state.winnerName,
+    flashes: new Map(),
+  };
+  game.bits.set(bytes.subarray(0, game.bits.length));
+  for (let i = 0; i < game.size; i++) if (isOn(i)) game.on++;
+  for (const p of state.players || []) {
+    game.players.set(p.playerId, { name: p.playerName, bot: p.autoPilot, left: p.left, bits: p.bitsFlipped });
+  }
+  clearInterval(listTimer);
+  $("lobby").hidden = true;
+  $("game").hidden = false;
+  $("game-title").textContent = `${added ? "Playing" : "Watching"} ${gameId.slice(0, 8)}`;
+  $("move").hidden = !added;
+  $("feed").replaceChildren();
+  if (started.delayMs > 0) log(`the feed is ${started.delayMs / 1000}s behind the game`);
+  layout();
+  drawBoard();
+  renderPlayers();
+  renderProgress();
+}
+
+function isOn(i) {
+  return (game.bits[i >> 3] & (1 << (i & 7))) !== 0;
+}
+
+// set turns a bit on, the server never turns one off.
+function set(i) {
+  if (i < 0 || i >= game.size || isOn(i)) return false;
+  game.bits[i >> 3] |= 1 << (i & 7);
+  game.on++;
+  game.flashes.set(i, performance.now());
+  drawCell(i);
+  return true;
+}
+
+function layout() {
+  const canvas = $("canvas");
+  game.cols = Math.ceil(Math.sqrt(game.size));
+  game.cell = Math.max(1, Math.floor(640 / game.cols));
+  canvas.width = game.cols * game.cell;
+  canvas.height = Math.ceil(game.size / game.cols) * game.cell;
+}
+
+const colors = {};
+function color(name) {
+  if (!colors[name]) colors[name] = getComputedStyle(document.documentElement).getPropertyValue(`--${name}`).trim();
+  return colors[name];
+}
+
+function drawCell(i, style) {
+  const ctx = $("canvas").getContext("2d");
+  const x = (i % game.cols) * game.cell;
+  const y = Math.floor(i / game.cols) * game.cell;
+  ctx.fillStyle = style || color(isOn(i) ? "on" : "off");
+  const gap = game.cell > 4 ? 1 : 0;
+  ctx.fillRect(x, y, game.cell - gap, game.cell - gap);
+}
+
+function drawBoard() {
+  const canvas = $("canvas");
+  canvas.getContext("2d").clearRect(0, 0, canvas.width, canvas.height);
+  for (let i = 0; i < game.size; i++) drawCell(i);
+}
+
+// flash fades the bits flipped in the last second
+function flash(now) {
+  if (game) {
+    for (const [i, at] of game.flashes) {
+      if (now - at > 1000) {
+        game.flashes.delete(i);
+        drawCell(i);
+      } else {
+        drawCell(i, color("flash"));
+      }
+    }
+  }
+  requestAnimationFrame(flash);
+}
+requestAnimationFrame(flash);
+
+function renderProgress() {
+  $("progress").value = game.on / game.size;
+  let label = `${game.on} / ${game.size} bits on (${Math.floor((100 * game.on) / game.size)}%)`;
+  if (game.finished) label += game.winner ? `, ${game.winner} won` : ", finished";
+  $("progress-label").textContent = label;
+}
+
+function renderPlayers() {
+  const list = $("players");
+  list.replaceChildren();
+  const ranking = [...game.players.entries()].sort((a, b) => b[1].bits - a[1].bits);
+  for (const [id, p] of ranking) {
+    const li = document.createElement("li");
+    li.textContent = `${p.name}${p.bot ? " (bot)" : ""}${p.left ? " (left)" : ""}: ${p.bits}`;
+    if (id === game.playerId) li.className = "me";
+    list.append(li);
+  }
+}
+
+function log(text, time) {
+  const li = document.createElement("li");
+  const at = time ? new Date(time) : new Date();
+  li.textContent = `${at.toLocaleTimeString()} ${text}`;
+  const feed = $("feed");
+  feed.prepend(li);
+  while (feed.childElementCount > 200) feed.lastElementChild.remove();
+}
+
+function nameOf(playerId) {
+  const p = game.players.get(playerId);
+  return p ? p.name : playerId.slice(0, 8);
+}
+
+function apply(e) {
+  if (e.playerAdded) {
+    const p = e.playerAdded;
+    game.players.set(p.playerId, { name: p.playerName, bot: p.autoPilot, bits: game.players.get(p.playerId)?.bits || 0 });
+    log(`${p.playerName} joined`, e.time);
+  } else if (e.playerRemoved || e.playerKicked) {
+    const id = (e.playerRemoved || e.playerKicked).playerId;
+    const p = game.players.get(id);
+    if (p) p.left = true;
+    log(`${nameOf(id)} ${e.playerKicked ? "was kicked" : "left"}`, e.time);
+  } else if (e.playerMoved) {
+    const m = e.playerMoved;
+    const p = game.players.get(m.playerId);
+    if (p) p.bits++;
+    set(m.index); // our own moves are already on the board
+    log(`${nameOf(m.playerId)} flipped ${m.index}`, e.time);
+  } else if (e.gamePaused) {
+    log("game paused", e.time);
+  } else if (e.gameResumed) {
+    log("game resumed", e.time);
+  } else if (e.chatMessage) {
+    log(`<${e.chatMessage.senderName}> ${e.chatMessage.text}`, e.time);
+  } else if (e.gameFinished) {
+    game.finished = true;
+    game.winner = e.gameFinished.winnerName;
+    log(game.winner ? `game finished, ${game.winner} won` : `game finished, ${e.gameFinished.reason}`, e.time);
+  }
+  renderPlayers();
+  renderProgress();
+}
+
+async function move(index) {
+  if (!game.playerId) return setStatus("you are watching, join the game to play");
+  if (game.finished) return setStatus("the game is finished");
+  try {
+    const moved = await rpc.call("player_move", { gameId: game.id, playerName: game.playerId, index });
+    if (!moved.gameId) setStatus(`move ${index} ignored`);
+    else if (moved.gameStatus.isPaused) setStatus("the game is paused");
+    else if (set(index)) {
+      setStatus(`flipped ${index}`);
+      renderProgress();
+    } else setStatus(`${index} was already on`);
+  } catch (err) {
+    fail(err);
+  }
+}
+
+function indexAt(e) {
+  const canvas = $("canvas");
+  const rect = canvas.getBoundingClientRect();
+  const x = Math.floor(((e.clientX - rect.left) * canvas.width) / rect.width / game.cell);
+  const y = Math.floor(((e.clientY - rect.top) * canvas.height) / rect.height / game.cell);
+  const i = y * game.cols + x;
+  return x < game.cols && i < game.size ? i : -1;
+}
+
+$("canvas").addEventListener("click", (e) => {
+  const i = indexAt(e);
+  if (i >= 0) move(i);
+});
+
+$("canvas").addEventListener("mousemove", (e) => {
+  const i = indexAt(e);
+  $("hover").textContent = i >= 0 ? `bit ${i} ${isOn(i) ? "on" : "off"}` : "";
+});
+
+$("move").addEventListener("submit", (e) => {
+  e.preventDefault();
+  move(Number(new FormData(e.target).get("index")));
+});
+
+$("leave").addEventListener("click", async () => {
+  const left = game;
+  game = null;
+  showLobby();
+  try {
+    await rpc.call("stop_spectating", { gameId: left.id });
+    if (left.playerId) await rpc.call("leave_game", { gameId: left.id, playerName: left.playerId });
+  } catch (err) {
+    fail(err);
+  }
+});
+
+rpc.on("spectator_event", (params) => {
+  if (game && params.gameId === game.id) apply(params.event);
+});
+rpc.on("spectate_finished", (params) => {
+  if (game && params.gameId === game.id) log("feed ended");
+});
+rpc.on("server_shutdown", (params) => setStatus(params.message));
+rpc.on("open", () => ($("connection").textContent = "connected"));
+rpc.on("close", () => ($("connection").textContent = "reconnecting…"));
+rpc.on("reconnected", () => {
+  // the server forgot the old connection, start the feed again
+  if (game) openGame(game.id, game.playerId ? { playerId: game.playerId } : null, game.password).catch(fail);
+  else listGames();
+});
+
+$("name").value = localStorage.getItem("battlebit-name") || "";
+showLobby();
@@ -11,7 +11,9 @@ type GameStatus struct {
 	Status      []byte
 	HasStarted  bool
 	HasFinished bool
-	mutex       sync.Mutex
+	// ones is kept as bits turn on, so a toggle does not scan the board
+	ones  int
+	mutex sync.Mutex
 }
 
 func NewGameStatus(sizeGame int) *GameStatus {
@@ -22,6 +24,29 @@ func NewGameStatus(sizeGame int) *GameStatus {
 		HasFinished: false,
 	}
 }
+
+func RestoreGameStatus(sizeGame int, bits []byte, hasStarted bool, hasFinished bool) *GameStatus {
+	g := NewGameStatus(sizeGame)
+	copy(g.Status, bits)
+	_, g.ones = g.countBits()
+	g.HasStarted = hasStarted
+	g.HasFinished = hasFinished
+	return g
+}
+
+func (g *GameStatus) Bytes() []byte {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	bits := make([]byte, len(g.Status))
+	copy(bits, g.Status)
+	return bits
+}
+func (g *GameStatus) IsOn(pos int) bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.isBitOn(pos)
+}
+
 func (g *GameStatus) isBitOn(pos int) bool {
 	return g.Status[pos>>3]&(1<<(pos&7)) != 0
 }
@@ -30,22 +55,24 @@ func (g *GameStatus) isBitOff(pos int) bool {
 	return g.Status[pos>>3]&(1<<(pos&7)) == 0
 }
 
-func (g *GameStatus) ToggleBit(ctx context.Context, pos int) {
+// ToggleBit turns the bit on and tells whether it was off.
+func (g *GameStatus) ToggleBit(ctx context.Context, pos int) bool {
 	slog := log.GetLogger(ctx)
 
 	g.mutex.Lock()
 	defer g.mutex.Unlock()
 	g.HasStarted = true
 	if g.isBitOn(pos) {
-		return
+		return false
 	}
 	g.Status[pos>>3] ^= (1 << (pos & 7))
-	zeroes, ones := g.countBits()
-	slog.Debug("Bit toggled", "pos", pos, "zeroes", zeroes, "ones", ones)
-	if zeroes == 0 && ones == g.Size {
+	g.ones++
+	slog.Debug("Bit toggled", "pos", pos, "zeroes", g.Size-g.ones, "ones", g.ones)
+	if g.ones == g.Size {
 		g.HasFinished = true
 		slog.Debug("Game finished")
 	}
+	return true
 }
 func (g *GameStatus) countBits() (int, int) {
 	count0, count1 := 0, 0
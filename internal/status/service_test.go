@@ -0,0 +1,32 @@
+package status
+
+import (
+	"context"
+	"testing"
+)
+
+func TestToggleBitFinishes(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name   string
+		status *GameStatus
+		moves  []int
+	}{
+		{name: "new board", status: NewGameStatus(10), moves: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+		{name: "repeated moves", status: NewGameStatus(3), moves: []int{0, 0, 1, 1, 2}},
+		{name: "restored board", status: RestoreGameStatus(10, []byte{0xff, 0x01}, true, false), moves: []int{9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, pos := range tt.moves {
+				if tt.status.HasFinished {
+					t.Fatalf("finished before move %d", i)
+				}
+				tt.status.ToggleBit(ctx, pos)
+			}
+			if !tt.status.HasFinished {
+				t.Error("not finished with every bit on")
+			}
+		})
+	}
+}
@@ -0,0 +1,253 @@
+package storage
+
+import (
+	"battlebit/internal/bb"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const snapshotExt = ".snapshot.json"
+const logExt = ".log.jsonl"
+
+// flushInterval bounds how many buffered events a crash can lose.
+const flushInterval = 200 * time.Millisecond
+
+type FileStore struct {
+	dir     string
+	mutex   sync.Mutex
+	logs    map[string]*eventLog
+	stop    chan struct{}
+	flushed chan struct{}
+}
+
+type eventLog struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating storage dir: %w", err)
+	}
+	slog.Debug("File store opened", "dir", dir)
+	s := &FileStore{
+		dir:     dir,
+		logs:    make(map[string]*eventLog),
+		stop:    make(chan struct{}),
+		flushed: make(chan struct{}),
+	}
+	go s.flushLogs()
+	return s, nil
+}
+
+func (s *FileStore) flushLogs() {
+	defer close(s.flushed)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+		}
+		s.mutex.Lock()
+		for gameId, el := range s.logs {
+			if el.writer.Buffered() == 0 {
+				continue
+			}
+			if err := el.writer.Flush(); err != nil {
+				slog.Error("Error flushing event log", "gameId", gameId, "error", err.Error())
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+func (s *FileStore) AppendEvent(gameId string, event *bb.Event) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	el, err := s.openLog(gameId)
+	if err != nil {
+		return err
+	}
+	p, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := el.writer.Write(append(p, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) SaveSnapshot(snapshot *bb.GameSnapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if el, ok := s.logs[snapshot.GameId]; ok {
+		if err := el.writer.Flush(); err != nil {
+			return err
+		}
+	}
+	p, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.snapshotPath(snapshot.GameId), p)
+}
+
+func (s *FileStore) LoadGames() ([]*Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+snapshotExt))
+	if err != nil {
+		return nil, err
+	}
+	records := make([]*Record, 0, len(paths))
+	for _, path := range paths {
+		p, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		snapshot := new(bb.GameSnapshot)
+		if err := json.Unmarshal(p, snapshot); err != nil {
+			slog.Error("Skipping corrupt snapshot", "path", path, "error", err.Error())
+			continue
+		}
+		events, err := s.readLog(snapshot.GameId)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, &Record{Snapshot: snapshot, Events: events})
+	}
+	slog.Debug("Games loaded from file store", "dir", s.dir, "games", len(records))
+	return records, nil
+}
+
+func (s *FileStore) LoadEvents(gameId string) ([]*bb.Event, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if el, ok := s.logs[gameId]; ok {
+		if err := el.writer.Flush(); err != nil {
+			return nil, err
+		}
+	}
+	events, err := s.readLog(gameId)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("game not found")
+	}
+	return events, nil
+}
+
+func (s *FileStore) RemoveGame(gameId string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if err := s.closeLog(gameId); err != nil {
+		return err
+	}
+	for _, path := range []string{s.snapshotPath(gameId), s.logPath(gameId)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FileStore) Ping() error {
+	info, err := os.Stat(s.dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", s.dir)
+	}
+	return nil
+}
+
+func (s *FileStore) Close() error {
+	close(s.stop)
+	<-s.flushed
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var errs []error
+	for gameId := range s.logs {
+		errs = append(errs, s.closeLog(gameId))
+	}
+	return errors.Join(errs...)
+}
+
+func (s *FileStore) snapshotPath(gameId string) string {
+	return filepath.Join(s.dir, gameId+snapshotExt)
+}
+
+func (s *FileStore) logPath(gameId string) string {
+	return filepath.Join(s.dir, gameId+logExt)
+}
+
+func (s *FileStore) openLog(gameId string) (*eventLog, error) {
+	if el, ok := s.logs[gameId]; ok {
+		return el, nil
+	}
+	file, err := os.OpenFile(s.logPath(gameId), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	el := &eventLog{file: file, writer: bufio.NewWriter(file)}
+	s.logs[gameId] = el
+	return el, nil
+}
+
+func (s *FileStore) closeLog(gameId string) error {
+	el, ok := s.logs[gameId]
+	if !ok {
+		return nil
+	}
+	delete(s.logs, gameId)
+	return errors.Join(el.writer.Flush(), el.file.Close())
+}
+
+func (s *FileStore) readLog(gameId string) ([]*bb.Event, error) {
+	file, err := os.Open(s.logPath(gameId))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	events := make([]*bb.Event, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		event := new(bb.Event)
+		if err := json.Unmarshal([]byte(line), event); err != nil {
+			// A torn write at the tail of the log is expected after a crash.
+			slog.Warn("Stopping at corrupt log entry", "gameId", gameId, "error", err.Error())
+			break
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+func writeFileAtomic(path string, p []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, p, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
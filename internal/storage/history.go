@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"battlebit/internal/history"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const historyFile = "history.jsonl"
+
+func (s *FileStore) AppendHistory(entry *history.Entry) error {
+	p, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	file, err := os.OpenFile(filepath.Join(s.dir, historyFile), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(p, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (s *FileStore) LoadHistory() ([]*history.Entry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	file, err := os.Open(filepath.Join(s.dir, historyFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries := make([]*history.Entry, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		entry := new(history.Entry)
+		if err := json.Unmarshal(scanner.Bytes(), entry); err != nil {
+			// A torn write at the tail is expected after a crash.
+			slog.Warn("Skipping corrupt history line", "error", err.Error())
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"battlebit/internal/rating"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const ratingsFile = "ratings.json"
+
+func (s *FileStore) SaveRatings(snapshot *rating.Snapshot) error {
+	p, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return writeFileAtomic(filepath.Join(s.dir, ratingsFile), p)
+}
+
+func (s *FileStore) LoadRatings() (*rating.Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p, err := os.ReadFile(filepath.Join(s.dir, ratingsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := new(rating.Snapshot)
+	if err := json.Unmarshal(p, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
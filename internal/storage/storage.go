@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"battlebit/internal/bb"
+	"battlebit/internal/history"
+	"battlebit/internal/rating"
+	"battlebit/internal/tournament"
+)
+
+type Record struct {
+	Snapshot *bb.GameSnapshot
+	Events   []*bb.Event
+}
+
+type Store interface {
+	bb.Journal
+	rating.Persister
+	history.Persister
+	tournament.Persister
+	LoadGames() ([]*Record, error)
+	RemoveGame(gameId string) error
+	Ping() error
+	Close() error
+}
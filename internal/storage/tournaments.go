@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"battlebit/internal/tournament"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const tournamentsFile = "tournaments.json"
+
+func (s *FileStore) SaveTournaments(snapshot *tournament.Snapshot) error {
+	p, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return writeFileAtomic(filepath.Join(s.dir, tournamentsFile), p)
+}
+
+func (s *FileStore) LoadTournaments() (*tournament.Snapshot, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	p, err := os.ReadFile(filepath.Join(s.dir, tournamentsFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	snapshot := new(tournament.Snapshot)
+	if err := json.Unmarshal(p, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
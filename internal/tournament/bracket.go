@@ -0,0 +1,145 @@
+package tournament
+
+import "fmt"
+
+// seedOrder returns the seeds of a bracket of size players in slot order, so
+// the best seeds only meet in the last rounds: 1 v 8, 4 v 5, 2 v 7, 3 v 6.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, s := range order {
+			next = append(next, s, len(order)*2+1-s)
+		}
+		order = next
+	}
+	return order
+}
+
+func bracketSize(entrants int) (size int, rounds int) {
+	size = 1
+	for size < entrants {
+		size *= 2
+		rounds++
+	}
+	return size, rounds
+}
+
+func matchId(prefix string, round int, i int) string {
+	return fmt.Sprintf("%s%d-%d", prefix, round, i+1)
+}
+
+// eliminationMatches builds the winners bracket, plus the losers bracket and
+// the grand final when double. Seeds past the last entrant are byes.
+func eliminationMatches(entrants []*Entrant, double bool) []*Match {
+	size, rounds := bracketSize(len(entrants))
+	matches := make([]*Match, 0)
+	for r := 1; r <= rounds; r++ {
+		for i := 0; i < size>>r; i++ {
+			m := &Match{MatchId: matchId("W", r, i), Bracket: BracketWinners, Round: r, Status: MatchPending}
+			if r < rounds {
+				m.WinnerTo = &Link{MatchId: matchId("W", r+1, i/2), Slot: i % 2}
+			} else if double {
+				m.WinnerTo = &Link{MatchId: "GF", Slot: 0}
+			}
+			matches = append(matches, m)
+		}
+	}
+	for i, seed := range seedOrder(size) {
+		slot := &matches[i/2].Slots[i%2]
+		slot.Resolved = true
+		if seed <= len(entrants) {
+			slot.EntrantId = entrants[seed-1].EntrantId
+		}
+	}
+	if !double {
+		return matches
+	}
+	winners := make(map[string]*Match)
+	for _, m := range matches {
+		winners[m.MatchId] = m
+	}
+	if rounds == 1 {
+		winners[matchId("W", 1, 0)].LoserTo = &Link{MatchId: "GF", Slot: 1}
+	}
+	// losers round 2(r-1) takes the losers of winners round r, the odd rounds
+	// in between halve the field
+	lr := 0
+	for r := 2; r <= rounds; r++ {
+		if r == 2 {
+			lr++
+			for i := 0; i < size>>2; i++ {
+				matches = append(matches, &Match{MatchId: matchId("L", lr, i), Bracket: BracketLosers, Round: lr, Status: MatchPending})
+				winners[matchId("W", 1, i*2)].LoserTo = &Link{MatchId: matchId("L", lr, i), Slot: 0}
+				winners[matchId("W", 1, i*2+1)].LoserTo = &Link{MatchId: matchId("L", lr, i), Slot: 1}
+			}
+		} else {
+			lr++
+			for i := 0; i < size>>r; i++ {
+				matches = append(matches, &Match{MatchId: matchId("L", lr, i), Bracket: BracketLosers, Round: lr, Status: MatchPending})
+			}
+			for i := 0; i < size>>(r-1); i++ {
+				matchById(matches, matchId("L", lr-1, i)).WinnerTo = &Link{MatchId: matchId("L", lr, i/2), Slot: i % 2}
+			}
+		}
+		lr++
+		count := size >> r
+		for i := 0; i < count; i++ {
+			matches = append(matches, &Match{MatchId: matchId("L", lr, i), Bracket: BracketLosers, Round: lr, Status: MatchPending})
+			matchById(matches, matchId("L", lr-1, i)).WinnerTo = &Link{MatchId: matchId("L", lr, i), Slot: 0}
+			// dropping losers in reverse order delays rematches
+			winners[matchId("W", r, count-1-i)].LoserTo = &Link{MatchId: matchId("L", lr, i), Slot: 1}
+		}
+	}
+	if lr > 0 {
+		matchById(matches, matchId("L", lr, 0)).WinnerTo = &Link{MatchId: "GF", Slot: 1}
+	}
+	matches = append(matches,
+		&Match{MatchId: "GF", Bracket: BracketFinal, Round: 1, Status: MatchPending},
+		&Match{MatchId: "GF2", Bracket: BracketFinal, Round: 2, Status: MatchPending},
+	)
+	return matches
+}
+
+// roundRobinMatches pairs everyone once with the circle method, one round at a
+// time. With an odd field the entrant paired with nobody sits the round out.
+func roundRobinMatches(entrants []*Entrant) []*Match {
+	ids := make([]string, 0, len(entrants)+1)
+	for _, e := range entrants {
+		ids = append(ids, e.EntrantId)
+	}
+	if len(ids)%2 == 1 {
+		ids = append(ids, "")
+	}
+	n := len(ids)
+	matches := make([]*Match, 0)
+	for r := 1; r < n; r++ {
+		i := 0
+		for k := 0; k < n/2; k++ {
+			a, b := ids[k], ids[n-1-k]
+			if a == "" || b == "" {
+				continue
+			}
+			matches = append(matches, &Match{
+				MatchId: matchId("R", r, i),
+				Bracket: BracketRoundRobin,
+				Round:   r,
+				Slots:   [2]Slot{{EntrantId: a, Resolved: true}, {EntrantId: b, Resolved: true}},
+				Status:  MatchReady,
+			})
+			i++
+		}
+		// keep the first entrant in place and rotate the rest
+		ids = append([]string{ids[0], ids[n-1]}, ids[1:n-1]...)
+	}
+	return matches
+}
+
+func matchById(matches []*Match, matchId string) *Match {
+	for _, m := range matches {
+		if m.MatchId == matchId {
+			return m
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package tournament
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func entrants(n int) []*Entrant {
+	es := make([]*Entrant, 0, n)
+	for i := 1; i <= n; i++ {
+		es = append(es, &Entrant{EntrantId: fmt.Sprintf("e%d", i), Name: fmt.Sprintf("player%d", i), Seed: i})
+	}
+	return es
+}
+
+func TestSeedOrder(t *testing.T) {
+	tests := []struct {
+		size int
+		want []int
+	}{
+		{size: 1, want: []int{1}},
+		{size: 2, want: []int{1, 2}},
+		{size: 4, want: []int{1, 4, 2, 3}},
+		{size: 8, want: []int{1, 8, 4, 5, 2, 7, 3, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.size), func(t *testing.T) {
+			if got := seedOrder(tt.size); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEliminationMatches(t *testing.T) {
+	tests := []struct {
+		entrants int
+		double   bool
+		want     int
+	}{
+		{entrants: 2, want: 1},
+		{entrants: 5, want: 7},
+		{entrants: 8, want: 7},
+		{entrants: 2, double: true, want: 3},
+		{entrants: 4, double: true, want: 7},
+		{entrants: 8, double: true, want: 15},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d double %v", tt.entrants, tt.double), func(t *testing.T) {
+			matches := eliminationMatches(entrants(tt.entrants), tt.double)
+			if len(matches) != tt.want {
+				t.Errorf("%d matches, want %d", len(matches), tt.want)
+			}
+			// every slot is fed by exactly one match or by the seeding
+			feeds := make(map[Link]int)
+			for _, m := range matches {
+				for _, link := range []*Link{m.WinnerTo, m.LoserTo} {
+					if link == nil {
+						continue
+					}
+					if matchById(matches, link.MatchId) == nil {
+						t.Fatalf("%s links to missing match %s", m.MatchId, link.MatchId)
+					}
+					feeds[*link]++
+				}
+			}
+			for _, m := range matches {
+				for slot := range m.Slots {
+					fed := feeds[Link{MatchId: m.MatchId, Slot: slot}]
+					if m.Bracket == BracketWinners && m.Round == 1 {
+						fed++
+					}
+					if m.MatchId == "GF2" {
+						continue
+					}
+					if fed != 1 {
+						t.Errorf("%s slot %d is fed %d times", m.MatchId, slot, fed)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEliminationByes(t *testing.T) {
+	matches := eliminationMatches(entrants(5), false)
+	// seeds 6 to 8 are byes, so the top three seeds have nobody in round 1
+	byes := 0
+	for _, m := range matches[:4] {
+		if m.Slots[1].EntrantId == "" {
+			byes++
+			if m.Slots[0].EntrantId == "" {
+				t.Errorf("%s has two byes", m.MatchId)
+			}
+		}
+	}
+	if byes != 3 {
+		t.Errorf("%d byes, want 3", byes)
+	}
+}
+
+func TestRoundRobinMatches(t *testing.T) {
+	for _, n := range []int{2, 3, 4, 5, 8} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			matches := roundRobinMatches(entrants(n))
+			if want := n * (n - 1) / 2; len(matches) != want {
+				t.Errorf("%d matches, want %d", len(matches), want)
+			}
+			pairs := make(map[[2]string]bool)
+			rounds := make(map[int]map[string]bool)
+			for _, m := range matches {
+				a, b := m.Slots[0].EntrantId, m.Slots[1].EntrantId
+				if a > b {
+					a, b = b, a
+				}
+				if pairs[[2]string{a, b}] {
+					t.Errorf("%s and %s meet twice", a, b)
+				}
+				pairs[[2]string{a, b}] = true
+				if rounds[m.Round] == nil {
+					rounds[m.Round] = make(map[string]bool)
+				}
+				if rounds[m.Round][a] || rounds[m.Round][b] {
+					t.Errorf("round %d has an entrant playing twice", m.Round)
+				}
+				rounds[m.Round][a], rounds[m.Round][b] = true, true
+			}
+		})
+	}
+}
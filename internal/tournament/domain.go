@@ -0,0 +1,171 @@
+package tournament
+
+import "time"
+
+const (
+	FormatSingleElimination = "single_elimination"
+	FormatDoubleElimination = "double_elimination"
+	FormatRoundRobin        = "round_robin"
+)
+
+const (
+	StatusRegistering = "registering"
+	StatusRunning     = "running"
+	StatusFinished    = "finished"
+)
+
+const (
+	MatchPending = "pending"
+	MatchReady   = "ready"
+	MatchPlaying = "playing"
+	MatchDone    = "done"
+)
+
+const (
+	BracketWinners    = "winners"
+	BracketLosers     = "losers"
+	BracketFinal      = "final"
+	BracketRoundRobin = "round_robin"
+)
+
+const GameMode = "tournament"
+
+type Persister interface {
+	SaveTournaments(snapshot *Snapshot) error
+	LoadTournaments() (*Snapshot, error)
+}
+
+type Snapshot struct {
+	Tournaments []*Tournament `json:"tournaments"`
+}
+
+type Tournament struct {
+	TournamentId string     `json:"tournamentId"`
+	Name         string     `json:"name"`
+	Format       string     `json:"format"`
+	Size         int        `json:"size"`
+	AutoPilots   int        `json:"autoPilots"`
+	MaxEntrants  int        `json:"maxEntrants,omitempty"`
+	CreatorId    string     `json:"creatorId,omitempty"`
+	Status       string     `json:"status"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	StartedAt    time.Time  `json:"startedAt,omitempty"`
+	FinishedAt   time.Time  `json:"finishedAt,omitempty"`
+	ChampionId   string     `json:"championId,omitempty"`
+	Entrants     []*Entrant `json:"entrants"`
+	Matches      []*Match   `json:"matches"`
+	Standings    []Standing `json:"standings,omitempty"`
+	Events       []*Event   `json:"events,omitempty"`
+}
+
+// Entrant is a registered player. Owner is the account or anonymous connection
+// that registered it. Owner and Connection are saved but never sent.
+type Entrant struct {
+	EntrantId   string  `json:"entrantId"`
+	Name        string  `json:"name"`
+	AccountId   string  `json:"accountId,omitempty"`
+	Seed        int     `json:"seed,omitempty"`
+	Rating      float64 `json:"rating,omitempty"`
+	BitsFlipped int     `json:"bitsFlipped"`
+	Connection  string  `json:"connection,omitempty"`
+	Owner       string  `json:"owner,omitempty"`
+}
+
+// Slot is one side of a match. It is resolved once its entrant is known, or
+// known to be nobody because the match feeding it was a bye.
+type Slot struct {
+	EntrantId string `json:"entrantId,omitempty"`
+	PlayerId  string `json:"playerId,omitempty"`
+	Resolved  bool   `json:"resolved"`
+}
+
+// Link points at the slot of the match an entrant moves on to.
+type Link struct {
+	MatchId string `json:"matchId"`
+	Slot    int    `json:"slot"`
+}
+
+type Match struct {
+	MatchId  string  `json:"matchId"`
+	Bracket  string  `json:"bracket"`
+	Round    int     `json:"round"`
+	Slots    [2]Slot `json:"slots"`
+	Status   string  `json:"status"`
+	GameId   string  `json:"gameId,omitempty"`
+	WinnerId string  `json:"winnerId,omitempty"`
+	LoserId  string  `json:"loserId,omitempty"`
+	Walkover bool    `json:"walkover,omitempty"`
+	WinnerTo *Link   `json:"winnerTo,omitempty"`
+	LoserTo  *Link   `json:"loserTo,omitempty"`
+}
+
+type Standing struct {
+	EntrantId   string `json:"entrantId"`
+	Name        string `json:"name"`
+	Seed        int    `json:"seed"`
+	Played      int    `json:"played"`
+	Wins        int    `json:"wins"`
+	Losses      int    `json:"losses"`
+	BitsFlipped int    `json:"bitsFlipped"`
+}
+
+type EventType string
+
+const (
+	EventEntrantRegistered  EventType = "entrant_registered"
+	EventEntrantWithdrawn   EventType = "entrant_withdrawn"
+	EventTournamentStarted  EventType = "tournament_started"
+	EventMatchReady         EventType = "match_ready"
+	EventMatchFinished      EventType = "match_finished"
+	EventTournamentFinished EventType = "tournament_finished"
+)
+
+type Event struct {
+	Seq          uint64    `json:"seq"`
+	Time         time.Time `json:"time"`
+	Type         EventType `json:"type"`
+	TournamentId string    `json:"tournamentId"`
+	EntrantId    string    `json:"entrantId,omitempty"`
+	Match        *Match    `json:"match,omitempty"`
+}
+
+// Pairing is a ready match the hub has to create a game for.
+type Pairing struct {
+	TournamentId string
+	MatchId      string
+	Size         int
+	AutoPilots   int
+	CreatorId    string
+	Entrants     [2]Entrant
+}
+
+type CreateTournament struct {
+	Name        string `json:"name"`
+	Format      string `json:"format"`
+	Size        int    `json:"size"`
+	AutoPilots  int    `json:"autoPilots"`
+	MaxEntrants int    `json:"maxEntrants"`
+	CreatorId   string `json:"-"`
+}
+
+type TournamentId struct {
+	TournamentId string `json:"tournamentId"`
+}
+
+type Register struct {
+	TournamentId string `json:"tournamentId"`
+	PlayerName   string `json:"playerName"`
+	AccountId    string `json:"-"`
+	Connection   string `json:"-"`
+	Owner        string `json:"-"`
+}
+
+type Summary struct {
+	TournamentId string    `json:"tournamentId"`
+	Name         string    `json:"name"`
+	Format       string    `json:"format"`
+	Status       string    `json:"status"`
+	Entrants     int       `json:"entrants"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ChampionId   string    `json:"championId,omitempty"`
+}
@@ -0,0 +1,605 @@
+package tournament
+
+import (
+	"battlebit/internal/bb"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const MaxEntrants = 256
+
+type Registry struct {
+	mutex       sync.Mutex
+	tournaments map[string]*Tournament
+	store       Persister
+}
+
+// NewRegistry loads the tournaments from store, which may be nil to keep them
+// in memory only.
+func NewRegistry(store Persister) *Registry {
+	r := &Registry{tournaments: make(map[string]*Tournament), store: store}
+	if store == nil {
+		return r
+	}
+	snapshot, err := store.LoadTournaments()
+	if err != nil {
+		slog.Error("Error loading tournaments", "error", err.Error())
+		return r
+	}
+	if snapshot == nil {
+		return r
+	}
+	for _, t := range snapshot.Tournaments {
+		for _, e := range t.Entrants {
+			// saved before owners were kept
+			if e.Owner == "" {
+				e.Owner = e.AccountId
+			}
+			if e.Owner == "" {
+				e.Owner = "anonymous:" + e.EntrantId
+			}
+		}
+		r.tournaments[t.TournamentId] = t
+	}
+	slog.Debug("Tournaments loaded", "tournaments", len(r.tournaments))
+	return r
+}
+
+// save must be called with mutex held.
+func (r *Registry) save() {
+	if r.store == nil {
+		return
+	}
+	snapshot := &Snapshot{Tournaments: make([]*Tournament, 0, len(r.tournaments))}
+	for _, t := range r.tournaments {
+		snapshot.Tournaments = append(snapshot.Tournaments, t)
+	}
+	if err := r.store.SaveTournaments(snapshot); err != nil {
+		slog.Error("Error saving tournaments", "error", err.Error())
+	}
+}
+
+func (r *Registry) Create(ct CreateTournament) (*Tournament, error) {
+	switch ct.Format {
+	case "":
+		ct.Format = FormatSingleElimination
+	case FormatSingleElimination, FormatDoubleElimination, FormatRoundRobin:
+	default:
+		return nil, fmt.Errorf("unknown format %q, want single_elimination, double_elimination or round_robin", ct.Format)
+	}
+	if ct.Size < 1 {
+		return nil, fmt.Errorf("size must be positive")
+	}
+	if ct.AutoPilots < 0 {
+		return nil, fmt.Errorf("autoPilots can not be negative")
+	}
+	if ct.MaxEntrants < 0 || ct.MaxEntrants > MaxEntrants {
+		return nil, fmt.Errorf("maxEntrants must be between 0 and %d", MaxEntrants)
+	}
+	if ct.Name == "" {
+		ct.Name = "Tournament"
+	}
+	t := &Tournament{
+		TournamentId: uuid.New().String(),
+		Name:         ct.Name,
+		Format:       ct.Format,
+		Size:         ct.Size,
+		AutoPilots:   ct.AutoPilots,
+		MaxEntrants:  ct.MaxEntrants,
+		CreatorId:    ct.CreatorId,
+		Status:       StatusRegistering,
+		CreatedAt:    time.Now(),
+		Entrants:     make([]*Entrant, 0),
+		Matches:      make([]*Match, 0),
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.tournaments[t.TournamentId] = t
+	r.save()
+	return t.clone(), nil
+}
+
+// tournament must be called with mutex held.
+func (r *Registry) tournament(tournamentId string) (*Tournament, error) {
+	t, ok := r.tournaments[tournamentId]
+	if !ok {
+		return nil, fmt.Errorf("tournament not found")
+	}
+	return t, nil
+}
+
+func (r *Registry) Get(tournamentId string) (*Tournament, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, err := r.tournament(tournamentId)
+	if err != nil {
+		return nil, err
+	}
+	return t.clone(), nil
+}
+
+func (r *Registry) List() []*Summary {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	summaries := make([]*Summary, 0, len(r.tournaments))
+	for _, t := range r.tournaments {
+		summaries = append(summaries, &Summary{
+			TournamentId: t.TournamentId,
+			Name:         t.Name,
+			Format:       t.Format,
+			Status:       t.Status,
+			Entrants:     len(t.Entrants),
+			CreatedAt:    t.CreatedAt,
+			ChampionId:   t.ChampionId,
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].CreatedAt.After(summaries[j].CreatedAt) })
+	return summaries
+}
+
+func (r *Registry) Register(reg Register) (*Entrant, []*Event, error) {
+	if reg.PlayerName == "" {
+		return nil, nil, fmt.Errorf("playerName is required")
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, err := r.tournament(reg.TournamentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.Status != StatusRegistering {
+		return nil, nil, fmt.Errorf("registration is closed")
+	}
+	if t.MaxEntrants > 0 && len(t.Entrants) >= t.MaxEntrants {
+		return nil, nil, fmt.Errorf("tournament is full")
+	}
+	for _, e := range t.Entrants {
+		if e.Owner == reg.Owner {
+			return nil, nil, fmt.Errorf("already registered as %s", e.EntrantId)
+		}
+	}
+	e := &Entrant{
+		EntrantId:  uuid.New().String(),
+		Name:       reg.PlayerName,
+		AccountId:  reg.AccountId,
+		Connection: reg.Connection,
+		Owner:      reg.Owner,
+	}
+	t.Entrants = append(t.Entrants, e)
+	event := t.record(&Event{Type: EventEntrantRegistered, EntrantId: e.EntrantId})
+	r.save()
+	return e.clone(), []*Event{event}, nil
+}
+
+func (r *Registry) Withdraw(tournamentId string, owner string) ([]*Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, err := r.tournament(tournamentId)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != StatusRegistering {
+		return nil, fmt.Errorf("tournament has already started")
+	}
+	for i, e := range t.Entrants {
+		if e.Owner == owner {
+			t.Entrants = append(t.Entrants[:i], t.Entrants[i+1:]...)
+			event := t.record(&Event{Type: EventEntrantWithdrawn, EntrantId: e.EntrantId})
+			r.save()
+			return []*Event{event}, nil
+		}
+	}
+	return nil, fmt.Errorf("not registered")
+}
+
+func (r *Registry) CreatorId(tournamentId string) (string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, err := r.tournament(tournamentId)
+	if err != nil {
+		return "", err
+	}
+	return t.CreatorId, nil
+}
+
+// Start closes registration and seeds the bracket by rating, highest first,
+// keeping registration order between equal ratings.
+func (r *Registry) Start(tournamentId string, rating func(accountId string) float64) ([]*Event, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, err := r.tournament(tournamentId)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != StatusRegistering {
+		return nil, fmt.Errorf("tournament has already started")
+	}
+	if len(t.Entrants) < 2 {
+		return nil, fmt.Errorf("a tournament needs at least 2 entrants")
+	}
+	for _, e := range t.Entrants {
+		if e.AccountId != "" {
+			e.Rating = rating(e.AccountId)
+		}
+	}
+	sort.SliceStable(t.Entrants, func(i, j int) bool { return t.Entrants[i].Rating > t.Entrants[j].Rating })
+	for i, e := range t.Entrants {
+		e.Seed = i + 1
+	}
+	switch t.Format {
+	case FormatRoundRobin:
+		t.Matches = roundRobinMatches(t.Entrants)
+	default:
+		t.Matches = eliminationMatches(t.Entrants, t.Format == FormatDoubleElimination)
+	}
+	t.Status = StatusRunning
+	t.StartedAt = time.Now()
+	events := []*Event{t.record(&Event{Type: EventTournamentStarted})}
+	for _, m := range t.Matches {
+		events = append(events, t.resolve(m)...)
+	}
+	t.Standings = t.standings()
+	r.save()
+	return events, nil
+}
+
+// Pairings reserves every ready match whose entrants are not playing another
+// one, for the hub to create its game.
+func (r *Registry) Pairings() []*Pairing {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	pairings := make([]*Pairing, 0)
+	for _, t := range r.tournaments {
+		if t.Status != StatusRunning {
+			continue
+		}
+		busy := make(map[string]bool)
+		for _, m := range t.Matches {
+			if m.Status == MatchPlaying {
+				busy[m.Slots[0].EntrantId] = true
+				busy[m.Slots[1].EntrantId] = true
+			}
+		}
+		for _, m := range t.Matches {
+			a, b := m.Slots[0].EntrantId, m.Slots[1].EntrantId
+			if m.Status != MatchReady || busy[a] || busy[b] {
+				continue
+			}
+			busy[a], busy[b] = true, true
+			m.Status = MatchPlaying
+			pairings = append(pairings, &Pairing{
+				TournamentId: t.TournamentId,
+				MatchId:      m.MatchId,
+				Size:         t.Size,
+				AutoPilots:   t.AutoPilots,
+				CreatorId:    t.CreatorId,
+				Entrants:     [2]Entrant{*t.entrant(a), *t.entrant(b)},
+			})
+		}
+	}
+	return pairings
+}
+
+// Assign records the game created for a pairing and the player ids of its
+// entrants.
+func (r *Registry) Assign(p *Pairing, gameId string, playerIds [2]string) []*Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, m := r.match(p.TournamentId, p.MatchId)
+	if m == nil {
+		return nil
+	}
+	m.GameId = gameId
+	m.Slots[0].PlayerId = playerIds[0]
+	m.Slots[1].PlayerId = playerIds[1]
+	event := t.record(&Event{Type: EventMatchReady, Match: m.clone()})
+	r.save()
+	return []*Event{event}
+}
+
+// Release puts a reserved match back to ready, to be paired again.
+func (r *Registry) Release(p *Pairing) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, m := r.match(p.TournamentId, p.MatchId); m != nil && m.Status == MatchPlaying {
+		m.Status = MatchReady
+	}
+}
+
+// ReleaseGame replays the match played in a game that went away unfinished.
+func (r *Registry) ReleaseGame(gameId string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_, m := r.gameMatch(gameId)
+	if m == nil {
+		return false
+	}
+	m.reset()
+	r.save()
+	return true
+}
+
+// Report advances the winner of the match played in the game. The winning
+// entrant is the game winner, or when an autopilot won, the entrant that
+// stayed and flipped the most bits, then the better seed. Aborted games are
+// played again.
+func (r *Registry) Report(result *bb.GameResult) ([]*Event, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	t, m := r.gameMatch(result.GameId)
+	if m == nil {
+		return nil, false
+	}
+	events := t.report(m, result)
+	r.save()
+	return events, true
+}
+
+// Reconcile settles the matches a restart left playing without a live game:
+// the finished ones advance their winner from result, the others are played
+// again.
+func (r *Registry) Reconcile(live func(gameId string) bool, result func(gameId string) *bb.GameResult) []*Event {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	events := make([]*Event, 0)
+	changed := false
+	for _, t := range r.tournaments {
+		for _, m := range t.Matches {
+			if t.Status != StatusRunning || m.Status != MatchPlaying || (m.GameId != "" && live(m.GameId)) {
+				continue
+			}
+			changed = true
+			if m.GameId == "" {
+				m.reset()
+				continue
+			}
+			if res := result(m.GameId); res != nil {
+				events = append(events, t.report(m, res)...)
+				continue
+			}
+			m.reset()
+		}
+	}
+	if changed {
+		r.save()
+	}
+	return events
+}
+
+func (t *Tournament) report(m *Match, result *bb.GameResult) []*Event {
+	if result.Reason != bb.FinishReasonCompleted {
+		m.reset()
+		return nil
+	}
+	played := make(map[string]bb.Participant)
+	for _, p := range result.Players {
+		played[p.PlayerId] = p
+	}
+	for _, slot := range m.Slots {
+		t.entrant(slot.EntrantId).BitsFlipped += played[slot.PlayerId].BitsFlipped
+	}
+	winner := matchWinner(t, m, result.WinnerId, played)
+	events := t.finishMatch(m, m.Slots[winner].EntrantId, m.Slots[1-winner].EntrantId, false)
+	t.Standings = t.standings()
+	return events
+}
+
+func matchWinner(t *Tournament, m *Match, winnerId string, played map[string]bb.Participant) int {
+	for i, s := range m.Slots {
+		if s.PlayerId != "" && s.PlayerId == winnerId {
+			return i
+		}
+	}
+	a, b := played[m.Slots[0].PlayerId], played[m.Slots[1].PlayerId]
+	if a.Left != b.Left {
+		if a.Left {
+			return 1
+		}
+		return 0
+	}
+	if a.BitsFlipped != b.BitsFlipped {
+		if a.BitsFlipped > b.BitsFlipped {
+			return 0
+		}
+		return 1
+	}
+	if t.entrant(m.Slots[0].EntrantId).Seed < t.entrant(m.Slots[1].EntrantId).Seed {
+		return 0
+	}
+	return 1
+}
+
+// match must be called with mutex held.
+func (r *Registry) match(tournamentId string, matchId string) (*Tournament, *Match) {
+	t, ok := r.tournaments[tournamentId]
+	if !ok {
+		return nil, nil
+	}
+	return t, matchById(t.Matches, matchId)
+}
+
+// gameMatch must be called with mutex held.
+func (r *Registry) gameMatch(gameId string) (*Tournament, *Match) {
+	for _, t := range r.tournaments {
+		if t.Status != StatusRunning {
+			continue
+		}
+		for _, m := range t.Matches {
+			if m.Status == MatchPlaying && m.GameId == gameId {
+				return t, m
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (t *Tournament) record(event *Event) *Event {
+	event.Seq = uint64(len(t.Events)) + 1
+	event.Time = time.Now()
+	event.TournamentId = t.TournamentId
+	t.Events = append(t.Events, event)
+	return event
+}
+
+func (t *Tournament) entrant(entrantId string) *Entrant {
+	for _, e := range t.Entrants {
+		if e.EntrantId == entrantId {
+			return e
+		}
+	}
+	return &Entrant{EntrantId: entrantId}
+}
+
+// resolve starts a match once both slots are known, or settles it right away
+// when a slot is a bye.
+func (t *Tournament) resolve(m *Match) []*Event {
+	if m.Status != MatchPending || !m.Slots[0].Resolved || !m.Slots[1].Resolved {
+		return nil
+	}
+	a, b := m.Slots[0].EntrantId, m.Slots[1].EntrantId
+	switch {
+	case a != "" && b != "":
+		m.Status = MatchReady
+		return nil
+	case a != "":
+		return t.finishMatch(m, a, "", true)
+	default:
+		return t.finishMatch(m, b, "", true)
+	}
+}
+
+func (t *Tournament) finishMatch(m *Match, winnerId string, loserId string, walkover bool) []*Event {
+	m.Status = MatchDone
+	m.WinnerId = winnerId
+	m.LoserId = loserId
+	m.Walkover = walkover
+	events := make([]*Event, 0)
+	if winnerId != "" && !walkover {
+		events = append(events, t.record(&Event{Type: EventMatchFinished, EntrantId: winnerId, Match: m.clone()}))
+	}
+	switch {
+	case m.MatchId == "GF" && loserId != "" && winnerId == m.Slots[1].EntrantId:
+		// the losers bracket champion has to beat the unbeaten one twice
+		reset := matchById(t.Matches, "GF2")
+		reset.Slots = [2]Slot{{EntrantId: loserId, Resolved: true}, {EntrantId: winnerId, Resolved: true}}
+		events = append(events, t.resolve(reset)...)
+		return events
+	case m.MatchId == "GF":
+		matchById(t.Matches, "GF2").Status = MatchDone
+		return append(events, t.finish(winnerId)...)
+	}
+	for _, next := range []struct {
+		link      *Link
+		entrantId string
+	}{{m.WinnerTo, winnerId}, {m.LoserTo, loserId}} {
+		if next.link == nil {
+			continue
+		}
+		nm := matchById(t.Matches, next.link.MatchId)
+		nm.Slots[next.link.Slot] = Slot{EntrantId: next.entrantId, Resolved: true}
+		events = append(events, t.resolve(nm)...)
+	}
+	if m.WinnerTo == nil && m.Bracket != BracketRoundRobin {
+		return append(events, t.finish(winnerId)...)
+	}
+	if m.Bracket == BracketRoundRobin && t.allDone() {
+		t.Standings = t.standings()
+		return append(events, t.finish(t.Standings[0].EntrantId)...)
+	}
+	return events
+}
+
+func (t *Tournament) allDone() bool {
+	for _, m := range t.Matches {
+		if m.Status != MatchDone {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tournament) finish(championId string) []*Event {
+	t.Status = StatusFinished
+	t.FinishedAt = time.Now()
+	t.ChampionId = championId
+	return []*Event{t.record(&Event{Type: EventTournamentFinished, EntrantId: championId})}
+}
+
+// standings ranks entrants by wins, bits flipped and seed.
+func (t *Tournament) standings() []Standing {
+	rows := make(map[string]*Standing)
+	standings := make([]Standing, 0, len(t.Entrants))
+	for _, e := range t.Entrants {
+		rows[e.EntrantId] = &Standing{EntrantId: e.EntrantId, Name: e.Name, Seed: e.Seed, BitsFlipped: e.BitsFlipped}
+	}
+	for _, m := range t.Matches {
+		if m.Status != MatchDone || m.Walkover || m.WinnerId == "" {
+			continue
+		}
+		rows[m.WinnerId].Played++
+		rows[m.WinnerId].Wins++
+		rows[m.LoserId].Played++
+		rows[m.LoserId].Losses++
+	}
+	for _, e := range t.Entrants {
+		standings = append(standings, *rows[e.EntrantId])
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Wins != b.Wins {
+			return a.Wins > b.Wins
+		}
+		if a.BitsFlipped != b.BitsFlipped {
+			return a.BitsFlipped > b.BitsFlipped
+		}
+		return a.Seed < b.Seed
+	})
+	return standings
+}
+
+func (t *Tournament) clone() *Tournament {
+	c := *t
+	c.Entrants = make([]*Entrant, 0, len(t.Entrants))
+	for _, e := range t.Entrants {
+		c.Entrants = append(c.Entrants, e.clone())
+	}
+	c.Matches = make([]*Match, 0, len(t.Matches))
+	for _, m := range t.Matches {
+		c.Matches = append(c.Matches, m.clone())
+	}
+	c.Standings = append([]Standing(nil), t.Standings...)
+	c.Events = append([]*Event(nil), t.Events...)
+	return &c
+}
+
+// clone leaves out who owns the entrant.
+func (e *Entrant) clone() *Entrant {
+	c := *e
+	c.Connection, c.Owner = "", ""
+	return &c
+}
+
+// reset puts the match back to ready, to be played in a new game.
+func (m *Match) reset() {
+	m.Status = MatchReady
+	m.GameId = ""
+	m.Slots[0].PlayerId, m.Slots[1].PlayerId = "", ""
+}
+
+func (m *Match) clone() *Match {
+	c := *m
+	if m.WinnerTo != nil {
+		link := *m.WinnerTo
+		c.WinnerTo = &link
+	}
+	if m.LoserTo != nil {
+		link := *m.LoserTo
+		c.LoserTo = &link
+	}
+	return &c
+}
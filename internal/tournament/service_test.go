@@ -0,0 +1,200 @@
+package tournament
+
+import (
+	"battlebit/internal/bb"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+type memoryStore struct {
+	saved []byte
+}
+
+func (s *memoryStore) SaveTournaments(snapshot *Snapshot) error {
+	p, err := json.Marshal(snapshot)
+	s.saved = p
+	return err
+}
+
+func (s *memoryStore) LoadTournaments() (*Snapshot, error) {
+	snapshot := new(Snapshot)
+	return snapshot, json.Unmarshal(s.saved, snapshot)
+}
+
+func startTournament(t *testing.T, r *Registry, format string, n int) string {
+	t.Helper()
+	tr, err := r.Create(CreateTournament{Format: format, Size: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		name := fmt.Sprintf("player%d", i)
+		if _, _, err := r.Register(Register{TournamentId: tr.TournamentId, PlayerName: name, Owner: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := r.Start(tr.TournamentId, func(string) float64 { return 0 }); err != nil {
+		t.Fatal(err)
+	}
+	return tr.TournamentId
+}
+
+// pair assigns a game to every ready match, with players named after the
+// entrants.
+func pair(r *Registry) []*Pairing {
+	pairings := r.Pairings()
+	for _, p := range pairings {
+		r.Assign(p, p.MatchId+"-game", [2]string{p.Entrants[0].EntrantId, p.Entrants[1].EntrantId})
+	}
+	return pairings
+}
+
+// play finishes tournaments by having winner pick the winning entrant of each
+// pairing, and returns how many matches were played.
+func play(t *testing.T, r *Registry, winner func(p *Pairing) int) int {
+	t.Helper()
+	played := 0
+	for {
+		pairings := pair(r)
+		if len(pairings) == 0 {
+			return played
+		}
+		for _, p := range pairings {
+			result := &bb.GameResult{GameId: p.MatchId + "-game", Reason: bb.FinishReasonCompleted, WinnerId: p.Entrants[winner(p)].EntrantId}
+			if _, ok := r.Report(result); !ok {
+				t.Fatalf("match %s not found", p.MatchId)
+			}
+			played++
+		}
+	}
+}
+
+func bestSeed(p *Pairing) int {
+	if p.Entrants[0].Seed < p.Entrants[1].Seed {
+		return 0
+	}
+	return 1
+}
+
+func TestSingleElimination(t *testing.T) {
+	r := NewRegistry(nil)
+	id := startTournament(t, r, FormatSingleElimination, 5)
+	if played := play(t, r, bestSeed); played != 4 {
+		t.Errorf("%d matches played, want 4 with 3 byes", played)
+	}
+	tr, _ := r.Get(id)
+	if tr.Status != StatusFinished || tr.ChampionId != tr.Entrants[0].EntrantId {
+		t.Errorf("status %s champion %s, want the top seed to win", tr.Status, tr.ChampionId)
+	}
+}
+
+func TestDoubleEliminationReset(t *testing.T) {
+	r := NewRegistry(nil)
+	id := startTournament(t, r, FormatDoubleElimination, 2)
+	tr, _ := r.Get(id)
+	top := tr.Entrants[0].EntrantId
+	// the top seed wins the winners bracket, then loses twice in the final
+	played := play(t, r, func(p *Pairing) int {
+		if p.MatchId == "W1-1" {
+			return bestSeed(p)
+		}
+		return 1 - bestSeed(p)
+	})
+	if played != 3 {
+		t.Errorf("%d matches played, want 3 with the reset", played)
+	}
+	tr, _ = r.Get(id)
+	if tr.Status != StatusFinished || tr.ChampionId == top {
+		t.Errorf("status %s champion %s, want the losers bracket champion", tr.Status, tr.ChampionId)
+	}
+}
+
+func TestRoundRobinStandings(t *testing.T) {
+	r := NewRegistry(nil)
+	id := startTournament(t, r, FormatRoundRobin, 4)
+	if played := play(t, r, bestSeed); played != 6 {
+		t.Errorf("%d matches played, want 6", played)
+	}
+	tr, _ := r.Get(id)
+	for i, s := range tr.Standings {
+		if s.Seed != i+1 || s.Wins != 3-i || s.Played != 3 {
+			t.Errorf("standing %d is seed %d with %d wins of %d", i+1, s.Seed, s.Wins, s.Played)
+		}
+	}
+	if tr.ChampionId != tr.Standings[0].EntrantId {
+		t.Errorf("champion %s, want %s", tr.ChampionId, tr.Standings[0].EntrantId)
+	}
+}
+
+func TestAbortedMatchIsReplayed(t *testing.T) {
+	r := NewRegistry(nil)
+	id := startTournament(t, r, FormatSingleElimination, 2)
+	p := pair(r)[0]
+	if _, ok := r.Report(&bb.GameResult{GameId: p.MatchId + "-game", Reason: bb.FinishReasonShutdown}); !ok {
+		t.Fatal("match not found")
+	}
+	tr, _ := r.Get(id)
+	if m := tr.Matches[0]; m.Status != MatchReady || m.GameId != "" {
+		t.Errorf("match %s in game %q, want it ready again", m.Status, m.GameId)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	r := NewRegistry(nil)
+	id := startTournament(t, r, FormatSingleElimination, 8)
+	pairings := pair(r)
+	if len(pairings) != 4 {
+		t.Fatalf("%d pairings, want 4", len(pairings))
+	}
+	live := map[string]bool{"W1-1-game": true}
+	results := map[string]*bb.GameResult{
+		"W1-2-game": {GameId: "W1-2-game", Reason: bb.FinishReasonCompleted, WinnerId: pairings[1].Entrants[1].EntrantId},
+		"W1-3-game": {GameId: "W1-3-game", Reason: bb.FinishReasonShutdown},
+	}
+	events := r.Reconcile(
+		func(gameId string) bool { return live[gameId] },
+		func(gameId string) *bb.GameResult { return results[gameId] },
+	)
+	if len(events) != 1 || events[0].Type != EventMatchFinished {
+		t.Errorf("%d events, want the finished match", len(events))
+	}
+	tr, _ := r.Get(id)
+	want := map[string]string{"W1-1": MatchPlaying, "W1-2": MatchDone, "W1-3": MatchReady, "W1-4": MatchReady}
+	for matchId, status := range want {
+		if m := matchById(tr.Matches, matchId); m.Status != status {
+			t.Errorf("%s is %s, want %s", matchId, m.Status, status)
+		}
+	}
+	if m := matchById(tr.Matches, "W1-2"); m.WinnerId != pairings[1].Entrants[1].EntrantId {
+		t.Errorf("W1-2 won by %s, want the winner in the history", m.WinnerId)
+	}
+}
+
+func TestOwnerSurvivesRestart(t *testing.T) {
+	store := &memoryStore{}
+	r := NewRegistry(store)
+	tr, err := r.Create(CreateTournament{Size: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entrant, _, err := r.Register(Register{TournamentId: tr.TournamentId, PlayerName: "anon", Connection: "session-1", Owner: "anonymous:session-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entrant.Owner != "" || entrant.Connection != "" {
+		t.Errorf("registration returned owner %q and connection %q", entrant.Owner, entrant.Connection)
+	}
+
+	restarted := NewRegistry(store)
+	got, _ := restarted.Get(tr.TournamentId)
+	if e := got.Entrants[0]; e.Owner != "" || e.Connection != "" {
+		t.Errorf("tournament shows owner %q and connection %q", e.Owner, e.Connection)
+	}
+	if _, err := restarted.Withdraw(tr.TournamentId, ""); err == nil {
+		t.Error("withdrew with an empty owner")
+	}
+	if _, err := restarted.Withdraw(tr.TournamentId, "anonymous:session-1"); err != nil {
+		t.Errorf("owner can not withdraw after a restart: %v", err)
+	}
+}
@@ -0,0 +1,265 @@
+// Package client is a Go client for the battlebit JSON-RPC API served on /ws.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type Options struct {
+	// Token is sent on the upgrade request, like a later Authenticate.
+	Token  string
+	Header http.Header
+	Dialer *websocket.Dialer
+	// MinBackoff and MaxBackoff bound the wait between reconnect attempts.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// EventBuffer is the size of the events channel. Events that do not fit
+	// are dropped rather than holding up responses.
+	EventBuffer int
+}
+
+// Client sends requests over one websocket at a time and matches responses
+// by id, so it is safe for concurrent use. When the connection is lost it
+// dials again until Close.
+type Client struct {
+	url     string
+	opts    Options
+	ctx     context.Context
+	cancel  context.CancelFunc
+	events  chan *Event
+	nextId  atomic.Uint64
+	dropped atomic.Uint64
+
+	mutex   sync.Mutex
+	conn    *websocket.Conn
+	token   string
+	pending map[uint64]chan *message
+
+	writeMutex sync.Mutex
+}
+
+// Dial connects to url, e.g. ws://localhost:8080/ws.
+func Dial(ctx context.Context, url string, opts Options) (*Client, error) {
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(30*time.Second, opts.MinBackoff)
+	}
+	if opts.EventBuffer <= 0 {
+		opts.EventBuffer = 64
+	}
+	c := &Client{
+		url:     url,
+		opts:    opts,
+		events:  make(chan *Event, opts.EventBuffer),
+		token:   opts.Token,
+		pending: make(map[uint64]chan *message),
+	}
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.conn = conn
+	go c.run(conn)
+	return c, nil
+}
+
+// Events returns the notifications pushed by the server. It is closed after
+// Close.
+func (c *Client) Events() <-chan *Event {
+	return c.events
+}
+
+// Dropped counts the events lost because the events channel was full.
+func (c *Client) Dropped() uint64 {
+	return c.dropped.Load()
+}
+
+func (c *Client) Close() error {
+	c.cancel()
+	c.mutex.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.mutex.Unlock()
+	if conn == nil {
+		return nil
+	}
+	c.writeMutex.Lock()
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMutex.Unlock()
+	return conn.Close()
+}
+
+// Call sends method with params and decodes the result into result, which
+// may be nil. Server errors are returned as *Error.
+func (c *Client) Call(ctx context.Context, method string, params any, result any) error {
+	id := c.nextId.Add(1)
+	ch := make(chan *message, 1)
+	c.mutex.Lock()
+	conn := c.conn
+	if c.ctx.Err() != nil {
+		c.mutex.Unlock()
+		return ErrClosed
+	}
+	if conn == nil {
+		c.mutex.Unlock()
+		return ErrDisconnected
+	}
+	c.pending[id] = ch
+	c.mutex.Unlock()
+
+	p, err := json.Marshal(&request{JSONRPC: "2.0", Method: method, Params: params, ID: id})
+	if err != nil {
+		c.forget(id)
+		return err
+	}
+	c.writeMutex.Lock()
+	err = conn.WriteMessage(websocket.TextMessage, p)
+	c.writeMutex.Unlock()
+	if err != nil {
+		c.forget(id)
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+	select {
+	case msg, ok := <-ch:
+		if !ok {
+			if c.ctx.Err() != nil {
+				return ErrClosed
+			}
+			return ErrDisconnected
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil || len(msg.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(msg.Result, result)
+	case <-ctx.Done():
+		c.forget(id)
+		return ctx.Err()
+	}
+}
+
+func (c *Client) forget(id uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.pending, id)
+}
+
+func (c *Client) dial(ctx context.Context) (*websocket.Conn, error) {
+	header := c.opts.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	c.mutex.Lock()
+	token := c.token
+	c.mutex.Unlock()
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+	conn, _, err := c.opts.Dialer.DialContext(ctx, c.url, header)
+	return conn, err
+}
+
+// run reads the connection until it fails, then replaces it until Close.
+func (c *Client) run(conn *websocket.Conn) {
+	defer close(c.events)
+	for {
+		c.read(conn)
+		c.drop(conn)
+		conn = c.reconnect()
+		if conn == nil {
+			return
+		}
+		c.emit(&Event{Method: EventReconnected})
+	}
+}
+
+func (c *Client) read(conn *websocket.Conn) {
+	for {
+		_, p, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		msg := new(message)
+		if err := json.Unmarshal(p, msg); err != nil {
+			// the server greets every connection in plain text
+			continue
+		}
+		if msg.Method != "" {
+			c.emit(&Event{Method: msg.Method, Params: msg.Params})
+			continue
+		}
+		if msg.ID == nil {
+			continue
+		}
+		c.mutex.Lock()
+		ch, ok := c.pending[*msg.ID]
+		delete(c.pending, *msg.ID)
+		c.mutex.Unlock()
+		if ok {
+			ch <- msg
+		}
+	}
+}
+
+// drop fails the calls still waiting on a lost connection.
+func (c *Client) drop(conn *websocket.Conn) {
+	c.mutex.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	c.mutex.Unlock()
+	conn.Close()
+}
+
+func (c *Client) reconnect() *websocket.Conn {
+	backoff := c.opts.MinBackoff
+	for {
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		conn, err := c.dial(c.ctx)
+		if err != nil {
+			backoff = min(backoff*2, c.opts.MaxBackoff)
+			continue
+		}
+		c.mutex.Lock()
+		if c.ctx.Err() != nil {
+			c.mutex.Unlock()
+			conn.Close()
+			return nil
+		}
+		c.conn = conn
+		c.mutex.Unlock()
+		return conn
+	}
+}
+
+func (c *Client) emit(e *Event) {
+	select {
+	case c.events <- e:
+	default:
+		c.dropped.Add(1)
+	}
+}
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	methodAuthenticate = "authenticate"
+	methodCreateGame   = "create_game"
+	methodListGames    = "list_game"
+	methodRemoveGame   = "remove_game"
+	methodJoinGame     = "join_game"
+	methodLeaveGame    = "leave_game"
+	methodPlayerMove   = "player_move"
+	methodGameMetrics  = "game_metrics"
+	methodSpectate     = "spectate_game"
+	methodStopSpectate = "stop_spectating"
+)
+
+const (
+	EventMatchFound       = "match_found"
+	EventTournamentEvent  = "tournament_event"
+	EventChatMessage      = "chat_message"
+	EventSpectatorEvent   = "spectator_event"
+	EventSpectateFinished = "spectate_finished"
+	EventReplayEvent      = "replay_event"
+	EventServerShutdown   = "server_shutdown"
+	// EventReconnected is sent by the client itself after a new connection
+	// replaced a lost one. The server forgot the old connection, so games,
+	// queues and watches have to be joined again.
+	EventReconnected = "reconnected"
+)
+
+var (
+	ErrClosed       = errors.New("client closed")
+	ErrDisconnected = errors.New("connection lost")
+)
+
+// Error is an error response of the server. Code follows HTTP status codes.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+}
+
+// Event is a notification pushed by the server.
+type Event struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+func (e *Event) Decode(v any) error {
+	return json.Unmarshal(e.Params, v)
+}
+
+// The wire types mirror what the server sends and accepts, so code outside
+// this module can use them.
+
+type Identity struct {
+	AccountId string    `json:"accountId"`
+	Name      string    `json:"name"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+type NewGame struct {
+	Size       int    `json:"size"`
+	Autopilots int    `json:"autopilots"`
+	Seed       *int64 `json:"seed,omitempty"`
+	Visibility string `json:"visibility,omitempty"`
+	Password   string `json:"password,omitempty"`
+	// SpectatorDelay is in seconds, the server default when nil.
+	SpectatorDelay *int `json:"spectatorDelay,omitempty"`
+}
+
+type GameStarted struct {
+	GameId           string        `json:"gameId"`
+	SizeGame         int           `json:"sizeGame"`
+	InitTime         time.Time     `json:"initTime"`
+	NumberAutoPilots int           `json:"numberAutoPilots"`
+	DelayAutoPilots  int           `json:"delayAutoPilots"`
+	Seed             int64         `json:"seed"`
+	CreatorId        string        `json:"creatorId,omitempty"`
+	Mode             string        `json:"mode,omitempty"`
+	MaxPlayers       int           `json:"maxPlayers,omitempty"`
+	Visibility       string        `json:"visibility,omitempty"`
+	SpectatorDelay   time.Duration `json:"spectatorDelay,omitempty"`
+}
+
+type GameCreated struct {
+	GameStarted
+	Visibility string `json:"visibility"`
+	InviteCode string `json:"inviteCode"`
+}
+
+type GameFinished struct {
+	GameId           string        `json:"gameId"`
+	SizeGame         int           `json:"sizeGame"`
+	InitTime         time.Time     `json:"initTime"`
+	NumberAutoPilots int           `json:"numberAutoPilots"`
+	WinnerId         string        `json:"winnerId"`
+	WinnerName       string        `json:"winnerName"`
+	Duration         time.Duration `json:"duration"`
+	Reason           string        `json:"reason"`
+}
+
+type GameStatus struct {
+	IsInProcess bool `json:"isInProcess"`
+	IsFinished  bool `json:"isFinished"`
+	IsPaused    bool `json:"isPaused,omitempty"`
+}
+
+type GameMetrics struct {
+	GameId                string     `json:"gameId"`
+	SizeGame              int        `json:"sizeGame"`
+	NumberAutoPilots      int        `json:"numberAutoPilots"`
+	Players               int        `json:"players"`
+	AutoPilots            int        `json:"autoPilots"`
+	AutoPilotTotalIters   uint64     `json:"autoPilotTotalIters"`
+	AutoPilotCurrentIters int        `json:"autoPilotCurrentIters"`
+	AutoPilotMoves        uint64     `json:"autoPilotMoves"`
+	CurrentDuration       string     `json:"currentDuration"`
+	GameStatus            GameStatus `json:"gameStatus"`
+	CreatorId             string     `json:"creatorId,omitempty"`
+	Visibility            string     `json:"visibility"`
+	Spectators            int        `json:"spectators"`
+	ServerHeapBytes       uint64     `json:"serverHeapBytes,omitempty"`
+}
+
+// PlayerJoin joins by GameId, or by InviteCode for unlisted games.
+type PlayerJoin struct {
+	GameId     string `json:"gameId"`
+	PlayerName string `json:"playerName"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+type PlayerAdded struct {
+	GameId     string `json:"gameId"`
+	PlayerId   string `json:"playerId"`
+	PlayerName string `json:"playerName"`
+	AccountId  string `json:"accountId,omitempty"`
+	AutoPilot  bool   `json:"autoPilot"`
+}
+
+type PlayerRemoved struct {
+	GameId   string `json:"gameId"`
+	PlayerId string `json:"playerId"`
+}
+
+type PlayerMoved struct {
+	GameId     string     `json:"gameId"`
+	PlayerId   string     `json:"playerId"`
+	Index      int        `json:"index"`
+	TimeMove   time.Time  `json:"timeMove"`
+	GameStatus GameStatus `json:"gameStatus"`
+}
+
+type PlayerKicked struct {
+	GameId    string `json:"gameId"`
+	PlayerId  string `json:"playerId"`
+	Reason    string `json:"reason,omitempty"`
+	Banned    bool   `json:"banned"`
+	AccountId string `json:"accountId,omitempty"`
+}
+
+type GamePaused struct {
+	GameId string `json:"gameId"`
+}
+
+type GameResumed struct {
+	GameId    string        `json:"gameId"`
+	PausedFor time.Duration `json:"pausedFor"`
+}
+
+type ChatMessage struct {
+	GameId     string `json:"gameId"`
+	PlayerId   string `json:"playerId,omitempty"`
+	SenderId   string `json:"senderId,omitempty"`
+	SenderName string `json:"senderName"`
+	Text       string `json:"text"`
+	Filtered   bool   `json:"filtered,omitempty"`
+}
+
+type PlayerMuted struct {
+	GameId    string `json:"gameId"`
+	PlayerId  string `json:"playerId"`
+	AccountId string `json:"accountId,omitempty"`
+	Muted     bool   `json:"muted"`
+}
+
+// GameEvent is an entry of a game log. Type names the one payload set.
+type GameEvent struct {
+	Seq           uint64         `json:"seq"`
+	Time          time.Time      `json:"time"`
+	Type          string         `json:"type"`
+	GameStarted   *GameStarted   `json:"gameStarted,omitempty"`
+	GameFinished  *GameFinished  `json:"gameFinished,omitempty"`
+	PlayerAdded   *PlayerAdded   `json:"playerAdded,omitempty"`
+	PlayerRemoved *PlayerRemoved `json:"playerRemoved,omitempty"`
+	PlayerMoved   *PlayerMoved   `json:"playerMoved,omitempty"`
+	PlayerKicked  *PlayerKicked  `json:"playerKicked,omitempty"`
+	GamePaused    *GamePaused    `json:"gamePaused,omitempty"`
+	GameResumed   *GameResumed   `json:"gameResumed,omitempty"`
+	ChatMessage   *ChatMessage   `json:"chatMessage,omitempty"`
+	PlayerMuted   *PlayerMuted   `json:"playerMuted,omitempty"`
+}
+
+// Participant is a player that took part in the game, including those who
+// left.
+type Participant struct {
+	PlayerId    string `json:"playerId"`
+	PlayerName  string `json:"playerName"`
+	AccountId   string `json:"accountId,omitempty"`
+	AutoPilot   bool   `json:"autoPilot"`
+	BitsFlipped int    `json:"bitsFlipped"`
+	Left        bool   `json:"left,omitempty"`
+}
+
+// GameView is the state of a game at the spectator's point of the feed.
+type GameView struct {
+	GameId           string        `json:"gameId"`
+	SizeGame         int           `json:"sizeGame"`
+	Status           []byte        `json:"status"`
+	HasStarted       bool          `json:"hasStarted"`
+	HasFinished      bool          `json:"hasFinished"`
+	Paused           bool          `json:"paused,omitempty"`
+	Players          []Participant `json:"players"`
+	NumberAutoPilots int           `json:"numberAutoPilots"`
+	MaxPlayers       int           `json:"maxPlayers"`
+	Mode             string        `json:"mode,omitempty"`
+	LastMoveTime     time.Time     `json:"lastMoveTime"`
+	LastMoveBy       string        `json:"lastMoveBy"`
+	WinnerId         string        `json:"winnerId,omitempty"`
+	WinnerName       string        `json:"winnerName,omitempty"`
+	Seq              uint64        `json:"seq"`
+}
+
+type SpectateGame struct {
+	GameId     string `json:"gameId"`
+	InviteCode string `json:"inviteCode,omitempty"`
+	Password   string `json:"password,omitempty"`
+}
+
+type SpectateStarted struct {
+	GameId  string    `json:"gameId"`
+	DelayMs int64     `json:"delayMs"`
+	Seq     uint64    `json:"seq"`
+	State   *GameView `json:"state,omitempty"`
+}
+
+// SpectatorEvent is the params of a spectator_event notification.
+type SpectatorEvent struct {
+	GameId string     `json:"gameId"`
+	Event  *GameEvent `json:"event"`
+}
+
+// SpectateFinished is the params of a spectate_finished notification.
+type SpectateFinished struct {
+	GameId string `json:"gameId"`
+	Seq    uint64 `json:"seq"`
+}
+
+// gameRef, playerLeave and playerMove are request params the caller never
+// builds itself. The server reads the player id of a leave or a move from
+// playerName.
+type gameRef struct {
+	ID string `json:"id"`
+}
+
+type playerLeave struct {
+	GameId   string `json:"gameId"`
+	PlayerId string `json:"playerName"`
+}
+
+type playerMove struct {
+	GameId   string `json:"gameId"`
+	PlayerId string `json:"playerName"`
+	Index    int    `json:"index"`
+}
+
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+	ID      uint64 `json:"id"`
+}
+
+// message is either a response, with an id, or a notification, with a method.
+type message struct {
+	ID     *uint64         `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
@@ -0,0 +1,94 @@
+package client
+
+import (
+	"battlebit/internal/auth"
+	"battlebit/internal/bb"
+	"battlebit/internal/hub"
+	"battlebit/internal/server"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// jsonFields maps the json names of a struct to their field types, with
+// embedded structs flattened as encoding/json does.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if f.Anonymous && name == "" {
+			for k, v := range jsonFields(f.Type) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func kind(t reflect.Type) reflect.Kind {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind()
+}
+
+func TestWireTypes(t *testing.T) {
+	tests := []struct {
+		client any
+		server any
+	}{
+		{Identity{}, auth.Identity{}},
+		{NewGame{}, hub.CrateNewGame{}},
+		{GameStarted{}, bb.GameStarted{}},
+		{GameCreated{}, server.GameCreated{}},
+		{GameFinished{}, bb.GameFinished{}},
+		{GameMetrics{}, bb.GameMetrics{}},
+		{PlayerJoin{}, bb.PlayerJoin{}},
+		{PlayerAdded{}, bb.PlayerAdded{}},
+		{PlayerRemoved{}, bb.PlayerRemoved{}},
+		{PlayerMoved{}, bb.PlayerMoved{}},
+		{PlayerKicked{}, bb.PlayerKicked{}},
+		{GamePaused{}, bb.GamePaused{}},
+		{GameResumed{}, bb.GameResumed{}},
+		{ChatMessage{}, bb.ChatMessage{}},
+		{PlayerMuted{}, bb.PlayerMuted{}},
+		{GameEvent{}, bb.Event{}},
+		{Participant{}, bb.Participant{}},
+		{GameView{}, bb.GameView{}},
+		{SpectateGame{}, bb.SpectateGame{}},
+		{SpectateStarted{}, server.SpectateStarted{}},
+		{SpectatorEvent{}, server.SpectatorEvent{}},
+		{SpectateFinished{}, server.SpectateFinished{}},
+		{gameRef{}, hub.GameId{}},
+		{playerLeave{}, bb.PlayerLeave{}},
+		{playerMove{}, bb.PlayerMove{}},
+	}
+	for _, tt := range tests {
+		name := reflect.TypeOf(tt.client).Name()
+		t.Run(name, func(t *testing.T) {
+			want := jsonFields(reflect.TypeOf(tt.server))
+			for field, typ := range jsonFields(reflect.TypeOf(tt.client)) {
+				serverType, ok := want[field]
+				if !ok {
+					t.Errorf("%s.%s is not sent by the server", name, field)
+					continue
+				}
+				if kind(typ) != kind(serverType) {
+					t.Errorf("%s.%s is a %s, the server uses a %s", name, field, kind(typ), kind(serverType))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import "context"
+
+// Authenticate identifies the connection with a token. The token is kept and
+// sent again when the client reconnects.
+func (c *Client) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	id := new(Identity)
+	if err := c.Call(ctx, methodAuthenticate, map[string]string{"token": token}, id); err != nil {
+		return nil, err
+	}
+	c.mutex.Lock()
+	c.token = token
+	c.mutex.Unlock()
+	return id, nil
+}
+
+func (c *Client) CreateGame(ctx context.Context, ng NewGame) (*GameCreated, error) {
+	created := new(GameCreated)
+	if err := c.Call(ctx, methodCreateGame, ng, created); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (c *Client) ListGames(ctx context.Context) ([]*GameMetrics, error) {
+	games := make([]*GameMetrics, 0)
+	if err := c.Call(ctx, methodListGames, nil, &games); err != nil {
+		return nil, err
+	}
+	return games, nil
+}
+
+func (c *Client) RemoveGame(ctx context.Context, gameId string) error {
+	return c.Call(ctx, methodRemoveGame, gameRef{ID: gameId}, nil)
+}
+
+func (c *Client) JoinGame(ctx context.Context, pj PlayerJoin) (*PlayerAdded, error) {
+	added := new(PlayerAdded)
+	if err := c.Call(ctx, methodJoinGame, pj, added); err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func (c *Client) LeaveGame(ctx context.Context, gameId string, playerId string) (*PlayerRemoved, error) {
+	removed := new(PlayerRemoved)
+	if err := c.Call(ctx, methodLeaveGame, playerLeave{GameId: gameId, PlayerId: playerId}, removed); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// Move flips the bit at index. The server answers a move it ignores, out of
+// range or after the game finished, with an empty or finished PlayerMoved.
+func (c *Client) Move(ctx context.Context, gameId string, playerId string, index int) (*PlayerMoved, error) {
+	moved := new(PlayerMoved)
+	if err := c.Call(ctx, methodPlayerMove, playerMove{GameId: gameId, PlayerId: playerId, Index: index}, moved); err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+func (c *Client) Metrics(ctx context.Context, gameId string) (*GameMetrics, error) {
+	metrics := new(GameMetrics)
+	if err := c.Call(ctx, methodGameMetrics, gameRef{ID: gameId}, metrics); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// Spectate starts the delayed feed of a game, pushed as spectator_event
+// notifications until spectate_finished.
+func (c *Client) Spectate(ctx context.Context, sg SpectateGame) (*SpectateStarted, error) {
+	started := new(SpectateStarted)
+	if err := c.Call(ctx, methodSpectate, sg, started); err != nil {
+		return nil, err
+	}
+	return started, nil
+}
+
+func (c *Client) StopSpectating(ctx context.Context, gameId string) error {
+	return c.Call(ctx, methodStopSpectate, SpectateGame{GameId: gameId}, nil)
+}
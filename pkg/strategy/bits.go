@@ -0,0 +1,49 @@
+package strategy
+
+// Bits is a Board kept by hand, for bots outside the server. It uses the
+// layout of the status of a snapshot: bit i is bits[i/8] & (1 << (i%8)).
+type Bits struct {
+	size int
+	on   int
+	bits []byte
+}
+
+func NewBits(size int) *Bits {
+	return &Bits{size: size, bits: make([]byte, (size+7)/8)}
+}
+
+// LoadBits starts a board from the status of a snapshot.
+func LoadBits(size int, status []byte) *Bits {
+	b := NewBits(size)
+	copy(b.bits, status)
+	for i := 0; i < size; i++ {
+		if b.IsOn(i) {
+			b.on++
+		}
+	}
+	return b
+}
+
+func (b *Bits) Size() int {
+	return b.size
+}
+
+func (b *Bits) IsOn(index int) bool {
+	return b.bits[index>>3]&(1<<(index&7)) != 0
+}
+
+// Set turns a bit on, as the server never turns one off, and reports whether
+// it was off.
+func (b *Bits) Set(index int) bool {
+	if index < 0 || index >= b.size || b.IsOn(index) {
+		return false
+	}
+	b.bits[index>>3] |= 1 << (index & 7)
+	b.on++
+	return true
+}
+
+// On returns how many bits are on.
+func (b *Bits) On() int {
+	return b.on
+}
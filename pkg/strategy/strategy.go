@@ -0,0 +1,126 @@
+// Package strategy decides which bit a bot flips next. The autopilots inside
+// the server and cmd/bbbot, which plays over the public protocol, use the
+// same strategies, so a bot written here can be tried on a live server first.
+package strategy
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// Board is the game as a bot sees it. Remote bots learn it from the
+// spectator feed, so it may lag behind the server.
+type Board interface {
+	Size() int
+	IsOn(index int) bool
+}
+
+type Strategy interface {
+	// Next returns the index of the bit to flip.
+	Next(b Board) int
+}
+
+// Options tell a strategy its randomness and which of the bots of a game it
+// plays, for strategies that share the board out.
+type Options struct {
+	Rand  *rand.Rand
+	Seat  int
+	Seats int
+}
+
+// Names are the strategies New knows, the first one is the autopilots'.
+var Names = []string{"gaussian", "random", "sweep", "hunter"}
+
+func New(name string, opts Options) (Strategy, error) {
+	if opts.Rand == nil {
+		opts.Rand = rand.New(rand.NewSource(rand.Int63()))
+	}
+	opts.Seats = max(1, opts.Seats)
+	switch name {
+	case "gaussian":
+		return &Gaussian{StdDev: 5, Rand: opts.Rand}, nil
+	case "random":
+		return &Random{Rand: opts.Rand}, nil
+	case "sweep":
+		return &Sweep{Seat: opts.Seat, Seats: opts.Seats}, nil
+	case "hunter":
+		return &Hunter{Rand: opts.Rand}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q, want one of %s", name, strings.Join(Names, ", "))
+}
+
+// Gaussian is how the autopilots play: around a mean that walks across the
+// board one bit per move and starts over past the end.
+type Gaussian struct {
+	StdDev float64
+	Mean   int
+	Rand   *rand.Rand
+}
+
+func (s *Gaussian) Next(b Board) int {
+	size := b.Size()
+	index := 0
+	for {
+		index = int(s.Rand.NormFloat64()*s.StdDev + float64(s.Mean))
+		if index >= 0 && index < size {
+			break
+		}
+	}
+	s.Mean++
+	if s.Mean > size {
+		s.Mean = 0
+	}
+	return index
+}
+
+// Random flips any bit, on or off, so it slows down as the board fills up.
+type Random struct {
+	Rand *rand.Rand
+}
+
+func (s *Random) Next(b Board) int {
+	return s.Rand.Intn(b.Size())
+}
+
+// Sweep walks the board from the share of its seat, skipping bits it knows
+// are on.
+type Sweep struct {
+	Seat    int
+	Seats   int
+	started bool
+	index   int
+}
+
+func (s *Sweep) Next(b Board) int {
+	size := b.Size()
+	if !s.started {
+		s.started = true
+		s.index = s.Seat * size / max(1, s.Seats)
+	}
+	index := firstOff(b, s.index)
+	s.index = (index + 1) % size
+	return index
+}
+
+// Hunter aims at a random bit and flips the first one off from there.
+type Hunter struct {
+	Rand *rand.Rand
+}
+
+func (s *Hunter) Next(b Board) int {
+	return firstOff(b, s.Rand.Intn(b.Size()))
+}
+
+// firstOff returns the first bit off from index on, wrapping around, or
+// index when every bit is on.
+func firstOff(b Board, index int) int {
+	size := b.Size()
+	index %= size
+	for i := 0; i < size; i++ {
+		if j := (index + i) % size; !b.IsOn(j) {
+			return j
+		}
+	}
+	return index
+}